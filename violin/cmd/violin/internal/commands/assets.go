@@ -0,0 +1,188 @@
+package commands
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"violin/internal/assets"
+	"violin/internal/drone"
+	"violin/internal/practice"
+	"violin/internal/render"
+
+	"github.com/pkg/errors"
+)
+
+// Index refreshes the asset index saved at indexPath with the files found
+// now, keeping the loudness measurements of the files that have not
+// changed, and prints what each family holds.
+func Index(log *log.Logger, ix *assets.Index, indexPath string) error {
+	saved, err := assets.Load(indexPath)
+	if err != nil {
+		return err
+	}
+	ix.Merge(saved)
+
+	if err := ix.Save(indexPath); err != nil {
+		return err
+	}
+	log.Printf("index : saved %s", indexPath)
+
+	type family struct {
+		name     string
+		files    int
+		size     int64
+		measured int
+	}
+	byName := make(map[string]*family)
+	var families []*family
+	for _, f := range ix.Files() {
+		name := path.Dir(f.Path)
+		fam, ok := byName[name]
+		if !ok {
+			fam = &family{name: name}
+			byName[name] = fam
+			families = append(families, fam)
+		}
+		fam.files++
+		fam.size += f.Size
+		if f.Loudness != nil {
+			fam.measured++
+		}
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "DIRECTORY\tFILES\tSIZE KB\tMEASURED")
+	for _, fam := range families {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\n", fam.name, fam.files, fam.size/1024, fam.measured)
+	}
+	tw.Flush()
+	return nil
+}
+
+// Check reports the asset files GoViolin has no use for, the recordings
+// served without normalization as their loudness is not measured, and how
+// many exercises of the scale page are drawn or synthesized for want of an
+// image or recording. It fails when there are files it cannot use.
+func Check(log *log.Logger, ix *assets.Index, indexPath string) error {
+	saved, err := assets.Load(indexPath)
+	if err != nil {
+		return err
+	}
+	ix.Merge(saved)
+
+	var paths []string
+	for _, f := range ix.Files() {
+		paths = append(paths, f.Path)
+	}
+	drones := make(map[string]bool)
+	for _, rec := range drone.Library(paths) {
+		drones[rec.Path] = true
+	}
+
+	var unusable, unmeasured []string
+	for _, f := range ix.Files() {
+		if !usable(f.Path, drones) {
+			unusable = append(unusable, f.Path)
+		}
+		if path.Ext(f.Path) == ".mp3" && f.Loudness == nil {
+			unmeasured = append(unmeasured, f.Path)
+		}
+	}
+
+	type kind struct {
+		exercises, drawn, synthesized int
+	}
+	kinds := make(map[string]*kind)
+	for _, it := range scaleItems() {
+		k, ok := kinds[it.Kind]
+		if !ok {
+			k = &kind{}
+			kinds[it.Kind] = k
+		}
+		img, audio, _ := render.SetAssetPaths(it.Pitch, it.Kind, render.SetActualKey(it.Pitch, it.Key), it.Octave)
+		k.exercises++
+		if !ix.Has(img) {
+			k.drawn++
+		}
+		if !ix.Has(audio) {
+			k.synthesized++
+		}
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "EXERCISE\tPAGES\tDRAWN\tSYNTHESIZED")
+	for _, name := range practice.ScaleKinds {
+		if k, ok := kinds[name]; ok {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\n", name, k.exercises, k.drawn, k.synthesized)
+		}
+	}
+
+	fmt.Fprintln(tw, "\nRecordings not measured, served without normalization until violin assets normalize runs:")
+	for _, p := range unmeasured {
+		fmt.Fprintln(tw, p)
+	}
+	if len(unmeasured) == 0 {
+		fmt.Fprintln(tw, "none")
+	}
+
+	fmt.Fprintln(tw, "\nFiles no page uses:")
+	for _, p := range unusable {
+		fmt.Fprintln(tw, p)
+	}
+	if len(unusable) == 0 {
+		fmt.Fprintln(tw, "none")
+	}
+	tw.Flush()
+
+	log.Printf("check : %d files, %d not measured, %d unusable", len(paths), len(unmeasured), len(unusable))
+	if len(unusable) > 0 {
+		return errors.Errorf("%d asset files cannot be used", len(unusable))
+	}
+	return nil
+}
+
+// usable reports whether a page uses the asset at p: the image or recording
+// of an exercise, a drone, a duet or one of the site's own images.
+func usable(p string, drones map[string]bool) bool {
+	if _, ok := render.ParseAssetPath(p); ok {
+		return true
+	}
+	switch assets.Family(p) {
+	case "duet":
+		return strings.HasPrefix(p, "img/") && path.Ext(p) == ".png" || strings.HasPrefix(p, "mp3/") && path.Ext(p) == ".mp3"
+	case "misc":
+		return strings.HasPrefix(p, "img/")
+	}
+	return drones[p]
+}
+
+// scaleItems lists every exercise the options of the scale page select,
+// each once: kinds that sound the same in major and minor keys are listed
+// in the pitch the page shows them in.
+func scaleItems() []practice.Item {
+	var items []practice.Item
+	for _, scale := range render.SetScaleOptions(practice.KindScale) {
+		for _, pitch := range render.SetPitchOptions("Major") {
+			if render.ExercisePitch(scale.Value, pitch.Value) != pitch.Value {
+				continue
+			}
+			for _, key := range render.SetKeyOptions("A") {
+				for _, octave := range render.SetOctaveOptions(scale.Value, pitch.Value, key.Value, "") {
+					if octave.IsDisabled {
+						continue
+					}
+					items = append(items, practice.Item{Kind: scale.Value, Pitch: pitch.Value, Key: key.Value, Octave: octave.Value})
+				}
+			}
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].ID() < items[j].ID()
+	})
+	return items
+}
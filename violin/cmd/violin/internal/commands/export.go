@@ -0,0 +1,96 @@
+package commands
+
+import (
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"violin/cmd/violin/internal/handlers"
+
+	"github.com/pkg/errors"
+)
+
+// links finds the urls a page or stylesheet refers to.
+var links = regexp.MustCompile(`(?:src|href)="([^"]*)"|url\("?([^")]*)"?\)`)
+
+// ExportStatic writes a read-only GoViolin to dir, for a static host
+// serving it from the root of a site. Every scale and arpeggio page the
+// scale page's options can select is rendered by mux at its export path,
+// then the links of each page are followed to the duets and the files the
+// pages use, which are copied as mux serves them. Pages are written in
+// language, or English when it is empty.
+func ExportStatic(log *log.Logger, mux http.Handler, dir, language string) error {
+	queue := []string{"/"}
+	for _, it := range scaleItems() {
+		if p := handlers.ExportPath(it); p != "" {
+			queue = append(queue, p)
+		}
+	}
+
+	seen := make(map[string]bool)
+	pages, files, skipped := 0, 0, 0
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+
+		r := httptest.NewRequest(http.MethodGet, p, nil)
+		if language != "" {
+			r.Header.Set("Accept-Language", language)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			if strings.HasSuffix(p, ".html") || p == "/" {
+				return errors.Errorf("exporting %s: %d %s", p, w.Code, http.StatusText(w.Code))
+			}
+			log.Printf("export : skipped %s : %d %s", p, w.Code, http.StatusText(w.Code))
+			skipped++
+			continue
+		}
+
+		name := p
+		if strings.HasSuffix(name, "/") {
+			name += "index.html"
+		}
+		dst := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return errors.Wrapf(err, "exporting %s", p)
+		}
+		if err := os.WriteFile(dst, w.Body.Bytes(), 0644); err != nil {
+			return errors.Wrapf(err, "exporting %s", p)
+		}
+
+		switch ct := w.Header().Get("Content-Type"); {
+		case strings.HasPrefix(ct, "text/html"):
+			pages++
+		case strings.HasPrefix(ct, "text/css"):
+			files++
+		default:
+			files++
+			continue
+		}
+		for _, m := range links.FindAllStringSubmatch(w.Body.String(), -1) {
+			link := m[1] + m[2]
+			if !strings.HasPrefix(link, "/") || strings.HasPrefix(link, "//") {
+				continue
+			}
+			if strings.ContainsAny(link, "?#") {
+				log.Printf("export : skipped %s : linked from %s with a query", link, p)
+				skipped++
+				continue
+			}
+			queue = append(queue, link)
+		}
+	}
+
+	log.Printf("export : wrote %d pages and %d files to %s, skipped %d", pages, files, dir, skipped)
+	return nil
+}
@@ -0,0 +1,140 @@
+// Package commands holds the maintenance commands of the violin binary.
+package commands
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path"
+	"sort"
+	"text/tabwriter"
+
+	"violin/internal/assets"
+	"violin/internal/audio"
+
+	"github.com/pkg/errors"
+)
+
+// Normalize measures the loudness of every MP3 in the index that changed
+// since it was last measured, saves the index to indexPath and prints a
+// report of the gain the server will apply to bring each recording to the
+// target loudness, listing the outliers.
+func Normalize(log *log.Logger, ix *assets.Index, indexPath string, target float64) error {
+	saved, err := assets.Load(indexPath)
+	if err != nil {
+		return err
+	}
+	ix.Merge(saved)
+
+	measured := 0
+	for _, f := range ix.Files() {
+		if path.Ext(f.Path) != ".mp3" || f.Loudness != nil {
+			continue
+		}
+
+		l, err := measure(f.Path)
+		if err != nil {
+			return errors.Wrapf(err, "measuring %s", f.Path)
+		}
+		ix.SetLoudness(f.Path, l)
+		measured++
+	}
+	log.Printf("normalize : measured %d recordings", measured)
+
+	if err := ix.Save(indexPath); err != nil {
+		return err
+	}
+	log.Printf("normalize : saved %s", indexPath)
+
+	report(os.Stdout, ix.Normalize(target), target)
+	return nil
+}
+
+// measure returns the loudness of the MP3 file at path.
+func measure(path string) (assets.Loudness, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return assets.Loudness{}, err
+	}
+	defer f.Close()
+
+	s, err := audio.NewMP3Decoder(f)
+	if err != nil {
+		return assets.Loudness{}, err
+	}
+	l, err := audio.MeasureLoudness(s)
+	if err != nil {
+		return assets.Loudness{}, err
+	}
+
+	// JSON has no infinities, silence is stored at the gate.
+	if math.IsInf(l.Integrated, -1) {
+		l.Integrated = assets.Silence
+	}
+	if math.IsInf(l.Peak, -1) {
+		l.Peak = assets.Silence
+	}
+	return assets.Loudness{Integrated: l.Integrated, Peak: l.Peak}, nil
+}
+
+// report prints the range of gains applied to each family and the
+// recordings that need checking or were held below the target.
+func report(w io.Writer, list []assets.Normalization, target float64) {
+	type family struct {
+		name     string
+		files    int
+		loudness float64
+		min, max float64
+	}
+	byName := make(map[string]*family)
+	var families []*family
+	var outliers, limited []assets.Normalization
+	for _, n := range list {
+		fam, ok := byName[n.Family]
+		if !ok {
+			fam = &family{name: n.Family, loudness: n.FamilyLoudness, min: n.Gain, max: n.Gain}
+			byName[n.Family] = fam
+			families = append(families, fam)
+		}
+		fam.files++
+		fam.min = math.Min(fam.min, n.Gain)
+		fam.max = math.Max(fam.max, n.Gain)
+		if n.Outlier {
+			outliers = append(outliers, n)
+		}
+		if n.Limited {
+			limited = append(limited, n)
+		}
+	}
+	sort.Slice(families, func(i, j int) bool {
+		return families[i].name < families[j].name
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Target loudness %.1f LUFS\n\n", target)
+	fmt.Fprintln(tw, "FAMILY\tFILES\tMEDIAN LUFS\tGAIN dB")
+	for _, fam := range families {
+		fmt.Fprintf(tw, "%s\t%d\t%.1f\t%+.1f to %+.1f\n", fam.name, fam.files, fam.loudness, fam.min, fam.max)
+	}
+
+	fmt.Fprintf(tw, "\nOutliers, more than %d LU from their family median, worth checking:\n", assets.OutlierLU)
+	fmt.Fprintln(tw, "FILE\tLUFS\tFAMILY LUFS\tGAIN dB")
+	for _, n := range outliers {
+		fmt.Fprintf(tw, "%s\t%.1f\t%.1f\t%+.1f\n", n.Path, n.Loudness.Integrated, n.FamilyLoudness, n.Gain)
+	}
+	if len(outliers) == 0 {
+		fmt.Fprintln(tw, "none")
+	}
+
+	fmt.Fprintf(tw, "\nHeld below the target to keep peaks under %.1f dBFS:\n", assets.PeakCeiling)
+	fmt.Fprintln(tw, "FILE\tLUFS\tPEAK dBFS\tGAIN dB")
+	for _, n := range limited {
+		fmt.Fprintf(tw, "%s\t%.1f\t%.1f\t%+.1f\n", n.Path, n.Loudness.Integrated, n.Loudness.Peak, n.Gain)
+	}
+	if len(limited) == 0 {
+		fmt.Fprintln(tw, "none")
+	}
+	tw.Flush()
+}
@@ -0,0 +1,128 @@
+package commands
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"violin/internal/practice"
+	"violin/internal/render"
+
+	"github.com/pkg/errors"
+)
+
+// renderFormats maps the formats an exercise renders to onto the extension
+// of its drawn notation, or "" for audio.
+var renderFormats = map[string]string{
+	"svg":      ".svg",
+	"midi":     ".mid",
+	"musicxml": ".musicxml",
+	"wav":      "",
+}
+
+// Exercise names an exercise to render as the scale page would show it.
+// Kind is a practice kind such as "scale" or "dominant7", Key a key option
+// such as "C#/Db" or one of its spellings, Pitch "major" or "minor".
+type Exercise struct {
+	Kind   string
+	Key    string
+	Pitch  string
+	Octave string
+	Rhythm string
+	BPM    int
+}
+
+// Render writes an exercise to w in format, as mux serves it: notation as
+// SVG, a MIDI file or a MusicXML score, or a WAV recording, the recorded
+// one where there is one and otherwise played by the synthesizer.
+func Render(mux http.Handler, w io.Writer, ex Exercise, format string) error {
+	ext, ok := renderFormats[format]
+	if !ok {
+		return errors.Errorf("unknown format %q, want svg, wav, midi or musicxml", format)
+	}
+
+	kind := ""
+	for _, k := range practice.ScaleKinds {
+		if strings.EqualFold(k, ex.Kind) {
+			kind = k
+		}
+	}
+	if kind == "" {
+		return errors.Errorf("unknown exercise %q", ex.Kind)
+	}
+
+	pitch := ""
+	for _, p := range render.SetPitchOptions("Major") {
+		if strings.EqualFold(p.Value, ex.Pitch) {
+			pitch = render.ExercisePitch(kind, p.Value)
+		}
+	}
+	if pitch == "" {
+		return errors.Errorf("unknown pitch %q, want major or minor", ex.Pitch)
+	}
+
+	// A key is named by its option or by one of its spellings, which is
+	// the one the notation is written in.
+	key, spelling := "", ""
+	for _, k := range render.SetKeyOptions("A") {
+		for _, name := range strings.Split(k.Value, "/") {
+			if strings.EqualFold(k.Value, ex.Key) || strings.EqualFold(name, ex.Key) {
+				key, spelling = k.Value, name
+			}
+		}
+	}
+	if key == "" {
+		return errors.Errorf("unknown key %q", ex.Key)
+	}
+	if spelling == key || strings.EqualFold(key, ex.Key) {
+		spelling = ""
+	}
+	spelling = selectedOptionValue(render.SetSpellingOptions(pitch, key, spelling))
+
+	octaves := render.SetOctaveOptions(kind, pitch, key, ex.Octave)
+	if selectedOptionValue(octaves) != ex.Octave {
+		return errors.Errorf("%s does not fit %s octaves on the violin", practice.Title(kind, pitch, spelling), ex.Octave)
+	}
+
+	query := url.Values{}
+	if ex.Rhythm != "" {
+		query.Set("Rhythm", ex.Rhythm)
+	}
+	var target string
+	if ext != "" {
+		img, _, _ := render.SetAssetPaths(pitch, kind, spelling, ex.Octave)
+		target = "/notation/" + strings.TrimSuffix(strings.TrimPrefix(img, "img/"), ".png") + ext
+		if ex.BPM > 0 {
+			query.Set("BPM", strconv.Itoa(ex.BPM))
+		}
+	} else {
+		_, audio, _ := render.SetAssetPaths(pitch, kind, render.SetActualKey(pitch, key), ex.Octave)
+		target = "/api/v1/audio/" + audio
+		query.Set("format", format)
+	}
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	if rec.Code != http.StatusOK {
+		return errors.Errorf("rendering %s: %d %s", target, rec.Code, strings.TrimSpace(rec.Body.String()))
+	}
+	_, err := w.Write(rec.Body.Bytes())
+	return errors.Wrap(err, "writing exercise")
+}
+
+// selectedOptionValue returns the value of the checked option, or "" if
+// none is.
+func selectedOptionValue(options []render.Option) string {
+	for _, o := range options {
+		if o.IsChecked {
+			return o.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,23 @@
+package commands
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"violin/internal/user"
+
+	"github.com/pkg/errors"
+)
+
+// CreateUser adds an account to the user store, so a teacher's account can
+// be set up before the server runs, and prints its id.
+func CreateUser(log *log.Logger, users *user.Store, name, password, role string) error {
+	u, err := users.Create(name, password, role, time.Now())
+	if err != nil {
+		return errors.Wrapf(err, "creating user %q", name)
+	}
+	log.Printf("user : created %s %s", u.Role, u.Name)
+	fmt.Println(u.ID)
+	return nil
+}
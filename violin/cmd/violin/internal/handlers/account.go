@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"violin/internal/loglevel"
+	"violin/internal/planner"
+	"violin/internal/practice"
+	"violin/internal/quiz"
+	"violin/internal/render"
+	"violin/internal/user"
+
+	"github.com/pkg/errors"
+)
+
+// Cookie names used to identify visitors and logged in users.
+const (
+	visitorCookie = "violin_visitor"
+	sessionCookie = "violin_session"
+)
+
+// Account represents the handlers for logging in and out.
+type Account struct {
+	log      *loglevel.Logger
+	users    *user.Store
+	practice *practice.Store
+	plans    *planner.Store
+	quizzes  *quiz.Store
+}
+
+// Login handles GET and POST calls for the login page.
+func (a *Account) Login(w http.ResponseWriter, r *http.Request) {
+	a.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+	a.authenticate(w, r, "login.html", "Log In", func(name, password string) (user.User, error) {
+		return a.users.Authenticate(name, password)
+	})
+}
+
+// Signup handles GET and POST calls for the sign up page. Accounts signed
+// up for are students, teachers are created with the user command.
+func (a *Account) Signup(w http.ResponseWriter, r *http.Request) {
+	a.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+	a.authenticate(w, r, "signup.html", "Sign Up", func(name, password string) (user.User, error) {
+		return a.users.Create(name, password, user.RoleStudent, time.Now())
+	})
+}
+
+// Logout handles POST calls to end the current login session.
+func (a *Account) Logout(w http.ResponseWriter, r *http.Request) {
+	a.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if c, err := r.Cookie(sessionCookie); err == nil {
+		if err := a.users.EndSession(c.Value); err != nil {
+			a.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		}
+	}
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Value: "", Path: "/", MaxAge: -1})
+	// Practice logged after logging out belongs to whoever uses the browser
+	// next, not to the account which was logged in before.
+	newVisitor(w, r)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// authenticate renders the login or sign up page, and on POST uses auth to
+// find the account, starts a session for it and merges the practice logged,
+// scheduled and quizzed anonymously by this browser into the account.
+func (a *Account) authenticate(w http.ResponseWriter, r *http.Request, tmpl, title string, auth func(name, password string) (user.User, error)) {
+	pv := render.PageVars{
+		Title:  title,
+		Locale: localeOf(r),
+	}
+
+	if r.Method == http.MethodPost {
+		r.ParseForm()
+		u, err := auth(r.PostForm.Get("Name"), r.PostForm.Get("Password"))
+		switch {
+		case err == nil:
+			if err := a.login(w, r, u); err != nil {
+				a.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			http.Redirect(w, r, "/progress", http.StatusSeeOther)
+			return
+		case errors.Is(err, user.ErrAuthenticationFailure):
+			pv.Error = "Name or password is incorrect."
+		case errors.Is(err, user.ErrExists):
+			pv.Error = "That name is already taken."
+		case errors.Is(err, user.ErrInvalid):
+			pv.Error = "Please enter a name and a password of at least 8 characters."
+		default:
+			a.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}
+
+	if err := renderPage(w, r, tmpl, pv); err != nil {
+		a.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		return
+	}
+}
+
+// login starts a session for the user and sets its cookie, along with the
+// language and note names saved with the account. The browser's anonymous
+// practice moves into the account and the browser gets a new visitor id, so
+// nothing logged there later is merged into it again.
+func (a *Account) login(w http.ResponseWriter, r *http.Request, u user.User) error {
+	token, err := a.users.StartSession(u.ID, time.Now())
+	if err != nil {
+		return errors.Wrap(err, "starting session")
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(user.SessionLifetime / time.Second),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Secure:   r.TLS != nil,
+	})
+	if u.Language != "" || u.Notes != "" {
+		setPreferences(w, u.Language, u.Notes)
+	}
+
+	if c, err := r.Cookie(visitorCookie); err == nil {
+		if err := a.practice.Merge(visitorOwner(c.Value), userOwner(u.ID)); err != nil {
+			return errors.Wrap(err, "merging visitor practice")
+		}
+		if err := a.plans.Merge(visitorOwner(c.Value), userOwner(u.ID)); err != nil {
+			return errors.Wrap(err, "merging visitor schedule")
+		}
+		if err := a.quizzes.Merge(visitorOwner(c.Value), userOwner(u.ID)); err != nil {
+			return errors.Wrap(err, "merging visitor quizzes")
+		}
+	}
+	newVisitor(w, r)
+	return nil
+}
+
+// currentUser returns the account logged in on this request, if any.
+func currentUser(users *user.Store, r *http.Request) (user.User, bool) {
+	c, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return user.User{}, false
+	}
+	u, err := users.SessionUser(c.Value, time.Now())
+	if err != nil {
+		return user.User{}, false
+	}
+	return u, true
+}
+
+// owner returns the key of the practice log for this request. Logged in
+// users own their account's log, everyone else gets a log scoped to a
+// visitor cookie which is created on first use.
+func owner(users *user.Store, w http.ResponseWriter, r *http.Request) string {
+	if u, ok := currentUser(users, r); ok {
+		return userOwner(u.ID)
+	}
+
+	if c, err := r.Cookie(visitorCookie); err == nil && c.Value != "" {
+		return visitorOwner(c.Value)
+	}
+	return visitorOwner(newVisitor(w, r))
+}
+
+// newVisitor sets a visitor cookie with a new random id and returns the id.
+func newVisitor(w http.ResponseWriter, r *http.Request) string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	id := hex.EncodeToString(b)
+	http.SetCookie(w, &http.Cookie{
+		Name:     visitorCookie,
+		Value:    id,
+		Path:     "/",
+		MaxAge:   int(365 * 24 * time.Hour / time.Second),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return id
+}
+
+// userOwner is the practice log owner key for an account.
+func userOwner(id string) string {
+	return "user:" + id
+}
+
+// visitorOwner is the practice log owner key for an anonymous visitor.
+func visitorOwner(id string) string {
+	return "visitor:" + id
+}
@@ -0,0 +1,495 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"violin/internal/assets"
+	"violin/internal/audio"
+	"violin/internal/drone"
+	"violin/internal/loglevel"
+	"violin/internal/notation"
+	"violin/internal/render"
+	"violin/internal/rhythm"
+
+	"github.com/pkg/errors"
+)
+
+// audioFormats maps the formats recordings can be converted to onto their
+// content types.
+var audioFormats = map[string]string{
+	"wav":  "audio/wav",
+	"flac": "audio/flac",
+}
+
+// convertRates are the sample rates recordings can be converted to. Each
+// conversion is cached, so the set is kept small.
+var convertRates = map[int]bool{
+	22050: true,
+	44100: true,
+	48000: true,
+}
+
+// Audio represents the handlers that convert the recordings.
+type Audio struct {
+	log    *loglevel.Logger
+	cache  *audio.Cache
+	target float64
+}
+
+// synthNoteLength is how long the synthesizer holds each crotchet of a
+// scale, close to the pace of the recordings, and synthBPM the tempo that
+// makes.
+const (
+	synthNoteLength = 800 * time.Millisecond
+	synthBPM        = int(time.Minute / synthNoteLength)
+)
+
+// Drone lengths in minutes: the length a drone lasts unless asked for
+// another, and the longest it can be asked for.
+const (
+	defaultDroneMinutes = 5
+	maxDroneMinutes     = 30
+)
+
+// Drone handles GET calls for /drone, such as
+// /drone?Key=C%23/Db&Pitch=Minor&Octave=1&Voicing=third&Minutes=5. It
+// serves a seamless drone of the tonic, an open fifth or the tonic and its
+// third lasting the given number of minutes, built from the recordings in
+// mp3/drone. Drones are rendered to WAV on first use and cached, so players
+// can seek in them and fetch them in ranges.
+func (a *Audio) Drone(w http.ResponseWriter, r *http.Request) {
+	a.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+	st := stateOf(r)
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	pitch := query.Get("Pitch")
+	if pitch == "" {
+		pitch = "Major"
+	}
+	octave, err := strconv.Atoi(query.Get("Octave"))
+	if err != nil {
+		octave = 1
+	}
+	voicing := query.Get("Voicing")
+	minutes := formInt(query, "Minutes", defaultDroneMinutes, 1, maxDroneMinutes)
+
+	notes, err := drone.Notes(query.Get("Key"), pitch, octave, voicing)
+	if err != nil {
+		http.Error(w, "unknown key, pitch, octave or voicing", http.StatusBadRequest)
+		return
+	}
+
+	f, err := a.drone(st, notes, voicing, time.Duration(minutes)*time.Minute)
+	if err != nil {
+		a.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		a.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "audio/wav")
+	http.ServeContent(w, r, "drone.wav", info.ModTime(), f)
+}
+
+// drone returns the cached WAV file of the notes sounding together for the
+// duration, rendering it on first use. Drones get the loudness gain of the
+// recording of their lowest note.
+func (a *Audio) drone(st *State, notes []int, voicing string, duration time.Duration) (*os.File, error) {
+	var sources []string
+	for _, note := range notes {
+		rec, _, err := drone.Closest(st.drones, note)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, rec.Path)
+	}
+	gain := st.gains[sources[0]]
+
+	params := fmt.Sprintf("drone notes %v voicing %s duration %s gain %.1f", notes, voicing, duration, gain)
+	key, err := a.cache.Key(sources, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.cache.Open(key, ".wav", func(f *os.File) error {
+		load := func(path string) (*audio.Buffer, error) {
+			b, err := decodePart(a.cache, path)
+			if err != nil {
+				return nil, err
+			}
+			b.Amplify(gain)
+			return b, nil
+		}
+		s, err := drone.Build(st.drones, notes, duration, load)
+		if err != nil {
+			return err
+		}
+		return audio.WriteWAV(f, s)
+	})
+}
+
+// Serve handles GET calls for /audio/<recording>, such as
+// /audio/mp3/drone/a1.mp3. Recordings measured by violin assets normalize
+// are served as FLAC with the gain that brings them to the target loudness,
+// others are served as they are. Exercises nobody has recorded, such as
+// most three octave ones, or asked for in a Rhythm other than the plain
+// one, are synthesized at the target loudness.
+func (a *Audio) Serve(w http.ResponseWriter, r *http.Request) {
+	a.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+	st := stateOf(r)
+
+	src := strings.TrimPrefix(r.URL.Path, "/audio/")
+	if path.Ext(src) != ".mp3" {
+		http.NotFound(w, r)
+		return
+	}
+	pattern := parsePattern(r.URL.Query())
+
+	var f *os.File
+	var err error
+	if st.Assets.Has(src) && pattern.ID == rhythm.Default {
+		gain, ok := st.gains[src]
+		if !ok {
+			http.ServeFile(w, r, src)
+			return
+		}
+		f, err = a.normalize(src, gain)
+	} else {
+		asset, ok := render.ParseAssetPath(src)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		f, err = a.synthesize(asset, pattern, "flac", 0)
+	}
+	if err != nil {
+		a.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		a.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "audio/flac")
+	http.ServeContent(w, r, strings.TrimSuffix(path.Base(src), ".mp3")+".flac", info.ModTime(), f)
+}
+
+// synthesize returns the cached WAV or FLAC of an exercise played by the
+// synthesizer in the rhythm and bowing pattern, resampled when a rate is
+// given, rendering it on first use.
+func (a *Audio) synthesize(asset render.Asset, pattern rhythm.Pattern, format string, rate int) (*os.File, error) {
+	key, err := a.cache.Key(nil, fmt.Sprintf("synth %+v %s %.1f %s %d", asset, pattern.ID, a.target, format, rate))
+	if err != nil {
+		return nil, err
+	}
+
+	return a.cache.Open(key, "."+format, func(f *os.File) error {
+		b, err := a.synthesizeBuffer(asset, pattern, synthNoteLength)
+		if err != nil {
+			return err
+		}
+		s := audio.Resample(b.Stream(), rate)
+
+		if format == "flac" {
+			return audio.WriteFLAC(f, s)
+		}
+		return audio.WriteWAV(f, s)
+	})
+}
+
+// synthesizeBuffer plays an exercise on the synthesizer at the target
+// loudness, holding each crotchet for beat.
+func (a *Audio) synthesizeBuffer(asset render.Asset, pattern rhythm.Pattern, beat time.Duration) (*audio.Buffer, error) {
+	chords, err := exerciseChords(asset, pattern)
+	if err != nil {
+		return nil, err
+	}
+	return a.synthesizeChords(chords, beat)
+}
+
+// synthesizeChords plays chords on the synthesizer in their rhythm and
+// bowing at the target loudness, holding each crotchet for beat.
+func (a *Audio) synthesizeChords(chords []notation.Chord, beat time.Duration) (*audio.Buffer, error) {
+	tones := make([]audio.Tone, len(chords))
+	for i, c := range chords {
+		for _, n := range c.Notes {
+			tones[i].Notes = append(tones[i].Notes, n.MIDI())
+		}
+		tones[i].Length = beat * time.Duration(c.Length()) / rhythm.Beat
+		tones[i].Slurred = c.Slurred
+	}
+
+	b := audio.SynthesizeTones(tones, 44100)
+	if err := a.level(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// level brings synthesized or mixed samples to the target loudness, keeping
+// their peaks below the ceiling recordings are held to.
+func (a *Audio) level(b *audio.Buffer) error {
+	l, err := audio.MeasureLoudness(b.Stream())
+	if err != nil {
+		return errors.Wrap(err, "measuring loudness")
+	}
+	gain := a.target - l.Integrated
+	if limit := assets.PeakCeiling - l.Peak; gain > limit {
+		gain = limit
+	}
+	b.Amplify(gain)
+	return nil
+}
+
+// track returns the samples of a play-along recording at the target
+// loudness, synthesizing exercises nobody has recorded or that are asked for
+// in another rhythm.
+func (a *Audio) track(st *State, src string, pattern rhythm.Pattern) (*audio.Buffer, error) {
+	if !st.Assets.Has(src) || pattern.ID != rhythm.Default {
+		asset, ok := render.ParseAssetPath(src)
+		if !ok {
+			return nil, errors.Errorf("no recording or synthesis of %s", src)
+		}
+		return a.synthesizeBuffer(asset, pattern, synthNoteLength)
+	}
+
+	b, err := decodePart(a.cache, src)
+	if err != nil {
+		return nil, err
+	}
+	b.Amplify(st.gains[src])
+	return b, nil
+}
+
+// normalize returns the cached FLAC of the recording with the gain applied,
+// encoding it on first use.
+func (a *Audio) normalize(src string, gain float64) (*os.File, error) {
+	key, err := a.cache.Key([]string{src}, "gain "+strconv.FormatFloat(gain, 'f', 1, 64))
+	if err != nil {
+		return nil, err
+	}
+
+	return a.cache.Open(key, ".flac", func(f *os.File) error {
+		in, err := os.Open(src)
+		if err != nil {
+			return errors.Wrap(err, "opening recording")
+		}
+		defer in.Close()
+
+		s, err := audio.NewMP3Decoder(in)
+		if err != nil {
+			return errors.Wrapf(err, "decoding %s", src)
+		}
+		return audio.WriteFLAC(f, audio.Gain(s, gain))
+	})
+}
+
+// Convert handles GET calls for /api/v1/audio/<recording>, such as
+// /api/v1/audio/mp3/drone/a1.mp3?format=flac&rate=22050. It decodes an MP3
+// recording, resamples it when one of the convertRates is given and serves
+// it as WAV or FLAC.
+// Exercises nobody has recorded, or asked for in a Rhythm other than the
+// plain one, are synthesized instead. Conversions are cached on disk.
+func (a *Audio) Convert(w http.ResponseWriter, r *http.Request) {
+	a.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+	st := stateOf(r)
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		respondError(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	src := strings.TrimPrefix(r.URL.Path, "/api/v1/audio/")
+	query := r.URL.Query()
+	pattern := parsePattern(query)
+	asset, exercise := render.ParseAssetPath(src)
+	recorded := st.Assets.Has(src) && (pattern.ID == rhythm.Default || !exercise)
+	if path.Ext(src) != ".mp3" || !recorded && !exercise {
+		respondError(w, http.StatusNotFound, "no recording "+src)
+		return
+	}
+
+	format := query.Get("format")
+	if format == "" {
+		format = "wav"
+	}
+	contentType, ok := audioFormats[format]
+	if !ok {
+		respondError(w, http.StatusBadRequest, "format must be wav or flac")
+		return
+	}
+
+	rate := 0
+	if v := query.Get("rate"); v != "" {
+		var err error
+		if rate, err = strconv.Atoi(v); err != nil || !convertRates[rate] {
+			respondError(w, http.StatusBadRequest, "rate must be 22050, 44100 or 48000")
+			return
+		}
+	}
+
+	var f *os.File
+	var err error
+	if recorded {
+		f, err = a.convert(src, format, rate)
+	} else {
+		f, err = a.synthesize(asset, pattern, format, rate)
+	}
+	if err != nil {
+		a.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		respondError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		a.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		respondError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+
+	name := strings.TrimSuffix(path.Base(src), ".mp3") + "." + format
+	w.Header().Set("Content-Type", contentType)
+	http.ServeContent(w, r, name, info.ModTime(), f)
+}
+
+// convert returns the cached conversion of the recording, encoding it on
+// first use.
+func (a *Audio) convert(src, format string, rate int) (*os.File, error) {
+	key, err := a.cache.Key([]string{src}, format+" "+strconv.Itoa(rate))
+	if err != nil {
+		return nil, err
+	}
+
+	return a.cache.Open(key, "."+format, func(f *os.File) error {
+		in, err := os.Open(src)
+		if err != nil {
+			return errors.Wrap(err, "opening recording")
+		}
+		defer in.Close()
+
+		s, err := audio.NewMP3Decoder(in)
+		if err != nil {
+			return errors.Wrapf(err, "decoding %s", src)
+		}
+		s = audio.Resample(s, rate)
+
+		if format == "flac" {
+			return audio.WriteFLAC(f, s)
+		}
+		return audio.WriteWAV(f, s)
+	})
+}
+
+// loudnessGains returns the normalization gain of every measured recording,
+// rounded to a tenth of a decibel so small changes in the measurements do
+// not invalidate the cache.
+func loudnessGains(ix *assets.Index, target float64) map[string]float64 {
+	gains := make(map[string]float64)
+	for _, n := range ix.Normalize(target) {
+		gains[n.Path] = math.Round(n.Gain*10) / 10
+	}
+	return gains
+}
+
+// droneLink returns the url of the drone that goes with a scale, or an
+// empty string when the asset path is not a drone.
+func droneLink(audioPath, key, pitch, octave, voicing, minutes string) string {
+	if !strings.HasPrefix(audioPath, "mp3/drone/") {
+		return ""
+	}
+	// Drones are recorded in two octaves, three octave scales start from
+	// the same tonic as one octave ones.
+	if octave != "2" {
+		octave = "1"
+	}
+	return "/drone?" + url.Values{
+		"Key":     {key},
+		"Pitch":   {pitch},
+		"Octave":  {octave},
+		"Voicing": {voicing},
+		"Minutes": {minutes},
+	}.Encode()
+}
+
+// droneLengthOptions returns the lengths, in minutes, a drone can be asked
+// for with the given one selected, the default when it is not offered.
+func droneLengthOptions(minutes string) []render.Option {
+	var options []render.Option
+	for _, m := range []int{5, 10, 20, 30} {
+		v := strconv.Itoa(m)
+		options = append(options, render.Option{Name: "Minutes", Value: v, Text: v + " min"})
+	}
+	if !hasOption(options, minutes) {
+		minutes = strconv.Itoa(defaultDroneMinutes)
+	}
+	for i := range options {
+		options[i].IsChecked = options[i].Value == minutes
+	}
+	return options
+}
+
+// voicingOptions returns the drone voicings with the given one selected,
+// the single tonic when it is unknown.
+func voicingOptions(voicing string) []render.Option {
+	options := []render.Option{
+		{Name: "Voicing", Value: drone.VoicingSingle, Text: "Tonic"},
+		{Name: "Voicing", Value: drone.VoicingFifth, Text: "Open fifth"},
+		{Name: "Voicing", Value: drone.VoicingThird, Text: "Tonic and third"},
+	}
+	if !hasOption(options, voicing) {
+		voicing = drone.VoicingSingle
+	}
+	for i := range options {
+		options[i].IsChecked = options[i].Value == voicing
+	}
+	return options
+}
+
+// selectedOption returns the value of the checked option.
+func selectedOption(options []render.Option) string {
+	for _, o := range options {
+		if o.IsChecked {
+			return o.Value
+		}
+	}
+	return ""
+}
+
+// assetPaths lists the paths of every indexed asset.
+func assetPaths(ix *assets.Index) []string {
+	var paths []string
+	for _, f := range ix.Files() {
+		paths = append(paths, f.Path)
+	}
+	return paths
+}
@@ -1,35 +1,50 @@
 package handlers
 
 import (
-	"log"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 
+	"violin/internal/drone"
+	"violin/internal/loglevel"
+	"violin/internal/practice"
 	"violin/internal/render"
+	"violin/internal/rhythm"
 )
 
-// Base represents the base handlers.
+// Base represents the base handlers. Static ones render the pages of a
+// static export, with links in place of the forms and without what needs
+// the server.
 type Base struct {
-	log *log.Logger
+	log    *loglevel.Logger
+	static bool
 }
 
 // Home handler for / renders the home.html.
 func (b *Base) Home(w http.ResponseWriter, r *http.Request) {
-	b.log.Printf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+	b.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
 
 	pv := render.PageVars{
-		Title: "GoViolin",
+		Title:  "GoViolin",
+		Locale: localeOf(r),
 	}
-	if err := render.Render(w, "home.html", pv); err != nil {
-		b.log.Printf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+	if b.static {
+		pv.Static, pv.Links = true, exportNav(stateOf(r))
+	}
+	if err := renderPage(w, r, "home.html", pv); err != nil {
+		b.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
 		return
 	}
 }
 
 // Scale handles GET calls for the scale page.
 func (b *Base) Scale(w http.ResponseWriter, r *http.Request) {
-	b.log.Printf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+	b.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
 
 	scale, pitch, key, octave := render.SetDefaultOptions()
+	loc := localeOf(r)
+	leftMusicLabel, rightMusicLabel := render.SetMusicLabels(loc, "Major", "Scale")
 
 	// Set default page variables.
 	pv := render.PageVars{
@@ -38,165 +53,260 @@ func (b *Base) Scale(w http.ResponseWriter, r *http.Request) {
 		Pitch:        "Major",
 		Key:          "A",
 		ScaleImgPath: "img/scale/major/a1.png",
-		GifPath:      "",
 		AudioPath:    "mp3/scale/major/a1.mp3",
 		AudioPath2:   "mp3/drone/a1.mp3",
-		LeftLabel:    "Listen to Major scale",
-		RightLabel:   "Listen to Drone",
+		LeftLabel:    leftMusicLabel,
+		RightLabel:   rightMusicLabel,
 		Scales:       scale,
 		Pitches:      pitch,
 		Keys:         key,
 		Octaves:      octave,
+		Item:         practice.ScaleItem("Scale", "Major", "A", "1"),
+		DronePath:    droneLink("mp3/drone/a1.mp3", "A", "Major", "1", drone.VoicingSingle, strconv.Itoa(defaultDroneMinutes)),
+		Voicings:     voicingOptions(drone.VoicingSingle),
+		DroneLengths: droneLengthOptions(""),
+		Metronome:    metronomeVars(nil, "mp3/scale/major/a1.mp3"),
+		Rhythms:      render.SetRhythmOptions(rhythm.Default),
+		MIDIPath:     "/notation/scale/major/a1.mid",
+		MusicXMLPath: "/notation/scale/major/a1.musicxml",
+		SharePath:    itemLink(practice.Item{Kind: "Scale", Pitch: "Major", Key: "A", Octave: "1"}),
+		NotationPath: "/notation/scale/major/a1.svg",
+		TimingPath:   "/timing/mp3/scale/major/a1.mp3",
+		Offline:      keyToggle(loc, "Major", "A"),
+		Locale:       loc,
 	}
 
-	if err := render.Render(w, "scale.html", pv); err != nil {
-		b.log.Printf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+	if err := renderPage(w, r, "scale.html", pv); err != nil {
+		b.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
 		return
 	}
 }
 
 // ScaleShow handles POST calls for the scale page.
 func (b *Base) ScaleShow(w http.ResponseWriter, r *http.Request) {
-	b.log.Printf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+	b.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
 
-	// TODO: write a fucntion to handle errors and missing data.
 	r.ParseForm()
-	pitch := r.Form["Pitch"][0]
-	var octave string
-	if len(r.Form["Octave"]) > 0 {
-		octave = r.Form["Octave"][0]
+	scale, ok1 := formOption(r.Form, "Scale", "Scale", render.SetScaleOptions)
+	pitch, ok2 := formOption(r.Form, "Pitch", "Major", render.SetPitchOptions)
+	key, ok3 := formOption(r.Form, "Key", "A", render.SetKeyOptions)
+	if !ok1 || !ok2 || !ok3 {
+		http.Error(w, "unknown exercise", http.StatusBadRequest)
+		return
 	}
-	scale := r.Form["Scale"][0]
-	key := r.Form["Key"][0]
+	octave := r.Form.Get("Octave")
+	pitch = render.ExercisePitch(scale, pitch)
 
 	keys := render.SetKeyOptions(key)
 	scales := render.SetScaleOptions(scale)
 	pitches := render.SetPitchOptions(pitch)
-	octaves := render.SetOctaveOptions(octave)
-	key = render.SetActualKey(pitch, key)
-	leftMusicLabel, rightMusicLabel := render.SetMusicLabels(pitch, scale)
-	imgPath, audioPath, audioPath2 := render.SetAssetPaths(pitch, scale, key, octave)
+	octaves := render.SetOctaveOptions(scale, pitch, key, octave)
+	octave = selectedOption(octaves)
+	item := practice.ScaleItem(scale, pitch, key, octave)
+	voicings := voicingOptions(r.Form.Get("Voicing"))
+	lengths := droneLengthOptions(r.Form.Get("Minutes"))
+	spellings := render.SetSpellingOptions(pitch, key, r.Form.Get("Spelling"))
+	spelling := selectedOption(spellings)
+	loc := localeOf(r)
+	leftMusicLabel, rightMusicLabel := render.SetMusicLabels(loc, pitch, scale)
+	imgPath, audioPath, audioPath2 := exercisePaths(pitch, scale, key, spelling, octave)
+	pattern := parsePattern(r.Form)
+	score := strings.TrimSuffix(notationLink(imgPath), ".svg")
 
 	pv := render.PageVars{
 		Title:        "Practice Scales and Arpeggios",
 		Scale:        scale,
-		Key:          key,
+		Key:          spellingName(render.SetActualKey(pitch, key), spelling),
 		Pitch:        pitch,
-		ScaleImgPath: imgPath,
-		GifPath:      "img/major/gif/a1.gif",
-		AudioPath:    audioPath,
-		AudioPath2:   audioPath2,
+		ScaleImgPath: scoreImage(imgPath, pattern),
+		AudioPath:    rhythmLink(audioPath, pattern),
+		AudioPath2:   scoreAudio(audioPath2, pattern),
 		LeftLabel:    leftMusicLabel,
 		RightLabel:   rightMusicLabel,
 		Scales:       scales,
 		Pitches:      pitches,
 		Keys:         keys,
+		Spellings:    spellings,
 		Octaves:      octaves,
+		Item:         item,
+		DronePath:    droneLink(audioPath2, key, pitch, octave, selectedOption(voicings), selectedOption(lengths)),
+		Voicings:     voicings,
+		DroneLengths: lengths,
+		Metronome:    metronomeVars(r.Form, audioPath),
+		Rhythms:      render.SetRhythmOptions(pattern.ID),
+		MIDIPath:     rhythmLink(score+".mid", pattern),
+		MusicXMLPath: rhythmLink(score+".musicxml", pattern),
+		SharePath:    spellingLink(rhythmShareLink(practice.Item{Kind: scale, Pitch: pitch, Key: key, Octave: octave}, pattern), spellings),
+		NotationPath: rhythmLink(score+".svg", pattern),
+		TimingPath:   rhythmLink("/timing/"+audioPath, pattern),
+		Offline:      keyToggle(loc, pitch, key),
+		Locale:       loc,
+	}
+	if b.static {
+		exportScale(&pv, stateOf(r), scale, pitch, key, octave)
 	}
 
-	if err := render.Render(w, "scale.html", pv); err != nil {
-		b.log.Printf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+	if err := renderPage(w, r, "scale.html", pv); err != nil {
+		b.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
 		return
 	}
 }
 
+// formOption returns the form value under name, or def when it is missing.
+// It reports false when the value is not one of its options.
+func formOption(form url.Values, name, def string, options func(string) []render.Option) (string, bool) {
+	v := form.Get(name)
+	if v == "" {
+		return def, true
+	}
+	return v, hasOption(options(v), v)
+}
+
 // Duets handles GET calls for the duets page.
 func (b *Base) Duets(w http.ResponseWriter, r *http.Request) {
-	b.log.Printf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+	b.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
 
-	// Define default duet options
-	options := []render.Option{
-		{Name: "Duet", Value: "G", IsDisabled: false, IsChecked: true, Text: "G Major"},
-		{Name: "Duet", Value: "D", IsDisabled: false, IsChecked: false, Text: "D Major"},
-		{Name: "Duet", Value: "A", IsDisabled: false, IsChecked: false, Text: "A Major"},
-	}
+	// The first duet is shown by default.
+	options := stateOf(r).duets
+	duet := options[0].Value
+	img, both, part1, part2 := duetAssetPaths(duet)
 
 	pv := render.PageVars{
 		Title:         "Practice Duets",
-		Key:           "G Major",
-		DuetImgPath:   "img/duet/gmajor.png",
-		DuetAudioBoth: "mp3/duet/gmajorduetboth.mp3",
-		DuetAudio1:    "mp3/duet/gmajorduetpt1.mp3",
-		DuetAudio2:    "mp3/duet/gmajorduetpt2.mp3",
+		Key:           duet + " Major",
+		DuetImgPath:   img,
+		DuetAudioBoth: both,
+		DuetAudio1:    part1,
+		DuetAudio2:    part2,
 		Duets:         options,
+		Item:          practice.DuetItem(duet),
+		Mix:           duetMix(duet, nil),
+		Offline:       duetToggle(localeOf(r), duet),
+		Locale:        localeOf(r),
 	}
 
-	if err := render.Render(w, "duets.html", pv); err != nil {
-		b.log.Printf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+	if err := renderPage(w, r, "duets.html", pv); err != nil {
+		b.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
 		return
 	}
 }
 
 // DuetShow handles post calls for the duet page.
 func (b *Base) DuetShow(w http.ResponseWriter, r *http.Request) {
-	b.log.Printf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+	b.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
 
-	// Define default duet options
-	options := []render.Option{
-		{Name: "Duet", Value: "G", IsDisabled: false, IsChecked: true, Text: "G Major"},
-		{Name: "Duet", Value: "D", IsDisabled: false, IsChecked: false, Text: "D Major"},
-		{Name: "Duet", Value: "A", IsDisabled: false, IsChecked: false, Text: "A Major"},
-	}
-
-	// Set a placeholder image path, this will be changed later.
-	DuetImgPath := "img/duet/gmajor.png"
-	DuetAudioBoth := "mp3/duet/gmajorduetboth.mp3"
-	DuetAudio1 := "mp3/duet/gmajorduetpt1"
-	DuetAudio2 := "mp3/duet/gmajorduetpt2"
-	// R is url.Values which is a map[string][]string
+	// Unknown duets fall back to the first one.
 	r.ParseForm()
-	var dvalues []string
-	for _, values := range r.Form {
-		dvalues = append(dvalues, values...)
-	}
-
-	switch dvalues[0] {
-	case "D":
-		options = []render.Option{
-			{Name: "Duet", Value: "G", IsDisabled: false, IsChecked: false, Text: "G Major"},
-			{Name: "Duet", Value: "D", IsDisabled: false, IsChecked: true, Text: "D Major"},
-			{Name: "Duet", Value: "A", IsDisabled: false, IsChecked: false, Text: "A Major"},
-		}
-		DuetImgPath = "img/duet/dmajor.png"
-		DuetAudioBoth = "mp3/duet/dmajorduetboth.mp3"
-		DuetAudio1 = "mp3/duet/dmajorduetpt1.mp3"
-		DuetAudio2 = "mp3/duet/dmajorduetpt2.mp3"
-	case "G":
-		options = []render.Option{
-			{Name: "Duet", Value: "G", IsDisabled: false, IsChecked: true, Text: "G Major"},
-			{Name: "Duet", Value: "D", IsDisabled: false, IsChecked: false, Text: "D Major"},
-			{Name: "Duet", Value: "A", IsDisabled: false, IsChecked: false, Text: "A Major"},
-		}
-		DuetImgPath = "img/duet/gmajor.png"
-		DuetAudioBoth = "mp3/duet/gmajorduetboth.mp3"
-		DuetAudio1 = "mp3/duet/gmajorduetpt1.mp3"
-		DuetAudio2 = "mp3/duet/gmajorduetpt2.mp3"
-
-	case "A":
-		options = []render.Option{
-			{Name: "Duet", Value: "G", IsDisabled: false, IsChecked: false, Text: "G Major"},
-			{Name: "Duet", Value: "D", IsDisabled: false, IsChecked: false, Text: "D Major"},
-			{Name: "Duet", Value: "A", IsDisabled: false, IsChecked: true, Text: "A Major"},
-		}
-		DuetImgPath = "img/duet/amajor.png"
-		DuetAudioBoth = "mp3/duet/amajorduetboth.mp3"
-		DuetAudio1 = "mp3/duet/amajorduetpt1.mp3"
-		DuetAudio2 = "mp3/duet/amajorduetpt2.mp3"
+	duets := stateOf(r).duets
+	duet := r.Form.Get("Duet")
+	if !hasOption(duets, duet) {
+		duet = duets[0].Value
 	}
+	options := duetChoices(duets, duet)
+	DuetImgPath, DuetAudioBoth, DuetAudio1, DuetAudio2 := duetAssetPaths(duet)
 
 	// Set default page variables
 	pv := render.PageVars{
 		Title:         "Practice Duets",
-		Key:           "G Major",
+		Key:           duet + " Major",
 		DuetImgPath:   DuetImgPath,
 		DuetAudioBoth: DuetAudioBoth,
 		DuetAudio1:    DuetAudio1,
 		DuetAudio2:    DuetAudio2,
 		Duets:         options,
+		Item:          practice.DuetItem(duet),
+		Mix:           duetMix(duet, r.Form),
+		Offline:       duetToggle(localeOf(r), duet),
+		Locale:        localeOf(r),
+	}
+	if b.static {
+		exportDuet(&pv, stateOf(r), options)
 	}
 
-	if err := render.Render(w, "duets.html", pv); err != nil {
-		b.log.Printf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+	if err := renderPage(w, r, "duets.html", pv); err != nil {
+		b.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
 		return
 	}
 }
+
+// duetAssetPaths builds the paths to the img and mp3 files of the duet in the
+// given key.
+func duetAssetPaths(key string) (img, both, part1, part2 string) {
+	name := strings.ToLower(key) + "major"
+	return "img/duet/" + name + ".png",
+		"mp3/duet/" + name + "duetboth.mp3",
+		"mp3/duet/" + name + "duetpt1.mp3",
+		"mp3/duet/" + name + "duetpt2.mp3"
+}
+
+// itemLink returns the url of the page that shows a practice item.
+func itemLink(it practice.Item) string {
+	if it.Kind == practice.KindDuet {
+		return "/duetshow?" + url.Values{"Duet": {it.Key}}.Encode()
+	}
+	return "/scaleshow?" + url.Values{
+		"Scale":  {it.Kind},
+		"Pitch":  {it.Pitch},
+		"Key":    {it.Key},
+		"Octave": {it.Octave},
+	}.Encode()
+}
+
+// exercisePaths builds the paths to the img and mp3 files of an exercise in
+// a key option such as "C#/Db". The image is written in the spelling of the
+// key, drawn where it has no engraved image, while both spellings share the
+// recordings of the key.
+func exercisePaths(pitch, scale, key, spelling, octave string) (img, audio, audio2 string) {
+	recorded := render.SetActualKey(pitch, key)
+	img, audio, audio2 = render.SetAssetPaths(pitch, scale, recorded, octave)
+	if spelling != "" && spelling != recorded {
+		img, _, _ = render.SetAssetPaths(pitch, scale, spelling, octave)
+	}
+	return img, audio, audio2
+}
+
+// spellingName returns the spelling a key is written under, or the key as
+// the recordings name it for keys with no spelling.
+func spellingName(key, spelling string) string {
+	if spelling == "" {
+		return key
+	}
+	return spelling
+}
+
+// spellingLink adds the spelling to a scale page link, unless it is the
+// conventional one the page picks by itself.
+func spellingLink(link string, spellings []render.Option) string {
+	if len(spellings) < 2 || spellings[0].IsChecked {
+		return link
+	}
+	return link + "&" + url.Values{"Spelling": {selectedOption(spellings)}}.Encode()
+}
+
+// scoreImage returns the image of an exercise in the rhythm and bowing
+// pattern: the img path for the plain pattern, otherwise its drawn notation.
+func scoreImage(img string, pattern rhythm.Pattern) string {
+	if pattern.ID == rhythm.Default {
+		return img
+	}
+	return rhythmLink(strings.TrimPrefix(notationLink(img), "/"), pattern)
+}
+
+// scoreAudio returns the second recording of the scale page in the rhythm
+// and bowing pattern. Drones are left as they are.
+func scoreAudio(audio string, pattern rhythm.Pattern) string {
+	if _, ok := render.ParseAssetPath(audio); !ok {
+		return audio
+	}
+	return rhythmLink(audio, pattern)
+}
+
+// rhythmShareLink returns the scale page link of an item in the rhythm and
+// bowing pattern.
+func rhythmShareLink(it practice.Item, pattern rhythm.Pattern) string {
+	link := itemLink(it)
+	if pattern.ID != rhythm.Default {
+		link += "&" + url.Values{"Rhythm": {pattern.ID}}.Encode()
+	}
+	return link
+}
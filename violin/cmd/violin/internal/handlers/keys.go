@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"violin/internal/i18n"
+	"violin/internal/loglevel"
+	"violin/internal/notation"
+	"violin/internal/practice"
+	"violin/internal/render"
+	"violin/internal/theory"
+)
+
+// Keys represents the handlers of the circle of fifths and key signature
+// reference.
+type Keys struct {
+	log *loglevel.Logger
+}
+
+// Page handles GET calls for the key reference page, the circle of fifths
+// with every key signature listed below it.
+func (k *Keys) Page(w http.ResponseWriter, r *http.Request) {
+	k.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	loc := localeOf(r)
+	var circle bytes.Buffer
+	if err := notation.WriteCircle(&circle, loc.T("Circle of Fifths"), theory.CircleOfFifths(), circleKey(loc)); err != nil {
+		k.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	view := render.KeyReference{Circle: template.HTML(circle.String())}
+	for fifths := -7; fifths <= 7; fifths++ {
+		s := theory.SignatureOf(fifths)
+		accidentals := loc.T("none")
+		if len(s.Accidentals) > 0 {
+			names := make([]string, len(s.Accidentals))
+			for i, a := range s.Accidentals {
+				names[i] = loc.Note(a)
+			}
+			accidentals = strings.Join(names, " ")
+		}
+		view.Signatures = append(view.Signatures, render.KeySignature{
+			Name:        signatureText(loc, fifths),
+			Major:       loc.T("%s major", loc.Note(s.Major)),
+			MajorPath:   keyLink(s.Major, "Major"),
+			Minor:       loc.T("%s minor", loc.Note(s.Minor)),
+			MinorPath:   keyLink(s.Minor, "Minor"),
+			Accidentals: accidentals,
+			ImgPath:     signatureLink(fifths),
+		})
+	}
+
+	pv := render.PageVars{
+		Title:        "Keys",
+		KeyReference: view,
+		Locale:       loc,
+	}
+	if err := renderPage(w, r, "keys.html", pv); err != nil {
+		k.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		return
+	}
+}
+
+// Image handles GET calls for /keys/circle.svg, the circle of fifths, and
+// /keys/signature.svg?Fifths=<n>, a key signature of n sharps or -n flats on
+// a staff.
+func (k *Keys) Image(w http.ResponseWriter, r *http.Request) {
+	k.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	var svg bytes.Buffer
+	switch r.URL.Path {
+	case "/keys/circle.svg":
+		loc := localeOf(r)
+		if err := notation.WriteCircle(&svg, loc.T("Circle of Fifths"), theory.CircleOfFifths(), circleKey(loc)); err != nil {
+			k.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+	case "/keys/signature.svg":
+		fifths, err := strconv.Atoi(r.URL.Query().Get("Fifths"))
+		if err != nil {
+			http.Error(w, "invalid Fifths", http.StatusBadRequest)
+			return
+		}
+		if err := notation.WriteKeySignature(&svg, fifths); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Vary", "Accept-Language, Cookie")
+	w.Write(svg.Bytes())
+}
+
+// keyView is a key signature as the API sends it, with links to the scale
+// pages of its keys.
+type keyView struct {
+	theory.Signature
+	MajorLink string `json:"majorLink"`
+	MinorLink string `json:"minorLink"`
+	Image     string `json:"image"`
+}
+
+// circleView is a position of the circle of fifths as the API sends it.
+type circleView struct {
+	Position   int       `json:"position"`
+	Signatures []keyView `json:"signatures"`
+}
+
+// API handles GET calls to /api/v1/keys, the positions of the circle of
+// fifths clockwise from C with the key signatures at each.
+func (k *Keys) API(w http.ResponseWriter, r *http.Request) {
+	k.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		respondError(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	var circle []circleView
+	for _, p := range theory.CircleOfFifths() {
+		v := circleView{Position: p.Position}
+		for _, s := range p.Signatures {
+			v.Signatures = append(v.Signatures, keyView{
+				Signature: s,
+				MajorLink: keyLink(s.Major, "Major"),
+				MinorLink: keyLink(s.Minor, "Minor"),
+				Image:     signatureLink(s.Fifths),
+			})
+		}
+		circle = append(circle, v)
+	}
+	respond(w, http.StatusOK, circle)
+}
+
+// keyLink returns the url of the one octave scale of a key, such as "Db" or
+// "G#", on the scale page, which names keys by the option of the same pitch
+// and spells them as asked.
+func keyLink(key, pitch string) string {
+	pc, err := theory.PitchClass(key)
+	if err != nil {
+		return ""
+	}
+	for _, o := range render.SetKeyOptions("A") {
+		if c, err := theory.PitchClass(strings.SplitN(o.Value, "/", 2)[0]); err == nil && c == pc {
+			link := itemLink(practice.Item{Kind: "Scale", Pitch: pitch, Key: o.Value, Octave: "1"})
+			return spellingLink(link, render.SetSpellingOptions(pitch, o.Value, key))
+		}
+	}
+	return ""
+}
+
+// signatureLink returns the url of a key signature drawn on a staff.
+func signatureLink(fifths int) string {
+	return "/keys/signature.svg?" + url.Values{"Fifths": {strconv.Itoa(fifths)}}.Encode()
+}
+
+// signatureText names a key signature in the locale, such as "3 sharps".
+func signatureText(loc i18n.Locale, fifths int) string {
+	switch {
+	case fifths == 1:
+		return loc.T("1 sharp")
+	case fifths > 1:
+		return loc.T("%d sharps", fifths)
+	case fifths == -1:
+		return loc.T("1 flat")
+	case fifths < -1:
+		return loc.T("%d flats", -fifths)
+	}
+	return loc.T("No sharps or flats")
+}
+
+// circleKey returns how the circle of fifths writes its keys in the
+// locale, minor keys in lower case and letter names with sharp and flat
+// signs, each linked to its scale.
+func circleKey(loc i18n.Locale) func(key, pitch string) (string, string) {
+	signs := strings.NewReplacer("#", "♯", "b", "♭")
+	return func(key, pitch string) (string, string) {
+		name := loc.Note(key)
+		if loc.Notes == i18n.NotesLetter {
+			name = signs.Replace(name)
+		}
+		if pitch == "Minor" {
+			name = strings.ToLower(name[:1]) + name[1:]
+		}
+		return name, keyLink(key, pitch)
+	}
+}
@@ -0,0 +1,312 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"violin/internal/audio"
+	"violin/internal/metronome"
+	"violin/internal/render"
+	"violin/internal/rhythm"
+
+	"github.com/pkg/errors"
+)
+
+// clickGain is the level of the clicks mixed under a recording.
+const clickGain = 0.5
+
+// clickLoop is the least a click track played on its own lasts before the
+// player repeats it.
+const clickLoop = 30 * time.Second
+
+// Metronome handles GET calls for /metronome, such as
+// /metronome?BPM=80&Meter=3/4&Accent=meter&Subdivision=2&CountIn=1&SilentEvery=4.
+// On its own it serves a loop of whole bars of clicks as WAV, which the
+// scale page's player repeats. Given a Track, the path of a scale page or
+// duet recording, it serves the recording, or the exercise synthesized in
+// the Rhythm asked for, at the tempo of the clicks mixed under it instead.
+func (a *Audio) Metronome(w http.ResponseWriter, r *http.Request) {
+	a.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+	st := stateOf(r)
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	settings := parseMetronome(query)
+	pattern := parsePattern(query)
+	track := query.Get("Track")
+
+	if track == "" {
+		format := audio.Format{SampleRate: 44100, Channels: 1}
+		s, err := metronome.Loop(settings, format, clickLoop)
+		if err != nil {
+			a.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		wav, err := audio.NewWAVReader(s)
+		if err != nil {
+			a.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "audio/wav")
+		http.ServeContent(w, r, "metronome.wav", time.Time{}, wav)
+		return
+	}
+
+	_, synthesized := render.ParseAssetPath(track)
+	if !isPlayAlong(track) || !st.Assets.Has(track) && !synthesized {
+		http.Error(w, "unknown track", http.StatusBadRequest)
+		return
+	}
+
+	f, err := a.overlay(st, track, settings, pattern)
+	if err != nil {
+		a.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		a.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "audio/wav")
+	http.ServeContent(w, r, strings.TrimSuffix(path.Base(track), ".mp3")+"click.wav", info.ModTime(), f)
+}
+
+// overlay returns the cached WAV file of a recording, or a synthesized
+// exercise in the rhythm and bowing pattern, with a click track mixed under
+// it, rendering it on first use.
+func (a *Audio) overlay(st *State, track string, settings metronome.Settings, pattern rhythm.Pattern) (*os.File, error) {
+	sources := []string{track}
+	params := "metronome " + metronomeQuery(settings).Encode() + " gain " + strconv.FormatFloat(st.gains[track], 'f', 1, 64) + " paced by notation"
+	if !st.Assets.Has(track) || pattern.ID != rhythm.Default {
+		sources = nil
+		params += fmt.Sprintf(" synth %s %s %.1f", track, pattern.ID, a.target)
+	}
+	key, err := a.cache.Key(sources, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.cache.Open(key, ".wav", func(f *os.File) error {
+		b, err := a.playAlong(st, track, pattern, settings.BPM)
+		if err != nil {
+			return err
+		}
+
+		mixed, err := metronome.Overlay(b, settings, clickGain)
+		if err != nil {
+			return errors.Wrap(err, "mixing clicks")
+		}
+		return audio.EncodeWAV(f, mixed)
+	})
+}
+
+// playAlong returns a track at the tempo of the clicks, starting on the beat
+// that falls on the first click after the count-in. Exercises are
+// synthesized at it. Their recordings are stretched to it note by note, each
+// detected note to its length in the notation. Duets are stretched as a
+// whole from the beat detected in them.
+func (a *Audio) playAlong(st *State, track string, pattern rhythm.Pattern, bpm int) (*audio.Buffer, error) {
+	beat := time.Minute / time.Duration(bpm)
+	asset, exercise := render.ParseAssetPath(track)
+	if exercise && (!st.Assets.Has(track) || pattern.ID != rhythm.Default) {
+		return a.synthesizeBuffer(asset, pattern, beat)
+	}
+
+	b, err := a.track(st, track, pattern)
+	if err != nil {
+		return nil, err
+	}
+	if !exercise {
+		return stretchBeat(b, beat), nil
+	}
+
+	chords, err := exerciseChords(asset, pattern)
+	if err != nil {
+		return nil, err
+	}
+	t, err := a.recordingTiming(track, asset)
+	if err != nil {
+		return nil, err
+	}
+	lengths := make([]int, len(t.Notes))
+	for i := range lengths {
+		lengths[i] = rhythm.Beat
+		if i < len(chords) {
+			lengths[i] = chords[i].Length()
+		}
+	}
+	pace := notePace(t, lengths)
+	if pace <= 0 {
+		return b, nil
+	}
+	return stretchNotes(b, t, lengths, beat, pace), nil
+}
+
+// stretchBeat stretches a recording from the beat detected in it to beat,
+// dropping anything before its first beat. A recording with too few notes
+// to tell its beat is left as it is.
+func stretchBeat(b *audio.Buffer, beat time.Duration) *audio.Buffer {
+	detected, first := audio.DetectBeat(audio.DetectOnsets(b))
+	if detected <= 0 {
+		return b
+	}
+	start := int(first.Seconds() * float64(b.SampleRate))
+	if start >= b.Frames() {
+		return b
+	}
+	seg := audio.NewBuffer(b.SampleRate, b.Channels, b.Frames()-start)
+	copy(seg.Samples, b.Samples[start*b.Channels:])
+	return audio.Stretch(seg, detected.Seconds()/beat.Seconds())
+}
+
+// stretchNotes stretches each note of a recording to its length in the
+// notation, in divisions of a beat, since recordings are not played in
+// strict time and a single tempo change would drift off the clicks. The
+// note after the last one detected is stretched from pace, the time the
+// recording takes over a beat, and anything before the first note is
+// dropped.
+func stretchNotes(b *audio.Buffer, t timing, lengths []int, beat, pace time.Duration) *audio.Buffer {
+	rate := float64(b.SampleRate)
+	frame := func(seconds float64) int {
+		f := int(seconds * rate)
+		if f > b.Frames() {
+			f = b.Frames()
+		}
+		return f
+	}
+
+	var segments []*audio.Buffer
+	var total, at int
+	for i, n := range t.Notes {
+		start, end := frame(n.Start), b.Frames()
+		tempo := pace.Seconds() / beat.Seconds()
+		at += lengths[i]
+		if i+1 < len(t.Notes) {
+			end = frame(t.Notes[i+1].Start)
+			// Place each note from the count of divisions rather than
+			// adding up rounded lengths, so the rounding does not build up.
+			want := int(float64(at)/rhythm.Beat*beat.Seconds()*rate) - total
+			if end <= start || want <= 0 {
+				continue
+			}
+			tempo = float64(end-start) / float64(want)
+		}
+		if end <= start {
+			continue
+		}
+		seg := audio.NewBuffer(b.SampleRate, b.Channels, end-start)
+		copy(seg.Samples, b.Samples[start*b.Channels:end*b.Channels])
+		seg = audio.Stretch(seg, tempo)
+		segments = append(segments, seg)
+		total += seg.Frames()
+	}
+
+	out := audio.NewBuffer(b.SampleRate, b.Channels, total)
+	var pos int
+	for _, seg := range segments {
+		copy(out.Samples[pos:], seg.Samples)
+		pos += len(seg.Samples)
+	}
+	return out
+}
+
+// notePace returns the time most notes of a timing take over a beat, given
+// the lengths of the notes in divisions of a beat, or 0 when it has too few
+// notes to tell.
+func notePace(t timing, lengths []int) time.Duration {
+	if len(t.Notes) < 2 {
+		return 0
+	}
+	paces := make([]float64, len(t.Notes)-1)
+	for i := range paces {
+		paces[i] = (t.Notes[i+1].Start - t.Notes[i].Start) * rhythm.Beat / float64(lengths[i])
+	}
+	sort.Float64s(paces)
+	return time.Duration(paces[len(paces)/2] * float64(time.Second))
+}
+
+// isPlayAlong reports whether a path is a recording clicks can be mixed
+// under: an exercise of the scale page or a duet.
+func isPlayAlong(track string) bool {
+	if path.Ext(track) != ".mp3" {
+		return false
+	}
+	if _, ok := render.ParseAssetPath(track); ok {
+		return true
+	}
+	return strings.HasPrefix(track, "mp3/duet/")
+}
+
+// parseMetronome reads the metronome settings from a form, falling back to
+// 80 BPM in 4/4 with the accents of the meter and a bar of count-in.
+func parseMetronome(form url.Values) metronome.Settings {
+	atoi := func(options []render.Option) int {
+		n, _ := strconv.Atoi(selectedOption(options))
+		return n
+	}
+	return metronome.Settings{
+		BPM:         formInt(form, "BPM", 80, metronome.MinBPM, metronome.MaxBPM),
+		Meter:       selectedOption(render.SetMeterOptions(form.Get("Meter"))),
+		Accent:      selectedOption(render.SetAccentOptions(form.Get("Accent"))),
+		Subdivision: atoi(render.SetSubdivisionOptions(form.Get("Subdivision"))),
+		CountIn:     atoi(render.SetCountInOptions(form.Get("CountIn"))),
+		SilentEvery: atoi(render.SetSilentBarOptions(form.Get("SilentEvery"))),
+	}
+}
+
+// metronomeQuery builds the /metronome query for the settings.
+func metronomeQuery(settings metronome.Settings) url.Values {
+	return url.Values{
+		"BPM":         {strconv.Itoa(settings.BPM)},
+		"Meter":       {settings.Meter},
+		"Accent":      {settings.Accent},
+		"Subdivision": {strconv.Itoa(settings.Subdivision)},
+		"CountIn":     {strconv.Itoa(settings.CountIn)},
+		"SilentEvery": {strconv.Itoa(settings.SilentEvery)},
+	}
+}
+
+// metronomeVars returns the metronome options for the scale page along
+// with the urls of the clicks alone and mixed under the track.
+func metronomeVars(form url.Values, track string) render.Metronome {
+	settings := parseMetronome(form)
+	query := metronomeQuery(settings)
+	m := render.Metronome{
+		BPM:          settings.BPM,
+		Meters:       render.SetMeterOptions(settings.Meter),
+		Accents:      render.SetAccentOptions(settings.Accent),
+		Subdivisions: render.SetSubdivisionOptions(query.Get("Subdivision")),
+		CountIns:     render.SetCountInOptions(query.Get("CountIn")),
+		SilentBars:   render.SetSilentBarOptions(query.Get("SilentEvery")),
+		Path:         "/metronome?" + query.Encode(),
+	}
+	if isPlayAlong(track) {
+		query.Set("Track", track)
+		if pattern := parsePattern(form); pattern.ID != rhythm.Default {
+			query.Set("Rhythm", pattern.ID)
+		}
+		m.MixPath = "/metronome?" + query.Encode()
+	}
+	return m
+}
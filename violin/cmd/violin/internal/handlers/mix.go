@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"bufio"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"violin/internal/audio"
+	"violin/internal/loglevel"
+	"violin/internal/render"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sync/singleflight"
+)
+
+// mixWait is how long a request waits for a mix to render, well within the
+// server's write timeout. Mixes that take longer keep rendering and the
+// request is answered 202 Accepted, to be asked again.
+const mixWait = 2 * time.Second
+
+// mixWorkers is how many mixes render at once. Each one holds both decoded
+// parts in memory, so requests for more are turned away until one is done.
+const mixWorkers = 2
+
+// errMixerBusy is returned when every mix worker is rendering.
+var errMixerBusy = errors.New("every mix worker is busy")
+
+// Mixer represents the handlers that render play-along mixes of the duets.
+type Mixer struct {
+	log     *loglevel.Logger
+	cache   *audio.Cache
+	renders singleflight.Group
+	workers chan struct{}
+}
+
+// Render handles GET and HEAD calls for /duetmix. It mixes the two parts of
+// a duet with the volume, mute, pan and tempo settings in the query and
+// serves the result as a WAV file. Mixes are rendered in the background by
+// GET calls: until one is ready the answer is 202 Accepted with a
+// Retry-After header, and the duet page asks again. When every worker is
+// busy the answer is 503 Service Unavailable. HEAD calls only look in the
+// cache.
+func (m *Mixer) Render(w http.ResponseWriter, r *http.Request) {
+	m.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	duet := query.Get("Duet")
+	if !hasOption(stateOf(r).duets, duet) {
+		http.Error(w, "unknown duet", http.StatusBadRequest)
+		return
+	}
+	settings := parseDuetMix(query)
+
+	_, _, part1, part2 := duetAssetPaths(duet)
+	key, err := m.cache.Key([]string{part1, part2}, "mix "+mixQuery("", settings).Encode())
+	if err != nil {
+		m.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	f, ok := m.cache.Lookup(key, ".wav")
+	if !ok && r.Method == http.MethodHead {
+		http.Error(w, "not mixed yet", http.StatusNotFound)
+		return
+	}
+	if !ok {
+		// Requests for a mix being rendered share its render, which goes
+		// on after they time out so that the next request finds it cached.
+		rendered := m.renders.DoChan(key, func() (interface{}, error) {
+			select {
+			case m.workers <- struct{}{}:
+			default:
+				return nil, errMixerBusy
+			}
+			defer func() { <-m.workers }()
+
+			f, err := m.mix(key, part1, part2, settings)
+			if err != nil {
+				return nil, err
+			}
+			return nil, f.Close()
+		})
+
+		select {
+		case res := <-rendered:
+			err = res.Err
+		case <-time.After(mixWait):
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "still mixing, try again shortly", http.StatusAccepted)
+			return
+		}
+		if errors.Is(err, errMixerBusy) {
+			w.Header().Set("Retry-After", "5")
+			http.Error(w, "the mixer is busy, try again shortly", http.StatusServiceUnavailable)
+			return
+		}
+		if err == nil {
+			if f, ok = m.cache.Lookup(key, ".wav"); !ok {
+				err = errors.New("mix missing from the cache")
+			}
+		}
+		if err != nil {
+			m.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		m.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "audio/wav")
+	http.ServeContent(w, r, duet+"mix.wav", info.ModTime(), f)
+}
+
+// mix returns the cached WAV file under key of the parts mixed with the
+// settings, rendering it on first use.
+func (m *Mixer) mix(key, part1, part2 string, settings render.DuetMix) (*os.File, error) {
+	return m.cache.Open(key, ".wav", func(f *os.File) error {
+		// Decoding dominates the first render of a duet, so decode both
+		// parts at the same time.
+		var b1, b2 *audio.Buffer
+		var err1, err2 error
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			b1, err1 = decodePart(m.cache, part1)
+		}()
+		go func() {
+			defer wg.Done()
+			b2, err2 = decodePart(m.cache, part2)
+		}()
+		wg.Wait()
+		if err1 != nil {
+			return err1
+		}
+		if err2 != nil {
+			return err2
+		}
+
+		mixed, err := audio.Mix([]audio.Track{
+			{Buffer: b1, Gain: float64(settings.Volume1) / 100, Pan: float64(settings.Pan1) / 100, Mute: settings.Mute1},
+			{Buffer: b2, Gain: float64(settings.Volume2) / 100, Pan: float64(settings.Pan2) / 100, Mute: settings.Mute2},
+		})
+		if err != nil {
+			return errors.Wrap(err, "mixing parts")
+		}
+		return audio.EncodeWAV(f, audio.Stretch(mixed, float64(settings.Tempo)/100))
+	})
+}
+
+// decodePart returns the samples of a recording, decoding it to a cached
+// WAV file on first use so later mixes skip the MP3 decoder.
+func decodePart(cache *audio.Cache, path string) (*audio.Buffer, error) {
+	key, err := cache.Key([]string{path}, "pcm")
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := cache.Open(key, ".wav", func(f *os.File) error {
+		src, err := os.Open(path)
+		if err != nil {
+			return errors.Wrap(err, "opening part")
+		}
+		defer src.Close()
+
+		s, err := audio.NewMP3Decoder(src)
+		if err != nil {
+			return errors.Wrapf(err, "decoding %s", path)
+		}
+		return audio.WriteWAV(f, s)
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return audio.DecodeWAV(bufio.NewReader(f))
+}
+
+// parseDuetMix reads the mixer settings from a form, falling back to both
+// parts at full volume, centred and at the recorded tempo.
+func parseDuetMix(form url.Values) render.DuetMix {
+	return render.DuetMix{
+		Volume1: formInt(form, "Volume1", 100, 0, 100),
+		Volume2: formInt(form, "Volume2", 100, 0, 100),
+		Mute1:   form.Get("Mute1") != "",
+		Mute2:   form.Get("Mute2") != "",
+		Pan1:    formInt(form, "Pan1", 0, -100, 100),
+		Pan2:    formInt(form, "Pan2", 0, -100, 100),
+		Tempo:   formInt(form, "Tempo", 100, 50, 150),
+	}
+}
+
+// mixQuery builds the /duetmix query for a duet and mixer settings.
+func mixQuery(duet string, settings render.DuetMix) url.Values {
+	q := url.Values{
+		"Duet":    {duet},
+		"Volume1": {strconv.Itoa(settings.Volume1)},
+		"Volume2": {strconv.Itoa(settings.Volume2)},
+		"Pan1":    {strconv.Itoa(settings.Pan1)},
+		"Pan2":    {strconv.Itoa(settings.Pan2)},
+		"Tempo":   {strconv.Itoa(settings.Tempo)},
+	}
+	if settings.Mute1 {
+		q.Set("Mute1", "on")
+	}
+	if settings.Mute2 {
+		q.Set("Mute2", "on")
+	}
+	return q
+}
+
+// formInt reads an integer form value, returning def when it is missing or
+// malformed and clamping it to [lo, hi].
+func formInt(form url.Values, name string, def, lo, hi int) int {
+	v, err := strconv.Atoi(form.Get(name))
+	if err != nil {
+		return def
+	}
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+// duetMix returns the mixer settings for the duet page along with the url
+// of the mix they produce.
+func duetMix(duet string, form url.Values) render.DuetMix {
+	settings := parseDuetMix(form)
+	settings.Path = "/duetmix?" + mixQuery(duet, settings).Encode()
+	return settings
+}
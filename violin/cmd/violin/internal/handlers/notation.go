@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"violin/internal/loglevel"
+	"violin/internal/metronome"
+	"violin/internal/notation"
+	"violin/internal/practice"
+	"violin/internal/render"
+	"violin/internal/rhythm"
+	"violin/internal/theory"
+
+	"github.com/pkg/errors"
+)
+
+// Notation represents the handlers that serve the notation images, drawing
+// the scales and arpeggios that have no engraved image.
+type Notation struct {
+	log   *loglevel.Logger
+	files http.Handler
+}
+
+// Image handles GET calls for /img/<image>. Images in the img folder are
+// served as they are, missing scale and arpeggio images redirect to their
+// drawn notation.
+func (n *Notation) Image(w http.ResponseWriter, r *http.Request) {
+	src := strings.TrimPrefix(r.URL.Path, "/")
+	if !stateOf(r).Assets.Has(src) {
+		if _, ok := render.ParseAssetPath(src); ok {
+			http.Redirect(w, r, notationLink(src), http.StatusFound)
+			return
+		}
+	}
+	n.files.ServeHTTP(w, r)
+}
+
+// Draw handles GET calls for /notation/<scale>.<format>, such as
+// /notation/scale/major/a3.svg?Rhythm=dotted, writing the exercise the
+// matching img path would hold in the rhythm and bowing pattern. The format
+// is svg for notation, mid for a MIDI file at BPM beats per minute or
+// musicxml for a MusicXML score.
+func (n *Notation) Draw(w http.ResponseWriter, r *http.Request) {
+	n.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	src := strings.TrimPrefix(r.URL.Path, "/notation/")
+	ext := path.Ext(src)
+	if ext != ".svg" && ext != ".mid" && ext != ".musicxml" {
+		http.NotFound(w, r)
+		return
+	}
+	asset, ok := render.ParseAssetPath("img/" + strings.TrimSuffix(src, ext) + ".png")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	query := r.URL.Query()
+	chords, err := exerciseChords(asset, parsePattern(query))
+	if err != nil {
+		n.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	sig, err := theory.KeySignature(asset.Key, asset.Pitch)
+	if err != nil {
+		n.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	switch ext {
+	case ".svg":
+		w.Header().Set("Content-Type", "image/svg+xml")
+		err = notation.WriteChords(w, notationTitle(asset), sig, chords)
+	case ".mid":
+		w.Header().Set("Content-Type", "audio/midi")
+		bpm := formInt(query, "BPM", synthBPM, metronome.MinBPM, metronome.MaxBPM)
+		err = notation.WriteMIDI(w, sig, bpm, chords)
+	case ".musicxml":
+		w.Header().Set("Content-Type", "application/vnd.recordare.musicxml+xml")
+		err = notation.WriteMusicXML(w, notationTitle(asset), sig, chords)
+	}
+	if err != nil {
+		n.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		return
+	}
+}
+
+// exerciseChords returns what an exercise plays in the rhythm and bowing
+// pattern, a chord at a time. Scales and arpeggios play one note at a time,
+// double stops two, marked unplayable where no fingering of the exercise
+// reaches them.
+func exerciseChords(asset render.Asset, pattern rhythm.Pattern) ([]notation.Chord, error) {
+	octaves, err := strconv.Atoi(asset.Octave)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing octave %q", asset.Octave)
+	}
+
+	if theory.IsDoubleStop(asset.Scale) {
+		stops, err := theory.DoubleStops(asset.Key, asset.Pitch, asset.Scale, octaves)
+		if err != nil {
+			return nil, err
+		}
+		steps := pattern.Apply(len(stops))
+		chords := make([]notation.Chord, len(stops))
+		for i, s := range stops {
+			chords[i] = notation.Chord{Notes: []theory.Note{s.Low, s.High}, Unplayable: !s.Playable, Step: steps[i]}
+		}
+		return chords, nil
+	}
+
+	notes, err := theory.Scale(asset.Key, asset.Pitch, asset.Scale, octaves, asset.Melodic)
+	if err != nil {
+		return nil, err
+	}
+	steps := pattern.Apply(len(notes))
+	chords := make([]notation.Chord, len(notes))
+	for i, n := range notes {
+		chords[i] = notation.Chord{Notes: []theory.Note{n}, Step: steps[i]}
+	}
+	return chords, nil
+}
+
+// parsePattern reads the rhythm and bowing pattern from a form, falling
+// back to separate bows.
+func parsePattern(form url.Values) rhythm.Pattern {
+	p, _ := rhythm.Find(selectedOption(render.SetRhythmOptions(form.Get("Rhythm"))))
+	return p
+}
+
+// rhythmLink adds the pattern to a link to notation or audio, unless it is
+// the plain one the recordings and images are in.
+func rhythmLink(link string, pattern rhythm.Pattern) string {
+	if pattern.ID == rhythm.Default {
+		return link
+	}
+	return link + "?" + url.Values{"Rhythm": {pattern.ID}}.Encode()
+}
+
+// notationLink returns the url of the drawn notation for an img path.
+func notationLink(img string) string {
+	return "/notation/" + strings.TrimSuffix(strings.TrimPrefix(img, "img/"), ".png") + ".svg"
+}
+
+// notationTitle names an exercise, such as "C# Harmonic Minor Scale,
+// 3 Octaves" or "Dominant 7th in the Key of G, 2 Octaves".
+func notationTitle(asset render.Asset) string {
+	pitch := asset.Pitch
+	if asset.Pitch == "Minor" && asset.Scale == "Scale" {
+		pitch = "Harmonic Minor"
+	}
+	octaves := "Octaves"
+	if asset.Octave == "1" {
+		octaves = "Octave"
+	}
+	return practice.Title(asset.Scale, pitch, asset.Key) + ", " + asset.Octave + " " + octaves
+}
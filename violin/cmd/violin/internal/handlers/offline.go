@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"violin/internal/assets"
+	"violin/internal/i18n"
+	"violin/internal/loglevel"
+	"violin/internal/notation"
+	"violin/internal/practice"
+	"violin/internal/render"
+	"violin/internal/theory"
+
+	"github.com/pkg/errors"
+)
+
+// maxBundleItems is how many items one asset bundle may hold.
+const maxBundleItems = 64
+
+// shellPages are the pages the service worker precaches so the site opens
+// without a connection.
+var shellPages = []string{
+	"/",
+	"/scale",
+	"/duets",
+	"/keys",
+	"/css/main.css",
+	"/offline.js",
+	"/manifest.webmanifest",
+	"/icon.svg",
+}
+
+// Offline represents the handlers that let GoViolin work without a
+// connection: the web app manifest, the service worker and the asset
+// bundles of the keys and duets students keep offline.
+type Offline struct {
+	log *loglevel.Logger
+}
+
+// bundleFile is a file of an asset bundle, the url it is fetched from and
+// the version it has on the server.
+type bundleFile struct {
+	URL     string `json:"url"`
+	Size    int64  `json:"size"`
+	Version string `json:"version"`
+}
+
+// bundleItem holds the pages and files of one item kept offline.
+type bundleItem struct {
+	Item  string       `json:"item"`
+	Pages []string     `json:"pages"`
+	Files []bundleFile `json:"files"`
+}
+
+// bundle is the answer of the asset bundle endpoint.
+type bundle struct {
+	Version string       `json:"version"`
+	Items   []bundleItem `json:"items"`
+}
+
+// Manifest handles GET calls for /manifest.webmanifest, which lets browsers
+// install GoViolin as an app.
+func (o *Offline) Manifest(w http.ResponseWriter, r *http.Request) {
+	o.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	type icon struct {
+		Src   string `json:"src"`
+		Sizes string `json:"sizes"`
+		Type  string `json:"type"`
+	}
+	manifest := struct {
+		Name            string `json:"name"`
+		ShortName       string `json:"short_name"`
+		Description     string `json:"description"`
+		Lang            string `json:"lang"`
+		StartURL        string `json:"start_url"`
+		Scope           string `json:"scope"`
+		Display         string `json:"display"`
+		BackgroundColor string `json:"background_color"`
+		ThemeColor      string `json:"theme_color"`
+		Icons           []icon `json:"icons"`
+	}{
+		Name:            "GoViolin",
+		ShortName:       "GoViolin",
+		Description:     localeOf(r).T("GoViolin is a helpful way to practice violin written in Go."),
+		Lang:            localeOf(r).Language,
+		StartURL:        "/",
+		Scope:           "/",
+		Display:         "standalone",
+		BackgroundColor: "#f8f8f8",
+		ThemeColor:      "#4b5786",
+		Icons:           []icon{{Src: "/icon.svg", Sizes: "any", Type: "image/svg+xml"}},
+	}
+
+	w.Header().Set("Content-Type", "application/manifest+json")
+	w.Header().Set("Vary", "Accept-Language, Cookie")
+	if err := json.NewEncoder(w).Encode(manifest); err != nil {
+		o.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		return
+	}
+}
+
+// Icon handles GET calls for /icon.svg, the icon of the installed app.
+func (o *Offline) Icon(w http.ResponseWriter, r *http.Request) {
+	o.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	if err := notation.WriteIcon(w); err != nil {
+		o.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		return
+	}
+}
+
+// Script handles GET calls for /offline.js, which registers the service
+// worker on every page and runs the toggles that keep keys and duets
+// offline.
+func (o *Offline) Script(w http.ResponseWriter, r *http.Request) {
+	o.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+	http.ServeFile(w, r, "templates/offline.js")
+}
+
+// Worker handles GET calls for /sw.js, the service worker. Its precache
+// list is the pages of the site and every image in the asset index, with
+// the version each file has, so the script changes whenever an asset does
+// and browsers install it again, fetching only the files that changed.
+func (o *Offline) Worker(w http.ResponseWriter, r *http.Request) {
+	o.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	ix := stateOf(r).Assets
+	config := struct {
+		Version string       `json:"version"`
+		Pages   []string     `json:"pages"`
+		Files   []bundleFile `json:"files"`
+	}{
+		Version: ix.Version(),
+		Pages:   shellPages,
+		Files:   []bundleFile{},
+	}
+	for _, f := range ix.Files() {
+		if strings.HasPrefix(f.Path, "img/") {
+			config.Files = append(config.Files, assetFile(f))
+		}
+	}
+	js, err := json.Marshal(config)
+	if err != nil {
+		o.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	t, err := template.ParseFiles("templates/sw.js")
+	if err != nil {
+		o.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	if err := t.Execute(w, string(js)); err != nil {
+		o.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		return
+	}
+}
+
+// Bundle handles GET calls for /api/v1/bundle?item=<item>, the asset bundle
+// of each item kept offline: every exercise of a key in a pitch, such as
+// key/minor/cs-db, or a duet, such as duet/g. It lists the pages to keep
+// and the files with their versions, so clients fetch only the files whose
+// version changed. The ETag follows the bundle, answering 304 Not Modified
+// when nothing did.
+func (o *Offline) Bundle(w http.ResponseWriter, r *http.Request) {
+	o.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		respondError(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+	items := r.URL.Query()["item"]
+	if len(items) > maxBundleItems {
+		respondError(w, http.StatusBadRequest, "at most "+strconv.Itoa(maxBundleItems)+" items")
+		return
+	}
+
+	ix := stateOf(r).Assets
+	b := bundle{Version: ix.Version(), Items: []bundleItem{}}
+	for _, id := range items {
+		it, err := o.bundleItem(ix, id)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		b.Items = append(b.Items, it)
+	}
+
+	body, err := json.Marshal(b)
+	if err != nil {
+		o.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		respondError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+	sum := sha1.Sum(body)
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(body, '\n'))
+}
+
+// bundleItem collects the pages and files of an item kept offline. Only
+// indexed files are kept: exercises nobody recorded are synthesized and
+// drones worked out as they play, both of which need the server.
+func (o *Offline) bundleItem(ix *assets.Index, id string) (bundleItem, error) {
+	it := bundleItem{Item: id, Pages: []string{}, Files: []bundleFile{}}
+	add := func(paths ...string) bool {
+		found := false
+		for _, p := range paths {
+			if f, ok := ix.File(p); ok {
+				it.Files = append(it.Files, assetFile(f))
+				found = true
+			}
+		}
+		return found
+	}
+
+	if strings.HasPrefix(id, "duet/") {
+		item, err := practice.ParseItem(id)
+		if err != nil {
+			return it, err
+		}
+		if !add(duetAssetPaths(item.Key)) {
+			return it, errors.Errorf("invalid item %q", id)
+		}
+		it.Pages = append(it.Pages, itemLink(item))
+		return it, nil
+	}
+
+	pitch, key, ok := parseKeyItem(id)
+	if !ok {
+		return it, errors.Errorf("invalid item %q", id)
+	}
+	spelling := selectedOption(render.SetSpellingOptions(pitch, key, ""))
+	for _, kind := range practice.ScaleKinds {
+		for octave := 1; octave <= theory.MaxOctaves(key, pitch, kind); octave++ {
+			img, audio, audio2 := exercisePaths(pitch, kind, key, spelling, strconv.Itoa(octave))
+			paths := []string{img, audio}
+			if !strings.HasPrefix(audio2, "mp3/drone/") {
+				paths = append(paths, audio2)
+			}
+			if add(paths...) {
+				it.Pages = append(it.Pages, itemLink(practice.Item{Kind: kind, Pitch: pitch, Key: key, Octave: strconv.Itoa(octave)}))
+			}
+		}
+	}
+	return it, nil
+}
+
+// assetFile returns the url an indexed file is fetched from, recordings
+// through the audio handler that normalizes them, with its version.
+func assetFile(f assets.File) bundleFile {
+	url := "/" + f.Path
+	if strings.HasPrefix(f.Path, "mp3/") {
+		url = "/audio/" + f.Path
+	}
+	return bundleFile{URL: url, Size: f.Size, Version: f.Version()}
+}
+
+// keyItem returns the id a key in a pitch is kept offline under, such as
+// "key/minor/cs-db".
+func keyItem(pitch, key string) string {
+	return "key/" + strings.ToLower(pitch) + "/" + practice.KeySlug(key)
+}
+
+// parseKeyItem reverses keyItem, reporting false for ids that are not one
+// of the scale page's keys.
+func parseKeyItem(id string) (pitch, key string, ok bool) {
+	for _, p := range render.SetPitchOptions("Major") {
+		for _, k := range render.SetKeyOptions("A") {
+			if keyItem(p.Value, k.Value) == id {
+				return p.Value, k.Value, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// keyToggle returns the toggle that keeps a key in a pitch offline.
+func keyToggle(loc i18n.Locale, pitch, key string) render.Offline {
+	name := loc.T("%s major", loc.Note(key))
+	if pitch == "Minor" {
+		name = loc.T("%s minor", loc.Note(key))
+	}
+	return render.Offline{Item: keyItem(pitch, key), Name: name}
+}
+
+// duetToggle returns the toggle that keeps the duet in a key offline.
+func duetToggle(loc i18n.Locale, key string) render.Offline {
+	return render.Offline{Item: practice.DuetItem(key), Name: loc.T("%s major", loc.Note(key))}
+}
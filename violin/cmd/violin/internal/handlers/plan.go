@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"violin/internal/drone"
+	"violin/internal/loglevel"
+	"violin/internal/planner"
+	"violin/internal/practice"
+	"violin/internal/render"
+	"violin/internal/studio"
+	"violin/internal/user"
+
+	"github.com/pkg/errors"
+)
+
+// Planner represents the handlers for the daily practice routine.
+type Planner struct {
+	log      *loglevel.Logger
+	users    *user.Store
+	practice *practice.Store
+	plans    *planner.Store
+	studios  *studio.Store
+}
+
+// Today handles GET calls for the today's practice page. It walks through the
+// routine one item at a time, rendering each item the same way the scale and
+// duet pages do.
+func (p *Planner) Today(w http.ResponseWriter, r *http.Request) {
+	p.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	plan := p.today(w, r)
+
+	// Default to the first item not yet rated today.
+	step := -1
+	if s, err := strconv.Atoi(r.URL.Query().Get("step")); err == nil && s >= 0 && s < len(plan.Entries) {
+		step = s
+	}
+	for i, e := range plan.Entries {
+		if step >= 0 {
+			break
+		}
+		if !e.Done {
+			step = i
+		}
+	}
+
+	pv := render.PageVars{
+		Title:  "Today's Practice",
+		Plan:   plan,
+		Step:   step,
+		Locale: localeOf(r),
+	}
+
+	if step >= 0 {
+		entry := plan.Entries[step]
+		it, err := practice.ParseItem(entry.Item)
+		if err != nil {
+			p.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		pv.Item = entry.Item
+		pv.Key = it.Key
+		pv.Pitch = it.Pitch
+		pv.Scale = it.Kind
+		if it.Kind == practice.KindDuet {
+			pv.DuetImgPath, pv.DuetAudioBoth, pv.DuetAudio1, pv.DuetAudio2 = duetAssetPaths(it.Key)
+			pv.ScaleImgPath = pv.DuetImgPath
+			pv.AudioPath = pv.DuetAudioBoth
+			pv.LeftLabel = "Listen to both parts"
+		} else {
+			spelling := selectedOption(render.SetSpellingOptions(it.Pitch, it.Key, ""))
+			pv.LeftLabel, pv.RightLabel = render.SetMusicLabels(pv.Locale, it.Pitch, it.Kind)
+			pv.ScaleImgPath, pv.AudioPath, pv.AudioPath2 = exercisePaths(it.Pitch, it.Kind, it.Key, spelling, it.Octave)
+			pv.DronePath = droneLink(pv.AudioPath2, it.Key, it.Pitch, it.Octave, drone.VoicingSingle, strconv.Itoa(defaultDroneMinutes))
+		}
+	}
+
+	if err := renderPage(w, r, "practice.html", pv); err != nil {
+		p.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		return
+	}
+}
+
+// Rate handles POST calls rating how well an item went.
+func (p *Planner) Rate(w http.ResponseWriter, r *http.Request) {
+	p.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.ParseForm()
+	quality, err := strconv.Atoi(r.PostForm.Get("Quality"))
+	if err != nil {
+		http.Error(w, "invalid quality", http.StatusBadRequest)
+		return
+	}
+
+	err = p.plans.Rate(owner(p.users, w, r), r.PostForm.Get("Item"), quality, time.Now())
+	switch {
+	case err == nil:
+		http.Redirect(w, r, "/practice", http.StatusSeeOther)
+	case errors.Is(err, planner.ErrInvalidRating), errors.Is(err, planner.ErrInvalidItem):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		p.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+// Pin handles POST calls which pin items to, or unpin them from, the routine.
+func (p *Planner) Pin(w http.ResponseWriter, r *http.Request) {
+	p.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.ParseForm()
+	items := make(map[string]bool)
+	for _, item := range r.PostForm["Item"] {
+		items[item] = true
+	}
+	owner := owner(p.users, w, r)
+
+	var pins []string
+	for _, pin := range p.plans.Pins(owner) {
+		if !items[pin] {
+			pins = append(pins, pin)
+		}
+	}
+	if r.PostForm.Get("Pinned") == "true" {
+		pins = append(pins, r.PostForm["Item"]...)
+	}
+
+	err := p.plans.SetPins(owner, pins)
+	switch {
+	case err == nil:
+		http.Redirect(w, r, "/practice", http.StatusSeeOther)
+	case errors.Is(err, planner.ErrInvalidItem):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		p.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+// Plan handles GET calls to /api/v1/plan returning today's routine.
+func (p *Planner) Plan(w http.ResponseWriter, r *http.Request) {
+	p.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		respondError(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+	respond(w, http.StatusOK, p.today(w, r))
+}
+
+// Pins handles calls to /api/v1/plan/pins. GET returns the pinned items and
+// PUT replaces them with the JSON array of item ids in the body.
+func (p *Planner) Pins(w http.ResponseWriter, r *http.Request) {
+	p.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	owner := owner(p.users, w, r)
+
+	switch r.Method {
+	case http.MethodGet:
+		pins := p.plans.Pins(owner)
+		if pins == nil {
+			pins = []string{}
+		}
+		respond(w, http.StatusOK, pins)
+
+	case http.MethodPut:
+		var pins []string
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&pins); err != nil {
+			respondError(w, http.StatusBadRequest, "decoding pins: "+err.Error())
+			return
+		}
+
+		err := p.plans.SetPins(owner, pins)
+		switch {
+		case err == nil:
+			respond(w, http.StatusOK, p.plans.Pins(owner))
+		case errors.Is(err, planner.ErrInvalidItem):
+			respondError(w, http.StatusBadRequest, err.Error())
+		default:
+			p.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+			respondError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		}
+
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		respondError(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+	}
+}
+
+// today builds the routine of the caller for today. Logged in students have
+// the items of their open assignments pinned.
+func (p *Planner) today(w http.ResponseWriter, r *http.Request) planner.Plan {
+	owner := owner(p.users, w, r)
+
+	var required []string
+	if u, ok := currentUser(p.users, r); ok && !u.IsTeacher() {
+		for _, a := range p.studios.StudentAssignments(u.ID) {
+			if a.Completed() {
+				continue
+			}
+			for _, t := range a.Tasks {
+				required = append(required, t.Item)
+			}
+		}
+	}
+
+	return p.plans.Today(owner, scaleCatalog(), required, p.practice.Sessions(owner), time.Now())
+}
+
+// scaleCatalog lists every exercise the scale page offers, leaving out the
+// octaves that do not fit the violin in some keys and the minor items of
+// exercises that are only played as major, neither of which parse.
+func scaleCatalog() []string {
+	scales, pitches, keys, octaves := render.SetDefaultOptions()
+	var items []string
+	for _, id := range planner.Catalog(optionValues(scales), optionValues(pitches), optionValues(keys), optionValues(octaves)) {
+		if _, err := practice.ParseItem(id); err == nil {
+			items = append(items, id)
+		}
+	}
+	return items
+}
+
+// optionValues returns the values of the options.
+func optionValues(options []render.Option) []string {
+	values := make([]string, len(options))
+	for i, o := range options {
+		values[i] = o.Value
+	}
+	return values
+}
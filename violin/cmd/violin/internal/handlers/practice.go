@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"violin/internal/loglevel"
+	"violin/internal/practice"
+	"violin/internal/render"
+	"violin/internal/user"
+
+	"github.com/pkg/errors"
+)
+
+// Practice represents the handlers for logging and reviewing practice.
+type Practice struct {
+	log      *loglevel.Logger
+	users    *user.Store
+	practice *practice.Store
+}
+
+// sessionEvent is the body posted by the scale and duet pages when playback
+// starts or stops.
+type sessionEvent struct {
+	Item  string `json:"item"`
+	Event string `json:"event"`
+}
+
+// Sessions handles calls to /api/v1/sessions. POST records a start or stop
+// event, GET returns the practice summary for the caller.
+func (p *Practice) Sessions(w http.ResponseWriter, r *http.Request) {
+	p.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	owner := owner(p.users, w, r)
+
+	switch r.Method {
+	case http.MethodGet:
+		respond(w, http.StatusOK, practice.Summarize(p.practice.Sessions(owner), time.Now()))
+
+	case http.MethodPost:
+		var ev sessionEvent
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<12)).Decode(&ev); err != nil {
+			respondError(w, http.StatusBadRequest, "decoding event: "+err.Error())
+			return
+		}
+
+		if it, err := practice.ParseItem(ev.Item); err == nil && it.Kind == practice.KindDuet && !hasOption(stateOf(r).duets, it.Key) {
+			respondError(w, http.StatusBadRequest, "no duet in "+it.Key)
+			return
+		}
+
+		err := p.practice.Record(owner, ev.Item, ev.Event, time.Now())
+		switch {
+		case err == nil:
+			w.WriteHeader(http.StatusNoContent)
+		case errors.Is(err, practice.ErrInvalidEvent):
+			respondError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, practice.ErrTooMany):
+			respondError(w, http.StatusTooManyRequests, err.Error())
+		default:
+			p.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+			respondError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		}
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		respondError(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+	}
+}
+
+// Progress handles GET calls for the progress page.
+func (p *Practice) Progress(w http.ResponseWriter, r *http.Request) {
+	p.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	owner := owner(p.users, w, r)
+
+	pv := render.PageVars{
+		Title:    "Practice Progress",
+		Progress: practice.Summarize(p.practice.Sessions(owner), time.Now()),
+		Locale:   localeOf(r),
+	}
+	if u, ok := currentUser(p.users, r); ok {
+		pv.UserName = u.Name
+	}
+
+	if err := renderPage(w, r, "progress.html", pv); err != nil {
+		p.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		return
+	}
+}
@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"violin/internal/audio"
+	"violin/internal/drone"
+	"violin/internal/loglevel"
+	"violin/internal/quiz"
+	"violin/internal/render"
+	"violin/internal/rhythm"
+	"violin/internal/user"
+
+	"github.com/pkg/errors"
+)
+
+// Lengths of the sounds of quiz questions.
+const (
+	quizNoteLength   = time.Second     // each note of an interval
+	quizDroneLead    = time.Second     // the drone alone before a tuning note
+	quizTuningLength = 3 * time.Second // the tuning note over the drone
+)
+
+// Quiz represents the ear training handlers. Questions are played with the
+// recordings and synthesizer of the audio handlers.
+type Quiz struct {
+	log     *loglevel.Logger
+	users   *user.Store
+	quizzes *quiz.Store
+	audio   *Audio
+}
+
+// Page handles GET and POST calls for the ear training page. GET asks a new
+// question of the Kind, POST scores the Answer to the question with the ID
+// and asks the next one.
+func (q *Quiz) Page(w http.ResponseWriter, r *http.Request) {
+	q.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	owner := owner(q.users, w, r)
+	r.ParseForm()
+	kinds := render.SetQuizKindOptions(r.Form.Get("Kind"))
+	view := render.Quiz{Kinds: kinds, Kind: selectedOption(kinds)}
+
+	if r.Method == http.MethodPost {
+		res, err := q.quizzes.Answer(owner, r.PostForm.Get("ID"), r.PostForm.Get("Answer"))
+		switch {
+		case err == nil:
+			view.Answered, view.Correct = true, res.Correct
+			view.Feedback = feedback(res)
+		case errors.Is(err, quiz.ErrNoQuestion):
+			// Answered already, in another tab or by going back.
+		default:
+			q.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	next, err := q.quizzes.Next(owner, view.Kind, time.Now())
+	if err != nil {
+		q.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	view.ID, view.Level, view.Prompt = next.ID, next.Level, next.Prompt
+	view.AudioPath = quizAudioLink(next)
+	for _, c := range next.Choices {
+		view.Choices = append(view.Choices, render.Option{Name: "Answer", Value: c.Value, Text: c.Text})
+	}
+	view.Stats = render.SetQuizStats(q.quizzes.Stats(owner))
+
+	pv := render.PageVars{
+		Title:  "Ear Training",
+		Quiz:   view,
+		Locale: localeOf(r),
+	}
+	if err := renderPage(w, r, "quiz.html", pv); err != nil {
+		q.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		return
+	}
+}
+
+// questionView is a question as the API sends it, without its answer.
+type questionView struct {
+	ID      string        `json:"id"`
+	Kind    string        `json:"kind"`
+	Level   int           `json:"level"`
+	Prompt  string        `json:"prompt"`
+	Choices []quiz.Choice `json:"choices"`
+	Audio   string        `json:"audio"`
+}
+
+// answer is the body posted to answer a question.
+type answer struct {
+	ID     string `json:"id"`
+	Answer string `json:"answer"`
+}
+
+// API handles calls to /api/v1/quiz. GET asks a new question of the kind,
+// such as /api/v1/quiz?kind=tuning, POST scores an answer and returns the
+// result with the caller's stats of the kind.
+func (q *Quiz) API(w http.ResponseWriter, r *http.Request) {
+	q.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	owner := owner(q.users, w, r)
+
+	switch r.Method {
+	case http.MethodGet:
+		next, err := q.quizzes.Next(owner, r.URL.Query().Get("kind"), time.Now())
+		switch {
+		case err == nil:
+			respond(w, http.StatusOK, questionView{
+				ID:      next.ID,
+				Kind:    next.Kind,
+				Level:   next.Level,
+				Prompt:  next.Prompt,
+				Choices: next.Choices,
+				Audio:   quizAudioLink(next),
+			})
+		case errors.Is(err, quiz.ErrInvalidKind):
+			respondError(w, http.StatusBadRequest, err.Error())
+		default:
+			q.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+			respondError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		}
+
+	case http.MethodPost:
+		var a answer
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<12)).Decode(&a); err != nil {
+			respondError(w, http.StatusBadRequest, "decoding answer: "+err.Error())
+			return
+		}
+
+		res, err := q.quizzes.Answer(owner, a.ID, a.Answer)
+		switch {
+		case err == nil:
+			respond(w, http.StatusOK, res)
+		case errors.Is(err, quiz.ErrNoQuestion):
+			respondError(w, http.StatusNotFound, err.Error())
+		default:
+			q.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+			respondError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		}
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		respondError(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+	}
+}
+
+// Audio handles GET calls for /quiz/audio?ID=<question>, serving what the
+// caller's unanswered question plays as WAV.
+func (q *Quiz) Audio(w http.ResponseWriter, r *http.Request) {
+	q.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	question, ok := q.quizzes.Question(owner(q.users, w, r), r.URL.Query().Get("ID"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	b, err := q.audio.question(stateOf(r), question)
+	if err != nil {
+		q.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	var wav bytes.Buffer
+	if err := audio.EncodeWAV(&wav, b); err != nil {
+		q.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	// The answer must not be found by replaying a cached question.
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "audio/wav")
+	http.ServeContent(w, r, "question.wav", time.Time{}, bytes.NewReader(wav.Bytes()))
+}
+
+// question returns the samples of a quiz question at the target loudness.
+// Intervals are synthesized, scale types use the one octave recordings where
+// there are any and tuning notes are synthesized over a recorded drone.
+func (a *Audio) question(st *State, q quiz.Question) (*audio.Buffer, error) {
+	switch q.Kind {
+	case quiz.KindInterval:
+		tones := []audio.Tone{
+			{Notes: q.Notes[:1], Length: quizNoteLength},
+			{Notes: q.Notes[1:], Length: quizNoteLength},
+		}
+		if q.Together {
+			tones = []audio.Tone{{Notes: q.Notes, Length: 2 * quizNoteLength}}
+		}
+		b := audio.SynthesizeTones(tones, 44100)
+		if err := a.level(b); err != nil {
+			return nil, err
+		}
+		return b, nil
+
+	case quiz.KindScale:
+		key := render.SetActualKey(q.Pitch, q.Key)
+		_, src, melodic := render.SetAssetPaths(q.Pitch, q.Scale, key, "1")
+		if q.Melodic {
+			src = melodic
+		}
+		plain, _ := rhythm.Find(rhythm.Default)
+		return a.track(st, src, plain)
+
+	case quiz.KindTuning:
+		notes, err := drone.Notes(q.Key, q.Pitch, 1, drone.VoicingSingle)
+		if err != nil {
+			return nil, errors.Wrapf(err, "drone of %s", q.Key)
+		}
+		tonic, _, err := drone.Closest(st.drones, notes[0])
+		if err != nil {
+			return nil, err
+		}
+		gain := st.gains[tonic.Path]
+		s, err := drone.Build(st.drones, notes, quizDroneLead+quizTuningLength, func(path string) (*audio.Buffer, error) {
+			b, err := decodePart(a.cache, path)
+			if err != nil {
+				return nil, err
+			}
+			b.Amplify(gain)
+			return b, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		d, err := audio.ReadBuffer(s)
+		if err != nil {
+			return nil, errors.Wrap(err, "reading drone")
+		}
+
+		note := audio.SynthesizeTones([]audio.Tone{
+			{Length: quizDroneLead},
+			{Notes: []int{notes[0] + q.Note}, Length: quizTuningLength, Cents: q.Cents},
+		}, d.SampleRate)
+		b, err := audio.Mix([]audio.Track{{Buffer: d, Gain: 1}, {Buffer: note, Gain: 1}})
+		if err != nil {
+			return nil, errors.Wrap(err, "mixing tuning note")
+		}
+		if err := a.level(b); err != nil {
+			return nil, err
+		}
+		return b, nil
+	}
+	return nil, errors.Wrapf(quiz.ErrInvalidKind, "kind %q", q.Kind)
+}
+
+// quizAudioLink returns the url of what a question plays.
+func quizAudioLink(q quiz.Question) string {
+	return "/quiz/audio?" + url.Values{"ID": {q.ID}}.Encode()
+}
+
+// feedback tells a student how their answer went.
+func feedback(res quiz.Result) string {
+	msg := "Not quite, it was " + res.Text + "."
+	if res.Correct {
+		msg = "Correct, " + res.Text + "."
+	}
+	switch res.Moved {
+	case 1:
+		msg += " Moving up a level."
+	case -1:
+		msg += " Moving down a level."
+	}
+	return msg
+}
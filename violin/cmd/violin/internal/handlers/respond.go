@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// respond writes v as a JSON document with the given status code.
+func respond(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// respondError writes a JSON error document with the given status code.
+func respondError(w http.ResponseWriter, status int, msg string) {
+	respond(w, status, struct {
+		Error string `json:"error"`
+	}{msg})
+}
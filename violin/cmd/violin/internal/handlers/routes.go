@@ -1,24 +1,112 @@
 package handlers
 
 import (
-	"log"
 	"net/http"
+
+	"violin/internal/audio"
+	"violin/internal/loglevel"
+	"violin/internal/planner"
+	"violin/internal/practice"
+	"violin/internal/quiz"
+	"violin/internal/studio"
+	"violin/internal/syllabus"
+	"violin/internal/user"
+
+	"golang.org/x/sync/singleflight"
 )
 
-// NewMux constructs and mux with all route predefined.
-func NewMux(log *log.Logger) *http.ServeMux {
+// NewMux constructs and mux with all route predefined. Every request is
+// served from the State current in states when it arrives.
+func NewMux(log *loglevel.Logger, users *user.Store, sessions *practice.Store, plans *planner.Store, studios *studio.Store, quizzes *quiz.Store, boards []syllabus.Board, states *States, cache *audio.Cache, targetLoudness float64) http.Handler {
 	mux := http.NewServeMux()
-	// Serve everything in the css folder, the img folder and mp3 folder as a file
+	// Serve everything in the css folder and mp3 folder as a file, the img
+	// folder is served by the notation handlers below
 	mux.Handle("/css/", http.StripPrefix("/css/", http.FileServer(http.Dir("css"))))
-	mux.Handle("/img/", http.StripPrefix("/img/", http.FileServer(http.Dir("img"))))
 	mux.Handle("/mp3/", http.StripPrefix("/mp3/", http.FileServer(http.Dir("mp3"))))
 
-	base := Base{log}
+	base := Base{log, false}
 	// When navigating to /home it should serve the home page
 	mux.HandleFunc("/", base.Home)
 	mux.HandleFunc("/scale", base.Scale)
 	mux.HandleFunc("/scaleshow", base.ScaleShow)
 	mux.HandleFunc("/duets", base.Duets)
 	mux.HandleFunc("/duetshow", base.DuetShow)
-	return mux
+
+	mix := Mixer{log, cache, singleflight.Group{}, make(chan struct{}, mixWorkers)}
+	mux.HandleFunc("/duetmix", mix.Render)
+
+	account := Account{log, users, sessions, plans, quizzes}
+	mux.HandleFunc("/login", account.Login)
+	mux.HandleFunc("/signup", account.Signup)
+	mux.HandleFunc("/logout", account.Logout)
+	mux.HandleFunc("/settings", account.Settings)
+
+	prac := Practice{log, users, sessions}
+	mux.HandleFunc("/progress", prac.Progress)
+	mux.HandleFunc("/api/v1/sessions", prac.Sessions)
+
+	plan := Planner{log, users, sessions, plans, studios}
+	mux.HandleFunc("/practice", plan.Today)
+	mux.HandleFunc("/practice/rate", plan.Rate)
+	mux.HandleFunc("/practice/pin", plan.Pin)
+	mux.HandleFunc("/api/v1/plan", plan.Plan)
+	mux.HandleFunc("/api/v1/plan/pins", plan.Pins)
+
+	syl := Syllabus{log, boards}
+	mux.HandleFunc("/syllabus", syl.Browse)
+
+	notes := Notation{log, http.StripPrefix("/img/", http.FileServer(http.Dir("img")))}
+	mux.HandleFunc("/img/", notes.Image)
+	mux.HandleFunc("/notation/", notes.Draw)
+
+	aud := Audio{log, cache, targetLoudness}
+	mux.HandleFunc("/api/v1/audio/", aud.Convert)
+	mux.HandleFunc("/timing/", aud.Timing)
+	mux.HandleFunc("/audio/", aud.Serve)
+	mux.HandleFunc("/drone", aud.Drone)
+	mux.HandleFunc("/metronome", aud.Metronome)
+
+	ear := Quiz{log, users, quizzes, &aud}
+	mux.HandleFunc("/quiz", ear.Page)
+	mux.HandleFunc("/quiz/audio", ear.Audio)
+	mux.HandleFunc("/api/v1/quiz", ear.API)
+
+	sight := SightReading{log, &aud}
+	mux.HandleFunc("/sightreading", sight.Page)
+	mux.HandleFunc("/sightreading/", sight.Melody)
+
+	off := Offline{log}
+	mux.HandleFunc("/manifest.webmanifest", off.Manifest)
+	mux.HandleFunc("/icon.svg", off.Icon)
+	mux.HandleFunc("/offline.js", off.Script)
+	mux.HandleFunc("/sw.js", off.Worker)
+	mux.HandleFunc("/api/v1/bundle", off.Bundle)
+
+	keys := Keys{log}
+	mux.HandleFunc("/keys", keys.Page)
+	mux.HandleFunc("/keys/", keys.Image)
+	mux.HandleFunc("/api/v1/keys", keys.API)
+
+	std := Studio{log, users, sessions, studios}
+	mux.HandleFunc("/studio", std.Dashboard)
+	mux.HandleFunc("/studio/create", std.Create)
+	mux.HandleFunc("/studio/invite", std.Invite)
+	mux.HandleFunc("/studio/join", std.Join)
+	mux.HandleFunc("/studio/assign", std.Assign)
+	mux.HandleFunc("/studio/complete", std.Complete)
+	mux.HandleFunc("/api/v1/assignments", std.Assignments)
+	return states.Handler(mux)
+}
+
+// NewRenderMux constructs a mux serving the drawn notation and the audio
+// conversions of the exercises from st, all the render command needs.
+func NewRenderMux(log *loglevel.Logger, st *State, cache *audio.Cache, targetLoudness float64) http.Handler {
+	mux := http.NewServeMux()
+
+	notes := Notation{log, http.StripPrefix("/img/", http.FileServer(http.Dir("img")))}
+	mux.HandleFunc("/notation/", notes.Draw)
+
+	aud := Audio{log, cache, targetLoudness}
+	mux.HandleFunc("/api/v1/audio/", aud.Convert)
+	return NewStates(st).Handler(mux)
 }
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"violin/internal/loglevel"
+	"violin/internal/ratelimit"
+	"violin/internal/render"
+)
+
+const (
+	// csrfCookie holds the token every form and script that changes data
+	// sends back, in csrfField or the csrfHeader.
+	csrfCookie = "violin_csrf"
+	csrfField  = "csrf_token"
+	csrfHeader = "X-CSRF-Token"
+	csrfBytes  = 32
+
+	// jQueryCDN is where the pages load jQuery from.
+	jQueryCDN = "https://ajax.googleapis.com"
+)
+
+// staticPrefixes are the paths served as files, which are not rate
+// limited.
+var staticPrefixes = []string{"/css/", "/mp3/", "/img/", "/icon.svg", "/offline.js", "/sw.js", "/manifest.webmanifest"}
+
+// Security holds the settings of the Secure middleware. Reads limits how
+// often a client may call the dynamic pages and APIs, Writes how often it
+// may post forms or data; nil lets every call through.
+type Security struct {
+	CSP    bool
+	CSRF   bool
+	Reads  *ratelimit.Limiter
+	Writes *ratelimit.Limiter
+}
+
+// secureKey is the context key the nonce and CSRF token of a request are
+// kept under.
+type secureKey struct{}
+
+// secureVars are what the pages of a request need from Secure: the nonce
+// of their inline scripts and the CSRF token of their forms.
+type secureVars struct {
+	nonce string
+	csrf  string
+}
+
+// Secure wraps h with the protections of sec. Every response is sent with
+// X-Content-Type-Options and Referrer-Policy headers and, with CSP on, a
+// Content-Security-Policy that only runs the page's own scripts, jQuery
+// and inline scripts carrying the nonce of the request. With CSRF on,
+// calls that change data must send back the token of the browser's CSRF
+// cookie. Clients calling too often are answered 429 Too Many Requests.
+func Secure(log *loglevel.Logger, h http.Handler, sec Security) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		write := r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodOptions
+
+		limiter := sec.Reads
+		if write {
+			limiter = sec.Writes
+		}
+		if write || !isStatic(r.URL.Path) {
+			if ok, wait := limiter.Allow(clientIP(r), time.Now()); !ok {
+				log.Printf("%s %s -> %s : rate limited", r.Method, r.URL.Path, r.RemoteAddr)
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+		}
+
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Referrer-Policy", "strict-origin-when-cross-origin")
+
+		var vars secureVars
+		if sec.CSP {
+			vars.nonce = randomToken(16)
+			w.Header().Set("Content-Security-Policy", contentSecurityPolicy(vars.nonce))
+		}
+		if sec.CSRF {
+			vars.csrf = csrfToken(w, r)
+			if write && !validCSRF(r, vars.csrf) {
+				log.Errorf("%s %s -> %s : ERROR missing or wrong CSRF token", r.Method, r.URL.Path, r.RemoteAddr)
+				http.Error(w, "missing or expired form token, reload the page and try again", http.StatusForbidden)
+				return
+			}
+		}
+
+		ctx := context.WithValue(r.Context(), secureKey{}, vars)
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// contentSecurityPolicy returns the policy of a page whose inline scripts
+// carry nonce. Styles may be inline, as the drawn notation the scale page
+// follows the notes on brings its own.
+func contentSecurityPolicy(nonce string) string {
+	return strings.Join([]string{
+		"default-src 'self'",
+		"script-src 'self' 'nonce-" + nonce + "' " + jQueryCDN,
+		"style-src 'self' 'unsafe-inline' https://fonts.googleapis.com",
+		"font-src 'self' https://fonts.gstatic.com",
+		"img-src 'self' data: blob:",
+		"media-src 'self' blob:",
+		"object-src 'none'",
+		"base-uri 'self'",
+		"form-action 'self'",
+		"frame-ancestors 'none'",
+	}, "; ")
+}
+
+// renderPage renders a page template with the nonce and CSRF token of the
+// request.
+func renderPage(w http.ResponseWriter, r *http.Request, tmpl string, pv render.PageVars) error {
+	if vars, ok := r.Context().Value(secureKey{}).(secureVars); ok {
+		pv.Nonce, pv.CSRF = vars.nonce, vars.csrf
+	}
+	return stateOf(r).Templates.Render(w, tmpl, pv)
+}
+
+// csrfToken returns the CSRF token of the browser, giving it one when it
+// has none.
+func csrfToken(w http.ResponseWriter, r *http.Request) string {
+	if c, err := r.Cookie(csrfCookie); err == nil && len(c.Value) == base64.RawURLEncoding.EncodedLen(csrfBytes) {
+		return c.Value
+	}
+	token := randomToken(csrfBytes)
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookie,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return token
+}
+
+// validCSRF reports whether a call sends back the CSRF token, in the
+// header scripts set or the field forms post. Beacons, which cannot set
+// headers, send it in the query.
+func validCSRF(r *http.Request, token string) bool {
+	sent := r.Header.Get(csrfHeader)
+	if sent == "" {
+		sent = r.FormValue(csrfField)
+	}
+	return sent != "" && subtle.ConstantTimeCompare([]byte(sent), []byte(token)) == 1
+}
+
+// randomToken returns n random bytes, base64 encoded for urls.
+func randomToken(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// isStatic reports whether path is served as a file.
+func isStatic(path string) bool {
+	for _, p := range staticPrefixes {
+		if strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the address a request came from, without its port.
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// HSTS wraps h to tell browsers reaching it over HTTPS to use HTTPS only
+// for the next maxAge, so the microphone pages are never loaded in plain.
+// A zero maxAge leaves the header out, as a development certificate
+// should not be pinned.
+func HSTS(h http.Handler, maxAge time.Duration) http.Handler {
+	if maxAge <= 0 {
+		return h
+	}
+	value := fmt.Sprintf("max-age=%d; includeSubDomains", int(maxAge/time.Second))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil {
+			w.Header().Set("Strict-Transport-Security", value)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// Redirect sends plain HTTP requests on to the same url over HTTPS.
+type Redirect struct {
+	log       *loglevel.Logger
+	httpsPort string
+}
+
+// NewRedirect constructs a Redirect to the HTTPS server listening on
+// httpsAddr, such as 0.0.0.0:8443.
+func NewRedirect(log *loglevel.Logger, httpsAddr string) *Redirect {
+	_, port, _ := net.SplitHostPort(httpsAddr)
+	return &Redirect{log, port}
+}
+
+// ServeHTTP redirects every request permanently, keeping its host name,
+// path and query. Only GET and HEAD calls are redirected, as a browser
+// would resend a form without its body.
+func (rd *Redirect) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rd.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "use HTTPS", http.StatusBadRequest)
+		return
+	}
+
+	host := r.Host
+	if h, _, err := net.SplitHostPort(r.Host); err == nil {
+		host = h
+	}
+	switch {
+	case rd.httpsPort != "" && rd.httpsPort != "443":
+		host = net.JoinHostPort(host, rd.httpsPort)
+	case strings.Contains(host, ":"):
+		host = "[" + host + "]"
+	}
+	http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+}
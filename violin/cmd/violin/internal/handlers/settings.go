@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"violin/internal/i18n"
+	"violin/internal/render"
+)
+
+// Cookie names holding the language and note naming system chosen on the
+// settings page.
+const (
+	languageCookie = "violin_language"
+	notesCookie    = "violin_notes"
+)
+
+// Settings handles GET and POST calls for the settings page, where the
+// Language of the pages and the naming system of Notes are chosen. Empty
+// values follow the browser's Accept-Language. Choices are kept in cookies
+// and, for logged in users, with their account.
+func (a *Account) Settings(w http.ResponseWriter, r *http.Request) {
+	a.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		r.ParseForm()
+		language := selectedOption(render.SetLanguageOptions(r.PostForm.Get("Language")))
+		notes := selectedOption(render.SetNamingOptions(r.PostForm.Get("Notes")))
+		if u, ok := currentUser(a.users, r); ok {
+			if _, err := a.users.SetPreferences(u.ID, language, notes); err != nil {
+				a.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+		}
+		setPreferences(w, language, notes)
+		http.Redirect(w, r, "/settings", http.StatusSeeOther)
+		return
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	language, notes := preferences(r)
+	pv := render.PageVars{
+		Title:     "Settings",
+		Languages: render.SetLanguageOptions(language),
+		Namings:   render.SetNamingOptions(notes),
+		Locale:    localeOf(r),
+	}
+	if err := renderPage(w, r, "settings.html", pv); err != nil {
+		a.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		return
+	}
+}
+
+// localeOf returns the locale to write a page in: the language and naming
+// system chosen on the settings page, otherwise the language the browser
+// asks for and the naming system usual for it.
+func localeOf(r *http.Request) i18n.Locale {
+	language, notes := preferences(r)
+	if language == "" {
+		language = i18n.Negotiate(r.Header.Get("Accept-Language"))
+	}
+	return i18n.New(language, notes)
+}
+
+// preferences returns the language and naming system chosen on the
+// settings page, empty where the browser is followed.
+func preferences(r *http.Request) (string, string) {
+	var language, notes string
+	if c, err := r.Cookie(languageCookie); err == nil {
+		language = c.Value
+	}
+	if c, err := r.Cookie(notesCookie); err == nil {
+		notes = c.Value
+	}
+	return language, notes
+}
+
+// setPreferences keeps the chosen language and naming system in cookies,
+// clearing those that follow the browser.
+func setPreferences(w http.ResponseWriter, language, notes string) {
+	for name, value := range map[string]string{languageCookie: language, notesCookie: notes} {
+		c := &http.Cookie{
+			Name:     name,
+			Value:    value,
+			Path:     "/",
+			MaxAge:   int(365 * 24 * time.Hour / time.Second),
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		}
+		if value == "" {
+			c.MaxAge = -1
+		}
+		http.SetCookie(w, c)
+	}
+}
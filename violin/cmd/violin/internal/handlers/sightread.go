@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"violin/internal/audio"
+	"violin/internal/loglevel"
+	"violin/internal/metronome"
+	"violin/internal/notation"
+	"violin/internal/render"
+	"violin/internal/sightread"
+	"violin/internal/theory"
+
+	"github.com/pkg/errors"
+)
+
+// SightReading represents the handlers of the sight-reading generator.
+// Melodies are played with the synthesizer of the audio handlers.
+type SightReading struct {
+	log   *loglevel.Logger
+	audio *Audio
+}
+
+// Page handles GET calls for the sight-reading page, such as
+// /sightreading?Key=D&Pitch=Major&Level=2&Seed=42. Settings left out take
+// the defaults of the level. Without a Seed it redirects to a new melody,
+// so every melody shown has a link that brings it back.
+func (s *SightReading) Page(w http.ResponseWriter, r *http.Request) {
+	s.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	r.ParseForm()
+	key, settings, seeded := parseSightReading(r.Form)
+	if !seeded {
+		settings.Seed = newSeed()
+		http.Redirect(w, r, sightReadingLink("/sightreading", key, settings), http.StatusFound)
+		return
+	}
+
+	newMelody := sightReadingQuery(key, settings)
+	newMelody.Del("Seed")
+	view := render.SightReading{
+		Keys:         render.SetKeyOptions(key),
+		Spellings:    render.SetSpellingOptions(settings.Pitch, key, settings.Key),
+		Pitches:      render.SetPitchOptions(settings.Pitch),
+		Levels:       render.SetLevelOptions(settings.Level),
+		Ranges:       render.SetRangeOptions(settings.Range),
+		Leaps:        render.SetLeapOptions(settings.Leap),
+		Rhythms:      render.SetCellOptions(settings.Rhythms),
+		Bars:         settings.Bars,
+		Seed:         settings.Seed,
+		Name:         melodyName(settings),
+		ImgPath:      sightReadingLink("/sightreading/melody.svg", key, settings),
+		AudioPath:    sightReadingLink("/sightreading/melody.flac", key, settings),
+		MIDIPath:     sightReadingLink("/sightreading/melody.mid", key, settings),
+		MusicXMLPath: sightReadingLink("/sightreading/melody.musicxml", key, settings),
+		SharePath:    sightReadingLink("/sightreading", key, settings),
+		NewPath:      "/sightreading?" + newMelody.Encode(),
+	}
+
+	pv := render.PageVars{
+		Title:        "Sight-Reading",
+		SightReading: view,
+		Locale:       localeOf(r),
+	}
+	if err := renderPage(w, r, "sightread.html", pv); err != nil {
+		s.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		return
+	}
+}
+
+// Melody handles GET calls for /sightreading/melody.<format>, with the
+// settings of the page, such as
+// /sightreading/melody.svg?Key=D&Pitch=Major&Level=2&Seed=42. The format
+// is svg for notation, flac for the synthesized melody, mid for a MIDI file
+// at BPM beats per minute or musicxml for a MusicXML score.
+func (s *SightReading) Melody(w http.ResponseWriter, r *http.Request) {
+	s.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	ext := path.Ext(r.URL.Path)
+	if strings.TrimSuffix(r.URL.Path, ext) != "/sightreading/melody" {
+		http.NotFound(w, r)
+		return
+	}
+
+	query := r.URL.Query()
+	_, settings, seeded := parseSightReading(query)
+	if !seeded {
+		http.Error(w, "missing seed", http.StatusBadRequest)
+		return
+	}
+	m, err := sightread.Generate(settings)
+	if err != nil {
+		if errors.Is(err, sightread.ErrInvalid) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	chords := make([]notation.Chord, len(m.Notes))
+	for i, n := range m.Notes {
+		chords[i] = notation.Chord{Notes: []theory.Note{n}, BarLine: m.BarEnds[i], Step: m.Steps[i]}
+	}
+	sig, err := theory.KeySignature(settings.Key, settings.Pitch)
+	if err != nil {
+		s.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	switch ext {
+	case ".svg":
+		w.Header().Set("Content-Type", "image/svg+xml")
+		err = notation.WriteChords(w, melodyName(settings), sig, chords)
+	case ".mid":
+		w.Header().Set("Content-Type", "audio/midi")
+		bpm := formInt(query, "BPM", synthBPM, metronome.MinBPM, metronome.MaxBPM)
+		err = notation.WriteMIDI(w, sig, bpm, chords)
+	case ".musicxml":
+		w.Header().Set("Content-Type", "application/vnd.recordare.musicxml+xml")
+		err = notation.WriteMusicXML(w, melodyName(settings), sig, chords)
+	case ".flac":
+		var f *os.File
+		if f, err = s.audio.melody(settings, chords); err != nil {
+			break
+		}
+		defer f.Close()
+		var info os.FileInfo
+		if info, err = f.Stat(); err != nil {
+			break
+		}
+		w.Header().Set("Content-Type", "audio/flac")
+		http.ServeContent(w, r, "melody.flac", info.ModTime(), f)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		s.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		return
+	}
+}
+
+// melody returns the cached FLAC of a sight-reading melody played by the
+// synthesizer, rendering it on first use.
+func (a *Audio) melody(settings sightread.Settings, chords []notation.Chord) (*os.File, error) {
+	key, err := a.cache.Key(nil, fmt.Sprintf("sightread %+v %.1f", settings, a.target))
+	if err != nil {
+		return nil, err
+	}
+
+	return a.cache.Open(key, ".flac", func(f *os.File) error {
+		b, err := a.synthesizeChords(chords, synthNoteLength)
+		if err != nil {
+			return err
+		}
+		return audio.WriteFLAC(f, b.Stream())
+	})
+}
+
+// parseSightReading reads the settings of a melody from a form, with the
+// defaults of the level filled in, along with the key as the page names it.
+// It reports whether the form has a seed.
+func parseSightReading(form url.Values) (string, sightread.Settings, bool) {
+	key := form.Get("Key")
+	if !hasOption(render.SetKeyOptions("A"), key) {
+		key = "A"
+	}
+	pitch := selectedOption(render.SetPitchOptions(form.Get("Pitch")))
+
+	settings := sightread.Settings{
+		Key:     selectedOption(render.SetSpellingOptions(pitch, key, form.Get("Spelling"))),
+		Pitch:   pitch,
+		Level:   formInt(form, "Level", sightread.MinLevel, sightread.MinLevel, sightread.MaxLevel),
+		Range:   form.Get("Range"),
+		Leap:    form.Get("Leap"),
+		Rhythms: form["Rhythm"],
+		Bars:    formInt(form, "Bars", 0, sightread.MinBars, sightread.MaxBars),
+	}.Defaults()
+
+	seed, err := strconv.ParseInt(form.Get("Seed"), 10, 64)
+	settings.Seed = seed
+	return key, settings, err == nil
+}
+
+// sightReadingQuery returns the query that brings back a melody.
+func sightReadingQuery(key string, settings sightread.Settings) url.Values {
+	return url.Values{
+		"Key":      {key},
+		"Spelling": {settings.Key},
+		"Pitch":    {settings.Pitch},
+		"Level":    {strconv.Itoa(settings.Level)},
+		"Range":    {settings.Range},
+		"Leap":     {settings.Leap},
+		"Rhythm":   settings.Rhythms,
+		"Bars":     {strconv.Itoa(settings.Bars)},
+		"Seed":     {strconv.FormatInt(settings.Seed, 10)},
+	}
+}
+
+// sightReadingLink returns the url of a melody's page or one of its files.
+func sightReadingLink(link, key string, settings sightread.Settings) string {
+	return link + "?" + sightReadingQuery(key, settings).Encode()
+}
+
+// melodyName names a melody, such as "Sight-Reading in D Major, Level 2".
+func melodyName(settings sightread.Settings) string {
+	return fmt.Sprintf("Sight-Reading in %s %s, Level %d", settings.Key, settings.Pitch, settings.Level)
+}
+
+// newSeed returns a random seed for a new melody.
+func newSeed() int64 {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return int64(binary.BigEndian.Uint64(b) >> 1)
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"violin/internal/assets"
+	"violin/internal/drone"
+	"violin/internal/render"
+	"violin/internal/theory"
+
+	"github.com/pkg/errors"
+)
+
+// State is what the handlers serve that a reload replaces as a whole: the
+// asset index with the gains and drones worked out from it, the duets the
+// index holds and the page templates. A State is never changed once built,
+// a reload builds a new one.
+type State struct {
+	Assets    *assets.Index
+	Templates *render.Templates
+	gains     map[string]float64
+	drones    []drone.Recording
+	duets     []render.Option
+}
+
+// NewState builds the State serving the assets in index, with recordings
+// normalized to the target loudness, and the page templates. It fails when
+// the index holds no complete duet, as the duets page would have nothing to
+// show.
+func NewState(index *assets.Index, templates *render.Templates, target float64) (*State, error) {
+	st := State{
+		Assets:    index,
+		Templates: templates,
+		gains:     loudnessGains(index, target),
+		drones:    drone.Library(assetPaths(index)),
+		duets:     duetCatalog(index),
+	}
+	if len(st.duets) == 0 {
+		return nil, errors.New("no complete duet in img/duet and mp3/duet")
+	}
+	return &st, nil
+}
+
+// Duets returns how many duets the State offers.
+func (st *State) Duets() int {
+	return len(st.duets)
+}
+
+// States holds the State the handlers serve, which a reload swaps for a new
+// one. It is safe for concurrent use.
+type States struct {
+	v atomic.Value
+}
+
+// NewStates constructs States serving st.
+func NewStates(st *State) *States {
+	var s States
+	s.v.Store(st)
+	return &s
+}
+
+// Load returns the State served now.
+func (s *States) Load() *State {
+	return s.v.Load().(*State)
+}
+
+// Store makes st the State served from now on. Requests already running
+// keep the State they started with.
+func (s *States) Store(st *State) {
+	s.v.Store(st)
+}
+
+// stateKey is the context key a request's State is kept under.
+type stateKey struct{}
+
+// Handler wraps h so every request is served from the State current when
+// it arrived, whatever reloads happen while it runs.
+func (s *States) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), stateKey{}, s.Load())
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// stateOf returns the State a request is served from.
+func stateOf(r *http.Request) *State {
+	return r.Context().Value(stateKey{}).(*State)
+}
+
+// duetCatalog lists the duets the index holds an image and all three
+// recordings of, in the order of their key signatures, the first checked.
+func duetCatalog(ix *assets.Index) []render.Option {
+	type duet struct {
+		key    string
+		sharps int
+	}
+	var duets []duet
+	for _, f := range ix.Files() {
+		dir, name := path.Split(f.Path)
+		if dir != "img/duet/" || !strings.HasSuffix(name, "major.png") {
+			continue
+		}
+		name = strings.TrimSuffix(name, "major.png")
+		if name == "" {
+			continue
+		}
+		key := strings.ToUpper(name[:1]) + name[1:]
+		img, both, part1, part2 := duetAssetPaths(key)
+		if img != f.Path || !ix.Has(both) || !ix.Has(part1) || !ix.Has(part2) {
+			continue
+		}
+		sharps, err := theory.KeySignature(key, "Major")
+		if err != nil {
+			continue
+		}
+		duets = append(duets, duet{key, sharps})
+	}
+	sort.SliceStable(duets, func(i, j int) bool {
+		return duets[i].sharps < duets[j].sharps
+	})
+
+	options := make([]render.Option, 0, len(duets))
+	for i, d := range duets {
+		options = append(options, render.Option{Name: "Duet", Value: d.key, IsChecked: i == 0, Text: d.key + " Major"})
+	}
+	return options
+}
+
+// duetChoices returns the duet options with the duet in key checked.
+func duetChoices(duets []render.Option, key string) []render.Option {
+	options := make([]render.Option, len(duets))
+	for i, o := range duets {
+		o.IsChecked = o.Value == key
+		options[i] = o
+	}
+	return options
+}
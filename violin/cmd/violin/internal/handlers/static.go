@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"violin/internal/loglevel"
+	"violin/internal/practice"
+	"violin/internal/render"
+)
+
+// NewExportMux constructs a mux serving a static export of GoViolin from
+// st: the home page at /, each scale and duet page at its export path, such
+// as /scale/major/cs-db/2.html, and the files those pages use at the urls
+// they are exported under.
+func NewExportMux(log *loglevel.Logger, st *State) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/css/", http.StripPrefix("/css/", http.FileServer(http.Dir("css"))))
+	// Recordings are exported as they are, a static host has no way to
+	// normalize them.
+	mux.Handle("/audio/mp3/", http.StripPrefix("/audio/mp3/", http.FileServer(http.Dir("mp3"))))
+
+	base := Base{log, true}
+	mux.HandleFunc("/", base.Export)
+
+	notes := Notation{log, http.StripPrefix("/img/", http.FileServer(http.Dir("img")))}
+	mux.HandleFunc("/img/", notes.Image)
+	mux.HandleFunc("/notation/", notes.Draw)
+	return NewStates(st).Handler(mux)
+}
+
+// Export handles GET calls for the pages of a static export: / for the home
+// page and the export path of a practice item for its scale or duet page.
+func (b *Base) Export(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/" {
+		b.Home(w, r)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/")
+	if !strings.HasSuffix(id, ".html") {
+		http.NotFound(w, r)
+		return
+	}
+	it, err := practice.ParseItem(strings.TrimSuffix(id, ".html"))
+	if err != nil || exportPath(it) != r.URL.Path {
+		http.NotFound(w, r)
+		return
+	}
+	if it.Kind == practice.KindDuet && !hasOption(stateOf(r).duets, it.Key) {
+		http.NotFound(w, r)
+		return
+	}
+
+	link := itemLink(it)
+	r.URL.RawQuery = link[strings.Index(link, "?")+1:]
+	if it.Kind == practice.KindDuet {
+		b.DuetShow(w, r)
+		return
+	}
+	b.ScaleShow(w, r)
+}
+
+// ExportPath returns the path the page of a practice item is exported
+// under, such as /scale/major/cs-db/2.html, or "" for an item the scale page
+// would show under another, such as a dominant 7th in a minor key. Duets are
+// exported under their own path when the export holds them.
+func ExportPath(it practice.Item) string {
+	if it.Kind != practice.KindDuet {
+		if it.Pitch != render.ExercisePitch(it.Kind, it.Pitch) || it.Octave != selectedOption(render.SetOctaveOptions(it.Kind, it.Pitch, it.Key, it.Octave)) {
+			return ""
+		}
+	}
+	return exportPath(it)
+}
+
+// exportPath returns the path the page of a practice item is exported
+// under.
+func exportPath(it practice.Item) string {
+	return "/" + it.ID() + ".html"
+}
+
+// exportLink returns the export path of the scale page a form with the
+// given options shows, after the page corrects the pitch and octave.
+func exportLink(scale, pitch, key, octave string) string {
+	pitch = render.ExercisePitch(scale, pitch)
+	octave = selectedOption(render.SetOctaveOptions(scale, pitch, key, octave))
+	return exportPath(practice.Item{Kind: scale, Pitch: pitch, Key: key, Octave: octave})
+}
+
+// exportNav returns the links of the navigation of an exported page, keyed
+// "Nav=<page>". The duets link to the first of them.
+func exportNav(st *State) map[string]string {
+	return map[string]string{
+		"Nav=Scales": exportLink(practice.KindScale, "Major", "A", "1"),
+		"Nav=Duets":  exportPath(practice.Item{Kind: practice.KindDuet, Key: st.duets[0].Value}),
+	}
+}
+
+// exportScale turns a scale page into its static export. Each option links
+// to the page it selects, keyed "<name>=<value>" in Links, in place of the
+// form. Rhythms, drones, the metronome, the other spelling, downloads and
+// the pin go, as they need the server, and so do players and images
+// nobody recorded or engraved; missing images are exported drawn instead.
+func exportScale(pv *render.PageVars, st *State, scale, pitch, key, octave string) {
+	pv.Static, pv.Links = true, exportNav(st)
+	for _, o := range pv.Scales {
+		pv.Links[o.Name+"="+o.Value] = exportLink(o.Value, pitch, key, octave)
+	}
+	for _, o := range pv.Pitches {
+		pv.Links[o.Name+"="+o.Value] = exportLink(scale, o.Value, key, octave)
+	}
+	for _, o := range pv.Keys {
+		pv.Links[o.Name+"="+o.Value] = exportLink(scale, pitch, o.Value, octave)
+	}
+	for _, o := range pv.Octaves {
+		pv.Links[o.Name+"="+o.Value] = exportLink(scale, pitch, key, o.Value)
+	}
+
+	if !st.Assets.Has(pv.ScaleImgPath) {
+		pv.ScaleImgPath = strings.TrimPrefix(notationLink(pv.ScaleImgPath), "/")
+	}
+	if !st.Assets.Has(pv.AudioPath) {
+		pv.AudioPath = ""
+	}
+	if !st.Assets.Has(pv.AudioPath2) {
+		pv.AudioPath2 = ""
+	}
+	pv.Spellings = nil
+	pv.Rhythms = nil
+	pv.DronePath = ""
+	pv.Voicings = nil
+	pv.DroneLengths = nil
+	pv.Metronome = render.Metronome{}
+	pv.MIDIPath, pv.MusicXMLPath, pv.SharePath = "", "", ""
+	pv.NotationPath, pv.TimingPath = "", ""
+	pv.Offline = render.Offline{}
+}
+
+// exportDuet turns a duet page into its static export, each duet linking
+// to its page and without the mixer, which needs the server.
+func exportDuet(pv *render.PageVars, st *State, options []render.Option) {
+	pv.Static, pv.Links = true, exportNav(st)
+	for _, o := range options {
+		pv.Links[o.Name+"="+o.Value] = exportPath(practice.Item{Kind: practice.KindDuet, Key: o.Value})
+	}
+	pv.Mix = render.DuetMix{}
+	pv.Offline = render.Offline{}
+}
@@ -0,0 +1,379 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"violin/internal/loglevel"
+	"violin/internal/practice"
+	"violin/internal/render"
+	"violin/internal/studio"
+	"violin/internal/user"
+
+	"github.com/pkg/errors"
+)
+
+// Studio represents the handlers for teacher and student dashboards.
+type Studio struct {
+	log      *loglevel.Logger
+	users    *user.Store
+	practice *practice.Store
+	studios  *studio.Store
+}
+
+// Dashboard handles GET calls for the studio page. Teachers see their
+// studios, students and the progress of every assignment, students see the
+// studios they joined and the work assigned to them.
+func (s *Studio) Dashboard(w http.ResponseWriter, r *http.Request) {
+	s.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	u, ok := currentUser(s.users, r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	now := time.Now()
+	pv := render.PageVars{
+		Title:    "Studio",
+		UserName: u.Name,
+		Role:     u.Role,
+		Error:    r.URL.Query().Get("error"),
+		Today:    now.Format("2006-01-02"),
+		Locale:   localeOf(r),
+	}
+
+	if u.IsTeacher() {
+		pv.Catalog = catalogOptions(stateOf(r).duets)
+		for _, st := range s.studios.TeacherStudios(u.ID) {
+			view, err := s.studioView(u, st, now)
+			if err != nil {
+				s.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			pv.Studios = append(pv.Studios, view)
+		}
+	} else {
+		for _, st := range s.studios.StudentStudios(u.ID) {
+			view := render.StudioView{Studio: st}
+			if t, err := s.users.ByID(st.TeacherID); err == nil {
+				view.Teacher = t.Name
+			}
+			pv.Studios = append(pv.Studios, view)
+		}
+		for _, a := range s.studios.StudentAssignments(u.ID) {
+			pv.Assignments = append(pv.Assignments, s.assignmentView(a, now))
+		}
+	}
+
+	if err := renderPage(w, r, "studio.html", pv); err != nil {
+		s.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		return
+	}
+}
+
+// Create handles POST calls from a teacher starting a new studio.
+func (s *Studio) Create(w http.ResponseWriter, r *http.Request) {
+	s.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	u, ok := s.authorize(w, r, user.RoleTeacher)
+	if !ok {
+		return
+	}
+
+	_, err := s.studios.Create(u.ID, r.PostForm.Get("Name"), time.Now())
+	s.redirect(w, r, err)
+}
+
+// Invite handles POST calls from a teacher creating an invite code.
+func (s *Studio) Invite(w http.ResponseWriter, r *http.Request) {
+	s.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	u, ok := s.authorize(w, r, user.RoleTeacher)
+	if !ok {
+		return
+	}
+
+	_, err := s.studios.Invite(u.ID, r.PostForm.Get("StudioID"), time.Now())
+	s.redirect(w, r, err)
+}
+
+// Join handles POST calls from a student joining a studio with a code.
+func (s *Studio) Join(w http.ResponseWriter, r *http.Request) {
+	s.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	u, ok := s.authorize(w, r, user.RoleStudent)
+	if !ok {
+		return
+	}
+
+	_, err := s.studios.Join(u.ID, r.PostForm.Get("Code"), time.Now())
+	s.redirect(w, r, err)
+}
+
+// Assign handles POST calls from a teacher setting work for a student. The
+// form posts parallel Item and Tempo values, one pair per task.
+func (s *Studio) Assign(w http.ResponseWriter, r *http.Request) {
+	s.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	u, ok := s.authorize(w, r, user.RoleTeacher)
+	if !ok {
+		return
+	}
+
+	na := studio.NewAssignment{
+		StudioID:  r.PostForm.Get("StudioID"),
+		StudentID: r.PostForm.Get("StudentID"),
+		Notes:     r.PostForm.Get("Notes"),
+		Due:       r.PostForm.Get("Due"),
+	}
+	tempos := r.PostForm["Tempo"]
+	for i, item := range r.PostForm["Item"] {
+		if item == "" {
+			continue
+		}
+		t := studio.Task{Item: item}
+		if i < len(tempos) && tempos[i] != "" {
+			tempo, err := strconv.Atoi(tempos[i])
+			if err != nil {
+				s.redirect(w, r, errors.Wrap(studio.ErrInvalid, "target tempo must be a number"))
+				return
+			}
+			t.TargetTempo = tempo
+		}
+		na.Tasks = append(na.Tasks, t)
+	}
+
+	_, err := s.studios.Assign(u.ID, na, time.Now())
+	s.redirect(w, r, err)
+}
+
+// Complete handles POST calls from a student marking an assignment as done,
+// or reopening it.
+func (s *Studio) Complete(w http.ResponseWriter, r *http.Request) {
+	s.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	u, ok := s.authorize(w, r, user.RoleStudent)
+	if !ok {
+		return
+	}
+
+	_, err := s.studios.Complete(u.ID, r.PostForm.Get("AssignmentID"), r.PostForm.Get("Done") == "true", time.Now())
+	s.redirect(w, r, err)
+}
+
+// Assignments handles calls to /api/v1/assignments. GET returns the
+// assignments visible to the caller, all assignments in their studios for a
+// teacher or their own for a student. POST lets a teacher create an
+// assignment from a JSON document.
+func (s *Studio) Assignments(w http.ResponseWriter, r *http.Request) {
+	s.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	u, ok := currentUser(s.users, r)
+	if !ok {
+		respondError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		return
+	}
+
+	now := time.Now()
+	switch r.Method {
+	case http.MethodGet:
+		views := []render.AssignmentView{}
+		if u.IsTeacher() {
+			for _, st := range s.studios.TeacherStudios(u.ID) {
+				list, err := s.studios.StudioAssignments(u.ID, st.ID)
+				if err != nil {
+					s.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+					respondError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+					return
+				}
+				for _, a := range list {
+					views = append(views, s.assignmentView(a, now))
+				}
+			}
+		} else {
+			for _, a := range s.studios.StudentAssignments(u.ID) {
+				views = append(views, s.assignmentView(a, now))
+			}
+		}
+		respond(w, http.StatusOK, views)
+
+	case http.MethodPost:
+		if !u.IsTeacher() {
+			respondError(w, http.StatusForbidden, http.StatusText(http.StatusForbidden))
+			return
+		}
+
+		var na studio.NewAssignment
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&na); err != nil {
+			respondError(w, http.StatusBadRequest, "decoding assignment: "+err.Error())
+			return
+		}
+
+		a, err := s.studios.Assign(u.ID, na, now)
+		if err != nil {
+			status := studioErrorStatus(err)
+			if status == http.StatusInternalServerError {
+				s.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+				respondError(w, status, http.StatusText(status))
+				return
+			}
+			respondError(w, status, err.Error())
+			return
+		}
+		respond(w, http.StatusCreated, s.assignmentView(a, now))
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		respondError(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+	}
+}
+
+// authorize checks the request is a POST from a logged in user with the
+// given role and parses its form. It writes the error response and returns
+// false when the request is not allowed.
+func (s *Studio) authorize(w http.ResponseWriter, r *http.Request, role string) (user.User, bool) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return user.User{}, false
+	}
+
+	u, ok := currentUser(s.users, r)
+	if !ok {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return user.User{}, false
+	}
+	if u.IsTeacher() != (role == user.RoleTeacher) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return user.User{}, false
+	}
+
+	r.ParseForm()
+	return u, true
+}
+
+// redirect sends the browser back to the dashboard after a form post,
+// showing the error when the post failed because of the input.
+func (s *Studio) redirect(w http.ResponseWriter, r *http.Request, err error) {
+	if err == nil {
+		http.Redirect(w, r, "/studio", http.StatusSeeOther)
+		return
+	}
+
+	status := studioErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		s.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	if status == http.StatusForbidden {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	msg := err.Error()
+	if status == http.StatusNotFound {
+		msg = "That studio, invite code or assignment does not exist."
+	}
+	http.Redirect(w, r, "/studio?error="+url.QueryEscape(msg), http.StatusSeeOther)
+}
+
+// studioView builds the teacher dashboard view of a studio.
+func (s *Studio) studioView(teacher user.User, st studio.Studio, now time.Time) (render.StudioView, error) {
+	view := render.StudioView{Studio: st, Teacher: teacher.Name}
+
+	for _, id := range st.Students {
+		name := id
+		if u, err := s.users.ByID(id); err == nil {
+			name = u.Name
+		}
+		view.Members = append(view.Members, render.Option{Name: "StudentID", Value: id, Text: name})
+	}
+
+	invites, err := s.studios.Invites(teacher.ID, st.ID, now)
+	if err != nil {
+		return view, errors.Wrap(err, "listing invites")
+	}
+	view.Invites = invites
+
+	list, err := s.studios.StudioAssignments(teacher.ID, st.ID)
+	if err != nil {
+		return view, errors.Wrap(err, "listing assignments")
+	}
+	for _, a := range list {
+		view.Assignments = append(view.Assignments, s.assignmentView(a, now))
+	}
+
+	return view, nil
+}
+
+// assignmentView adds the practice the student logged on each task since the
+// assignment was set.
+func (s *Studio) assignmentView(a studio.Assignment, now time.Time) render.AssignmentView {
+	view := render.AssignmentView{
+		Assignment: a,
+		Student:    a.StudentID,
+		Overdue:    a.Overdue(now),
+	}
+	if u, err := s.users.ByID(a.StudentID); err == nil {
+		view.Student = u.Name
+	}
+
+	played := make(map[string]time.Duration)
+	for _, ses := range s.practice.Sessions(userOwner(a.StudentID)) {
+		if !ses.Start.Before(a.DateCreated) {
+			played[ses.Item] += ses.Duration()
+		}
+	}
+
+	var total time.Duration
+	for _, t := range a.Tasks {
+		tv := render.TaskView{Task: t, Title: t.Item, Minutes: int(played[t.Item] / time.Minute)}
+		if it, err := practice.ParseItem(t.Item); err == nil {
+			tv.Title = it.String()
+			tv.Link = itemLink(it)
+		}
+		total += played[t.Item]
+		view.Tasks = append(view.Tasks, tv)
+	}
+	view.Minutes = int(total / time.Minute)
+
+	return view
+}
+
+// studioErrorStatus maps studio store errors to http status codes.
+func studioErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, studio.ErrInvalid):
+		return http.StatusBadRequest
+	case errors.Is(err, studio.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, studio.ErrForbidden):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// catalogOptions lists every scale, arpeggio and duet that can be assigned.
+func catalogOptions(duets []render.Option) []render.Option {
+	items := scaleCatalog()
+	for _, o := range duets {
+		items = append(items, practice.DuetItem(o.Value))
+	}
+
+	options := make([]render.Option, 0, len(items))
+	for _, id := range items {
+		it, err := practice.ParseItem(id)
+		if err != nil {
+			continue
+		}
+		options = append(options, render.Option{Name: "Item", Value: id, Text: it.String()})
+	}
+	return options
+}
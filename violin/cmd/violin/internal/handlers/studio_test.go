@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"violin/internal/loglevel"
+	"violin/internal/practice"
+	"violin/internal/studio"
+	"violin/internal/user"
+)
+
+// studioFixture holds a Studio with two teachers, each running a studio,
+// and two students of the first teacher, one of them with an assignment.
+type studioFixture struct {
+	t        *testing.T
+	handlers *Studio
+	users    *user.Store
+	studios  *studio.Store
+
+	teacher, otherTeacher user.User
+	student, otherStudent user.User
+	studioID, otherID     string
+	assignmentID          string
+}
+
+func newStudioFixture(t *testing.T) *studioFixture {
+	t.Helper()
+	dir := t.TempDir()
+	now := time.Now()
+
+	users, err := user.NewStore(filepath.Join(dir, "users.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions, err := practice.NewStore(filepath.Join(dir, "practice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	studios, err := studio.NewStore(filepath.Join(dir, "studios.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := studioFixture{
+		t:        t,
+		handlers: &Studio{loglevel.Discard(), users, sessions, studios},
+		users:    users,
+		studios:  studios,
+	}
+	create := func(name, role string) user.User {
+		u, err := users.Create(name, "password", role, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u
+	}
+	f.teacher = create("teacher", user.RoleTeacher)
+	f.otherTeacher = create("other-teacher", user.RoleTeacher)
+	f.student = create("student", user.RoleStudent)
+	f.otherStudent = create("other-student", user.RoleStudent)
+
+	st, err := studios.Create(f.teacher.ID, "Violins", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.studioID = st.ID
+	other, err := studios.Create(f.otherTeacher.ID, "Violas", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.otherID = other.ID
+
+	inv, err := studios.Invite(f.teacher.ID, f.studioID, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range []user.User{f.student, f.otherStudent} {
+		if _, err := studios.Join(u.ID, inv.Code, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a, err := studios.Assign(f.teacher.ID, f.newAssignment(f.studioID, f.student.ID), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.assignmentID = a.ID
+	return &f
+}
+
+// newAssignment returns an assignment of one scale for the student.
+func (f *studioFixture) newAssignment(studioID, studentID string) studio.NewAssignment {
+	return studio.NewAssignment{
+		StudioID:  studioID,
+		StudentID: studentID,
+		Tasks:     []studio.Task{{Item: "scale/major/a/1", TargetTempo: 80}},
+		Due:       time.Now().AddDate(0, 0, 7).Format("2006-01-02"),
+	}
+}
+
+// request builds a request logged in as u, anonymous when u has no ID.
+func (f *studioFixture) request(u user.User, method, target string, body io.Reader, contentType string) *http.Request {
+	f.t.Helper()
+	r := httptest.NewRequest(method, target, body)
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	if u.ID != "" {
+		token, err := f.users.StartSession(u.ID, time.Now())
+		if err != nil {
+			f.t.Fatal(err)
+		}
+		r.AddCookie(&http.Cookie{Name: sessionCookie, Value: token})
+	}
+	return r
+}
+
+// post sends a form to a handler as u and returns the response.
+func (f *studioFixture) post(h http.HandlerFunc, u user.User, form url.Values) *httptest.ResponseRecorder {
+	f.t.Helper()
+	r := f.request(u, http.MethodPost, "/studio/form", strings.NewReader(form.Encode()), "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	h(w, r)
+	return w
+}
+
+// api calls the assignments API as u and returns the response.
+func (f *studioFixture) api(u user.User, method string, body interface{}) *httptest.ResponseRecorder {
+	f.t.Helper()
+	var b strings.Builder
+	if body != nil {
+		if err := json.NewEncoder(&b).Encode(body); err != nil {
+			f.t.Fatal(err)
+		}
+	}
+	r := f.request(u, method, "/api/v1/assignments", strings.NewReader(b.String()), "application/json")
+	w := httptest.NewRecorder()
+	f.handlers.Assignments(w, r)
+	return w
+}
+
+// checkStatus fails the test when the response does not have the status,
+// or for redirects does not go to location.
+func checkStatus(t *testing.T, name string, w *httptest.ResponseRecorder, status int, location string) {
+	t.Helper()
+	if w.Code != status {
+		t.Errorf("%s: status %d, want %d", name, w.Code, status)
+		return
+	}
+	if location != "" && w.Header().Get("Location") != location {
+		t.Errorf("%s: redirected to %q, want %q", name, w.Header().Get("Location"), location)
+	}
+}
+
+func TestStudioAssign(t *testing.T) {
+	f := newStudioFixture(t)
+	form := func(studioID, studentID string) url.Values {
+		return url.Values{
+			"StudioID":  {studioID},
+			"StudentID": {studentID},
+			"Item":      {"scale/major/d/2"},
+			"Tempo":     {"72"},
+			"Due":       {time.Now().AddDate(0, 0, 7).Format("2006-01-02")},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		as       user.User
+		form     url.Values
+		status   int
+		location string
+	}{
+		{"teacher of the studio", f.teacher, form(f.studioID, f.otherStudent.ID), http.StatusSeeOther, "/studio"},
+		{"teacher of another studio", f.otherTeacher, form(f.studioID, f.student.ID), http.StatusForbidden, ""},
+		{"student not in the studio", f.teacher, form(f.studioID, f.otherTeacher.ID), http.StatusForbidden, ""},
+		{"student", f.student, form(f.studioID, f.otherStudent.ID), http.StatusForbidden, ""},
+		{"anonymous", user.User{}, form(f.studioID, f.student.ID), http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		checkStatus(t, tt.name, f.post(f.handlers.Assign, tt.as, tt.form), tt.status, tt.location)
+	}
+
+	if got := len(f.studios.StudentAssignments(f.otherStudent.ID)); got != 1 {
+		t.Errorf("other student has %d assignments, want 1", got)
+	}
+	if got := len(f.studios.StudentAssignments(f.student.ID)); got != 1 {
+		t.Errorf("student has %d assignments, want the 1 they started with", got)
+	}
+}
+
+func TestStudioInvite(t *testing.T) {
+	f := newStudioFixture(t)
+	form := func(studioID string) url.Values {
+		return url.Values{"StudioID": {studioID}}
+	}
+
+	tests := []struct {
+		name     string
+		as       user.User
+		form     url.Values
+		status   int
+		location string
+	}{
+		{"teacher of the studio", f.teacher, form(f.studioID), http.StatusSeeOther, "/studio"},
+		{"teacher of another studio", f.otherTeacher, form(f.studioID), http.StatusForbidden, ""},
+		{"student", f.student, form(f.studioID), http.StatusForbidden, ""},
+		{"anonymous", user.User{}, form(f.studioID), http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		checkStatus(t, tt.name, f.post(f.handlers.Invite, tt.as, tt.form), tt.status, tt.location)
+	}
+
+	invites, err := f.studios.Invites(f.teacher.ID, f.studioID, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(invites) != 2 {
+		t.Errorf("studio has %d invites, want the fixture's and the teacher's", len(invites))
+	}
+}
+
+func TestStudioComplete(t *testing.T) {
+	f := newStudioFixture(t)
+	form := url.Values{"AssignmentID": {f.assignmentID}, "Done": {"true"}}
+
+	tests := []struct {
+		name     string
+		as       user.User
+		status   int
+		location string
+	}{
+		{"another student", f.otherStudent, http.StatusForbidden, ""},
+		{"teacher", f.teacher, http.StatusForbidden, ""},
+		{"anonymous", user.User{}, http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		checkStatus(t, tt.name, f.post(f.handlers.Complete, tt.as, form), tt.status, tt.location)
+	}
+	if a := f.studios.StudentAssignments(f.student.ID); len(a) != 1 || a[0].Completed() {
+		t.Fatalf("assignment completed by someone else: %+v", a)
+	}
+
+	checkStatus(t, "assigned student", f.post(f.handlers.Complete, f.student, form), http.StatusSeeOther, "/studio")
+	if a := f.studios.StudentAssignments(f.student.ID); len(a) != 1 || !a[0].Completed() {
+		t.Errorf("assignment not completed by its student: %+v", a)
+	}
+}
+
+func TestStudioAssignments(t *testing.T) {
+	f := newStudioFixture(t)
+
+	tests := []struct {
+		name   string
+		as     user.User
+		method string
+		body   interface{}
+		status int
+	}{
+		{"anonymous get", user.User{}, http.MethodGet, nil, http.StatusUnauthorized},
+		{"anonymous post", user.User{}, http.MethodPost, f.newAssignment(f.studioID, f.student.ID), http.StatusUnauthorized},
+		{"student post", f.student, http.MethodPost, f.newAssignment(f.studioID, f.otherStudent.ID), http.StatusForbidden},
+		{"teacher of another studio", f.otherTeacher, http.MethodPost, f.newAssignment(f.studioID, f.student.ID), http.StatusForbidden},
+		{"teacher of the studio", f.teacher, http.MethodPost, f.newAssignment(f.studioID, f.otherStudent.ID), http.StatusCreated},
+	}
+	for _, tt := range tests {
+		checkStatus(t, tt.name, f.api(tt.as, tt.method, tt.body), tt.status, "")
+	}
+
+	// Everyone sees only the assignments of their own studios or their own.
+	visible := []struct {
+		name string
+		as   user.User
+		want int
+	}{
+		{"teacher", f.teacher, 2},
+		{"other teacher", f.otherTeacher, 0},
+		{"student", f.student, 1},
+		{"other student", f.otherStudent, 1},
+	}
+	for _, tt := range visible {
+		w := f.api(tt.as, http.MethodGet, nil)
+		checkStatus(t, tt.name+" get", w, http.StatusOK, "")
+		var views []json.RawMessage
+		if err := json.NewDecoder(w.Body).Decode(&views); err != nil {
+			t.Fatalf("%s: decoding assignments: %v", tt.name, err)
+		}
+		if len(views) != tt.want {
+			t.Errorf("%s sees %d assignments, want %d", tt.name, len(views), tt.want)
+		}
+	}
+}
+
+func TestSignupCreatesStudents(t *testing.T) {
+	f := newStudioFixture(t)
+	a := &Account{loglevel.Discard(), f.users, nil, nil, nil}
+
+	form := url.Values{"Name": {"new-teacher"}, "Password": {"password"}, "Role": {user.RoleTeacher}}
+	checkStatus(t, "signup", f.post(a.Signup, user.User{}, form), http.StatusSeeOther, "/progress")
+
+	u, err := f.users.Authenticate("new-teacher", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.IsTeacher() {
+		t.Errorf("signing up made a %s, want a %s", u.Role, user.RoleStudent)
+	}
+}
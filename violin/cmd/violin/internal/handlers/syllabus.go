@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"violin/internal/assets"
+	"violin/internal/loglevel"
+	"violin/internal/practice"
+	"violin/internal/render"
+	"violin/internal/syllabus"
+	"violin/internal/theory"
+)
+
+// Syllabus represents the handlers for browsing exam syllabus requirements.
+type Syllabus struct {
+	log    *loglevel.Logger
+	boards []syllabus.Board
+}
+
+// Browse handles GET calls for the syllabus page. Without a board and grade
+// it lists every board, otherwise it lists the requirements of the grade.
+func (s *Syllabus) Browse(w http.ResponseWriter, r *http.Request) {
+	s.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	pv := render.PageVars{
+		Title:  "Exam Syllabus",
+		Boards: s.boards,
+		Locale: localeOf(r),
+	}
+
+	q := r.URL.Query()
+	if q.Get("board") != "" {
+		board, grade, ok := syllabus.Find(s.boards, q.Get("board"), q.Get("grade"))
+		if !ok && board.ID == "" {
+			http.NotFound(w, r)
+			return
+		}
+		pv.Board = board
+		if ok {
+			pv.Grade = grade
+			pv.Title = board.Name + " " + grade.Name
+			for _, req := range grade.Requirements {
+				pv.Requirements = append(pv.Requirements, resolve(stateOf(r).Assets, req))
+			}
+		}
+	}
+
+	if err := renderPage(w, r, "syllabus.html", pv); err != nil {
+		s.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		return
+	}
+}
+
+// resolve matches a requirement to the scale page item that plays it and
+// works out where its audio and notation come from. Requirements the scale
+// page has no option for are missing both.
+func resolve(ix *assets.Index, req syllabus.Requirement) render.Requirement {
+	res := render.Requirement{
+		Requirement: req,
+		Audio:       render.SourceMissing,
+		Notation:    render.SourceMissing,
+		Missing:     []string{"audio", "notation"},
+	}
+
+	it, ok := requirementItem(req)
+	if !ok {
+		return res
+	}
+
+	key := render.SetActualKey(it.Pitch, it.Key)
+	img, audio, audio2 := render.SetAssetPaths(it.Pitch, it.Kind, key, it.Octave)
+	res.Audio = assetSource(ix, audio)
+	// The second recording is the melodic minor scale or a drone, which is
+	// built from whichever drones there are.
+	if _, melodic := render.ParseAssetPath(audio2); melodic && res.Audio == render.SourceFile {
+		res.Audio = assetSource(ix, audio2)
+	}
+	res.Notation = assetSource(ix, img)
+
+	res.Missing = nil
+	if res.Audio == render.SourceMissing {
+		res.Missing = append(res.Missing, "audio")
+	}
+	if res.Notation == render.SourceMissing {
+		res.Missing = append(res.Missing, "notation")
+	}
+	res.Item = it.ID()
+	res.Link = itemLink(it)
+	return res
+}
+
+// assetSource reports whether an asset is on disk, can be synthesized or
+// drawn as the exercises of the scale page are, or is missing.
+func assetSource(ix *assets.Index, path string) string {
+	if ix.Has(path) {
+		return render.SourceFile
+	}
+	if _, ok := render.ParseAssetPath(path); ok {
+		return render.SourceGenerated
+	}
+	return render.SourceMissing
+}
+
+// requirementItem returns the scale page item for a requirement, if the
+// scale page offers a matching scale, pitch, key and octave.
+func requirementItem(req syllabus.Requirement) (practice.Item, bool) {
+	var it practice.Item
+	switch req.Exercise {
+	case syllabus.ExerciseScale:
+		// The scale page plays harmonic and melodic minor scales, but not
+		// natural minor ones.
+		if req.Form == syllabus.FormNatural {
+			return it, false
+		}
+		it.Kind = practice.KindScale
+	case syllabus.ExerciseArpeggio:
+		it.Kind = practice.KindArpeggio
+	case syllabus.ExerciseDominant7:
+		it.Kind = practice.KindDominant7
+	case syllabus.ExerciseDiminished7:
+		it.Kind = practice.KindDiminished7
+	case syllabus.ExerciseThirds:
+		it.Kind = practice.KindThirds
+	case syllabus.ExerciseSixths:
+		it.Kind = practice.KindSixths
+	case syllabus.ExerciseOctaves:
+		it.Kind = practice.KindOctaves
+	default:
+		return it, false
+	}
+	it.Pitch = req.Pitch
+	if !practice.Pitched(it.Kind) {
+		it.Pitch = "Major"
+	}
+
+	scales, pitches, keys, octaves := render.SetDefaultOptions()
+	if !hasOption(scales, it.Kind) || !hasOption(pitches, it.Pitch) {
+		return it, false
+	}
+
+	it.Octave = strconv.Itoa(req.Octaves)
+	if !hasOption(octaves, it.Octave) {
+		return it, false
+	}
+
+	// Key options may hold two enharmonic names such as "C#/Db", match on
+	// pitch class rather than spelling.
+	pc, err := theory.PitchClass(req.Key)
+	if err != nil {
+		return it, false
+	}
+	for _, o := range keys {
+		names := []string{o.Value}
+		if len(o.Value) > 2 {
+			names = []string{o.Value[:2], o.Value[3:]}
+		}
+		for _, name := range names {
+			if kpc, err := theory.PitchClass(name); err == nil && kpc == pc {
+				it.Key = o.Value
+				return it, req.Octaves <= theory.MaxOctaves(it.Key, it.Pitch, it.Kind)
+			}
+		}
+	}
+	return it, false
+}
+
+// hasOption reports whether one of the options has the value.
+func hasOption(options []render.Option, value string) bool {
+	for _, o := range options {
+		if o.Value == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"violin/internal/audio"
+	"violin/internal/render"
+	"violin/internal/rhythm"
+
+	"github.com/pkg/errors"
+)
+
+// Sources of note timings.
+const (
+	timingSequence = "sequence" // worked out from the synthesized sequence
+	timingOnsets   = "onsets"   // detected in the recording
+)
+
+// noteTime is when a note of an exercise sounds, in seconds from the start
+// of its audio.
+type noteTime struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+// timing is when each drawn note of an exercise sounds in its audio.
+type timing struct {
+	Source string     `json:"source"`
+	Notes  []noteTime `json:"notes"`
+}
+
+// Timing handles GET calls for /timing/<recording>, such as
+// /timing/mp3/scale/major/a1.mp3?Rhythm=slur2. It returns as JSON when each
+// note of the exercise's drawn notation starts and ends in the audio
+// /audio/<recording> serves, so the scale page can follow the notes as they
+// play. Synthesized exercises are timed from their sequence, recordings from
+// the onsets detected in them.
+func (a *Audio) Timing(w http.ResponseWriter, r *http.Request) {
+	a.log.Debugf("%s %s -> %s", r.Method, r.URL.Path, r.RemoteAddr)
+	st := stateOf(r)
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		respondError(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	src := strings.TrimPrefix(r.URL.Path, "/timing/")
+	asset, ok := render.ParseAssetPath(src)
+	if path.Ext(src) != ".mp3" || !ok {
+		respondError(w, http.StatusNotFound, "no exercise "+src)
+		return
+	}
+	pattern := parsePattern(r.URL.Query())
+
+	var t timing
+	var err error
+	if st.Assets.Has(src) && pattern.ID == rhythm.Default {
+		t, err = a.recordingTiming(src, asset)
+	} else {
+		t, err = sequenceTiming(asset, pattern)
+	}
+	if err != nil {
+		a.log.Errorf("%s %s -> %s : ERROR %+v", r.Method, r.URL.Path, r.RemoteAddr, err)
+		respondError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+	respond(w, http.StatusOK, t)
+}
+
+// sequenceTiming times the notes of an exercise the way the synthesizer
+// plays them.
+func sequenceTiming(asset render.Asset, pattern rhythm.Pattern) (timing, error) {
+	chords, err := exerciseChords(asset, pattern)
+	if err != nil {
+		return timing{}, err
+	}
+	t := timing{Source: timingSequence, Notes: make([]noteTime, len(chords))}
+	var at time.Duration
+	for i, c := range chords {
+		length := synthNoteLength * time.Duration(c.Length()) / rhythm.Beat
+		t.Notes[i] = noteTime{at.Seconds(), (at + length).Seconds()}
+		at += length
+	}
+	return t, nil
+}
+
+// recordingTiming times the notes of an exercise from the onsets detected in
+// its recording, cached on disk. Each note is taken to start at one of the
+// strongest onsets and last until the next; when too few are found the
+// notes are spread evenly between the first onset and the end.
+func (a *Audio) recordingTiming(src string, asset render.Asset) (timing, error) {
+	plain, _ := rhythm.Find(rhythm.Default)
+	chords, err := exerciseChords(asset, plain)
+	if err != nil {
+		return timing{}, err
+	}
+
+	key, err := a.cache.Key([]string{src}, fmt.Sprintf("onsets %d", len(chords)))
+	if err != nil {
+		return timing{}, err
+	}
+	f, err := a.cache.Open(key, ".json", func(f *os.File) error {
+		b, err := decodePart(a.cache, src)
+		if err != nil {
+			return err
+		}
+		return json.NewEncoder(f).Encode(onsetTiming(b, len(chords)))
+	})
+	if err != nil {
+		return timing{}, err
+	}
+	defer f.Close()
+
+	var t timing
+	if err := json.NewDecoder(f).Decode(&t); err != nil {
+		return timing{}, errors.Wrap(err, "decoding cached timing")
+	}
+	return t, nil
+}
+
+// onsetTiming times n notes from the onsets of a recording.
+func onsetTiming(b *audio.Buffer, n int) timing {
+	end := b.Duration().Seconds()
+	starts := make([]float64, n)
+	onsets := audio.StrongestOnsets(audio.DetectOnsets(b), n)
+	if len(onsets) == n {
+		for i, o := range onsets {
+			starts[i] = o.Time.Seconds()
+		}
+	} else {
+		first := 0.0
+		if len(onsets) > 0 {
+			first = onsets[0].Time.Seconds()
+		}
+		for i := range starts {
+			starts[i] = first + (end-first)*float64(i)/float64(n)
+		}
+	}
+
+	t := timing{Source: timingOnsets, Notes: make([]noteTime, n)}
+	for i, s := range starts {
+		t.Notes[i] = noteTime{s, end}
+		if i+1 < n {
+			t.Notes[i].End = starts[i+1]
+		}
+	}
+	return t
+}
@@ -4,12 +4,29 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
+	"violin/cmd/violin/internal/commands"
 	"violin/cmd/violin/internal/handlers"
+	"violin/internal/assets"
+	"violin/internal/audio"
+	"violin/internal/certs"
+	"violin/internal/jsonfile"
+	"violin/internal/loglevel"
+	"violin/internal/planner"
+	"violin/internal/practice"
+	"violin/internal/quiz"
+	"violin/internal/ratelimit"
+	"violin/internal/render"
+	"violin/internal/studio"
+	"violin/internal/syllabus"
+	"violin/internal/user"
 
 	"github.com/ardanlabs/conf"
 	"github.com/pkg/errors"
@@ -22,35 +39,123 @@ func main() {
 	}
 }
 
-func run() error {
-	// =======================================================================================
-	// Logging
+// usage lists the commands violin runs, printed after its configuration.
+const usage = `Commands:
+  serve                     run the web server, the default
+  assets check              report asset files no page uses and recordings not measured
+  assets index              save the asset index with its loudness measurements
+  assets normalize          measure the recordings and report the gain applied to each
+  render <exercise>         write an exercise as svg, wav, midi or musicxml
+                            e.g. violin render scale --key D --pitch minor --format svg -o d.svg
+  export-static [dir] [lang] write a read-only site to dir, static by default
+  user create               add an account: --name, --password and --role`
 
-	log := log.New(os.Stdout, "VIOLIN", log.Lshortfile|log.Ldate|log.Lmicroseconds)
+// config is the configuration every command shares. Web, Data and Audio
+// configure the server and the files the commands read, Render and User
+// the options of the render and user commands.
+//
+// Web.TLS is off to serve plain HTTP, on to serve HTTPS with the
+// certificate in Web.CertFile and Web.KeyFile, or dev to serve HTTPS with
+// a self-signed certificate generated under Data.Dir on first run. Over
+// HTTPS, Web.RedirectHost is an optional plain HTTP listener redirecting to
+// it, and Web.HSTSMaxAge how long browsers keep to HTTPS; dev sends no
+// HSTS.
+//
+// Audio.CacheMB caps the megabytes of converted and mixed audio kept under
+// Data.Dir/cache, the least recently used going first. 0 does not cap it.
+//
+// Log.Level is debug to log every request, info to log only what the
+// server does and errors, or error to log errors only. The log_level of
+// the server settings in Data.Dir/server.json, when there is one, takes
+// its place, and is read again on SIGHUP.
+//
+// Security turns the Content-Security-Policy and the CSRF tokens of forms
+// on or off, and sets how many calls a second each client may make to the
+// dynamic pages and APIs, and how many forms and data it may post, once
+// it has made the burst of them allowed at once. A rate of 0 turns the
+// limit off.
+type config struct {
+	Web struct {
+		APIHost         string        `conf:"default:0.0.0.0:8080"`
+		ReadTimeout     time.Duration `conf:"default:5s"`
+		WriteTimeout    time.Duration `conf:"default:5s"`
+		ShutdownTimeout time.Duration `conf:"default:5s"`
+		TLS             string        `conf:"default:off"`
+		CertFile        string
+		KeyFile         string
+		RedirectHost    string
+		HSTSMaxAge      time.Duration `conf:"default:8760h"`
+	}
+	Data struct {
+		Dir         string `conf:"default:data"`
+		SyllabusDir string `conf:"default:syllabus"`
+	}
+	Audio struct {
+		TargetLoudness float64 `conf:"default:-16"`
+		CacheMB        int64   `conf:"default:2048"`
+	}
+	Log struct {
+		Level string `conf:"default:debug"`
+	}
+	Security struct {
+		CSP        bool    `conf:"default:true"`
+		CSRF       bool    `conf:"default:true"`
+		ReadRate   float64 `conf:"default:20"`
+		ReadBurst  int     `conf:"default:100"`
+		WriteRate  float64 `conf:"default:1"`
+		WriteBurst int     `conf:"default:20"`
+	}
+	Render struct {
+		Key    string `conf:"default:A,flag:key"`
+		Pitch  string `conf:"default:major,flag:pitch"`
+		Octave string `conf:"default:1,flag:octave"`
+		Format string `conf:"default:svg,flag:format"`
+		Rhythm string `conf:"flag:rhythm"`
+		BPM    int    `conf:"flag:bpm"`
+		Output string `conf:"flag:output,short:o"`
+	}
+	User struct {
+		Name     string `conf:"flag:name"`
+		Password string `conf:"flag:password,noprint"`
+		Role     string `conf:"default:student,flag:role"`
+	}
+	Args conf.Args
+}
 
+func run() error {
 	// =======================================================================================
 	// Configuration
 
-	var cfg struct {
-		Web struct {
-			APIHost         string        `conf:"default:0.0.0.0:8080"`
-			ReadTimeout     time.Duration `conf:"default:5s"`
-			WriteTimeout    time.Duration `conf:"default:5s"`
-			ShutdownTimeout time.Duration `conf:"default:5s"`
-		}
-	}
-	if err := conf.Parse(os.Args[1:], "VIOLIN", &cfg); err != nil {
+	var cfg config
+	if err := conf.Parse(flagsFirst(os.Args[1:]), "VIOLIN", &cfg); err != nil {
 		if errors.Is(err, conf.ErrHelpWanted) {
-			usage, err := conf.Usage("VIOLIN", &cfg)
+			help, err := conf.Usage("VIOLIN", &cfg)
 			if err != nil {
 				return errors.Wrap(err, "generating config error")
 			}
+			fmt.Println(help)
 			fmt.Println(usage)
 			return nil
 		}
 		return errors.Wrap(err, "parsing config")
 	}
 
+	// =======================================================================================
+	// Logging
+
+	// Only the server logs to stdout, the other commands keep it for their
+	// output, such as a rendered exercise piped to a file.
+	logOut := os.Stderr
+	if cmd := cfg.Args.Num(0); cmd == "" || cmd == "serve" {
+		logOut = os.Stdout
+	}
+	level, err := logLevel(cfg)
+	if err != nil {
+		return err
+	}
+	logs := loglevel.NewWriter(logOut, level)
+	log := loglevel.New(logs, "VIOLIN", log.Lshortfile|log.Ldate|log.Lmicroseconds)
+
 	log.Println("main : Started : Application initializing")
 	defer log.Println("main : Completed")
 
@@ -60,51 +165,374 @@ func run() error {
 	}
 	log.Printf("main : Config :\n%v\n", out)
 
+	// =======================================================================================
+	// Commands
+
+	indexPath := filepath.Join(cfg.Data.Dir, "assets.json")
+	switch cfg.Args.Num(0) {
+	case "", "serve":
+		return serve(log, logs, cfg)
+	case "assets":
+		index, err := assets.Build(".", "img", "mp3")
+		if err != nil {
+			return errors.Wrap(err, "indexing assets")
+		}
+		switch cfg.Args.Num(1) {
+		case "check":
+			return commands.Check(log.Logger, index, indexPath)
+		case "index":
+			return commands.Index(log.Logger, index, indexPath)
+		case "normalize":
+			return commands.Normalize(log.Logger, index, indexPath, cfg.Audio.TargetLoudness)
+		}
+		return errors.Errorf("unknown assets command %q, want: violin assets check|index|normalize", cfg.Args.Num(1))
+	case "render":
+		return renderExercise(log, cfg)
+	case "export-static":
+		dir := cfg.Args.Num(1)
+		if dir == "" {
+			dir = "static"
+		}
+		st, err := loadState(cfg)
+		if err != nil {
+			return err
+		}
+		return commands.ExportStatic(log.Logger, handlers.NewExportMux(log, st), dir, cfg.Args.Num(2))
+	case "user":
+		if cfg.Args.Num(1) != "create" {
+			return errors.Errorf("unknown user command %q, want: violin user create", cfg.Args.Num(1))
+		}
+		users, err := user.NewStore(filepath.Join(cfg.Data.Dir, "users.json"))
+		if err != nil {
+			return errors.Wrap(err, "opening user store")
+		}
+		return commands.CreateUser(log.Logger, users, cfg.User.Name, cfg.User.Password, cfg.User.Role)
+	}
+	return errors.Errorf("unknown command %q, want one of: serve, assets, render, export-static, user", cfg.Args.Num(0))
+}
+
+// flagsFirst moves the flags of a command line ahead of its command, as
+// conf stops reading flags at the first argument that is not one, so that
+// violin render scale --key D reads as violin --key D render scale. A flag
+// without = takes the argument after it as its value, as it does in conf.
+func flagsFirst(args []string) []string {
+	var flags, rest []string
+	for len(args) > 0 {
+		s := args[0]
+		args = args[1:]
+		switch {
+		case s == "--":
+			return append(append(flags, rest...), args...)
+		case len(s) < 2 || s[0] != '-':
+			rest = append(rest, s)
+		default:
+			flags = append(flags, s)
+			if !strings.Contains(s, "=") && len(args) > 0 && len(args[0]) > 0 && args[0][0] != '-' {
+				flags = append(flags, args[0])
+				args = args[1:]
+			}
+		}
+	}
+	return append(flags, rest...)
+}
+
+// renderExercise writes the exercise named on the command line to the output file,
+// or to stdout if there is none.
+func renderExercise(log *loglevel.Logger, cfg config) error {
+	if cfg.Args.Num(1) == "" {
+		return errors.New("missing exercise, e.g. violin render scale --key D --pitch minor")
+	}
+
+	st, err := loadState(cfg)
+	if err != nil {
+		return err
+	}
+	cache, err := audio.NewCache(filepath.Join(cfg.Data.Dir, "cache"), cfg.Audio.CacheMB<<20)
+	if err != nil {
+		return errors.Wrap(err, "opening audio cache")
+	}
+	mux := handlers.NewRenderMux(log, st, cache, cfg.Audio.TargetLoudness)
+
+	ex := commands.Exercise{
+		Kind:   cfg.Args.Num(1),
+		Key:    cfg.Render.Key,
+		Pitch:  cfg.Render.Pitch,
+		Octave: cfg.Render.Octave,
+		Rhythm: cfg.Render.Rhythm,
+		BPM:    cfg.Render.BPM,
+	}
+
+	if cfg.Render.Output == "" {
+		return commands.Render(mux, os.Stdout, ex, strings.ToLower(cfg.Render.Format))
+	}
+	f, err := os.Create(cfg.Render.Output)
+	if err != nil {
+		return errors.Wrap(err, "creating output")
+	}
+	if err := commands.Render(mux, f, ex, strings.ToLower(cfg.Render.Format)); err != nil {
+		f.Close()
+		os.Remove(cfg.Render.Output)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "writing output")
+	}
+	log.Printf("render : wrote %s", cfg.Render.Output)
+	return nil
+}
+
+// loadIndex indexes the asset files with the loudness measurements saved
+// for them.
+func loadIndex(cfg config) (*assets.Index, error) {
+	index, err := assets.Build(".", "img", "mp3")
+	if err != nil {
+		return nil, errors.Wrap(err, "indexing assets")
+	}
+	measured, err := assets.Load(filepath.Join(cfg.Data.Dir, "assets.json"))
+	if err != nil {
+		return nil, errors.Wrap(err, "loading loudness measurements")
+	}
+	index.Merge(measured)
+	return index, nil
+}
+
+// loadState loads what the handlers serve: the asset index with its
+// loudness measurements, the duets it holds and the page templates.
+func loadState(cfg config) (*handlers.State, error) {
+	index, err := loadIndex(cfg)
+	if err != nil {
+		return nil, err
+	}
+	templates, err := render.LoadTemplates("templates")
+	if err != nil {
+		return nil, errors.Wrap(err, "loading templates")
+	}
+	st, err := handlers.NewState(index, templates, cfg.Audio.TargetLoudness)
+	if err != nil {
+		return nil, errors.Wrap(err, "checking assets")
+	}
+	return st, nil
+}
+
+// serverSettings are the settings of a running server that SIGHUP reads
+// again from Data.Dir/server.json.
+type serverSettings struct {
+	LogLevel string `json:"log_level"`
+}
+
+// logLevel returns the level to log at: the one in the server settings,
+// or Log.Level when they set none.
+func logLevel(cfg config) (loglevel.Level, error) {
+	var settings serverSettings
+	if err := jsonfile.Load(filepath.Join(cfg.Data.Dir, "server.json"), &settings); err != nil {
+		return loglevel.Debug, errors.Wrap(err, "loading server settings")
+	}
+	if settings.LogLevel == "" {
+		settings.LogLevel = cfg.Log.Level
+	}
+	return loglevel.Parse(settings.LogLevel)
+}
+
+// serve runs the web server until it fails or the OS asks it to stop.
+func serve(log *loglevel.Logger, logs *loglevel.Writer, cfg config) error {
+	// =======================================================================================
+	// Storage
+
+	users, err := user.NewStore(filepath.Join(cfg.Data.Dir, "users.json"))
+	if err != nil {
+		return errors.Wrap(err, "opening user store")
+	}
+	sessions, err := practice.NewStore(filepath.Join(cfg.Data.Dir, "practice"))
+	if err != nil {
+		return errors.Wrap(err, "opening practice store")
+	}
+	plans, err := planner.NewStore(filepath.Join(cfg.Data.Dir, "planner.json"))
+	if err != nil {
+		return errors.Wrap(err, "opening planner store")
+	}
+	studios, err := studio.NewStore(filepath.Join(cfg.Data.Dir, "studios.json"))
+	if err != nil {
+		return errors.Wrap(err, "opening studio store")
+	}
+	quizzes, err := quiz.NewStore(filepath.Join(cfg.Data.Dir, "quizzes.json"))
+	if err != nil {
+		return errors.Wrap(err, "opening quiz store")
+	}
+	cache, err := audio.NewCache(filepath.Join(cfg.Data.Dir, "cache"), cfg.Audio.CacheMB<<20)
+	if err != nil {
+		return errors.Wrap(err, "opening audio cache")
+	}
+
+	// =======================================================================================
+	// Content
+
+	st, err := loadState(cfg)
+	if err != nil {
+		return err
+	}
+	states := handlers.NewStates(st)
+	boards, err := syllabus.Load(cfg.Data.SyllabusDir)
+	if err != nil {
+		return errors.Wrap(err, "loading syllabus")
+	}
+
+	reloader, err := loadTLS(log, cfg)
+	if err != nil {
+		return err
+	}
+
+	mux := handlers.NewMux(log, users, sessions, plans, studios, quizzes, boards, states, cache, cfg.Audio.TargetLoudness)
 	api := http.Server{
 		Addr:         cfg.Web.APIHost,
-		Handler:      handlers.NewMux(log),
+		Handler:      mux,
 		ReadTimeout:  cfg.Web.ReadTimeout,
 		WriteTimeout: cfg.Web.WriteTimeout,
 	}
+	api.Handler = handlers.Secure(log, mux, handlers.Security{
+		CSP:    cfg.Security.CSP,
+		CSRF:   cfg.Security.CSRF,
+		Reads:  ratelimit.New(cfg.Security.ReadRate, cfg.Security.ReadBurst),
+		Writes: ratelimit.New(cfg.Security.WriteRate, cfg.Security.WriteBurst),
+	})
+	if reloader != nil {
+		api.TLSConfig = reloader.Config()
+		if cfg.Web.TLS == "on" {
+			api.Handler = handlers.HSTS(api.Handler, cfg.Web.HSTSMaxAge)
+		}
+	}
 
 	// Mark a channel to listen for an interrupt or terminate signal from the OS.
 	// Use a buffered channel because the signal package requires it.
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
 
-	serverErrors := make(chan error, 1)
+	// SIGHUP asks for the templates, assets, server settings and certificate
+	// to be loaded again, without stopping the listeners.
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+
+	servers := []*http.Server{&api}
+	serverErrors := make(chan error, 2)
 	go func() {
-		log.Printf("main : API listening on %s", api.Addr)
-		serverErrors <- api.ListenAndServe()
+		if reloader == nil {
+			log.Printf("main : API listening on %s", api.Addr)
+			serverErrors <- api.ListenAndServe()
+			return
+		}
+		log.Printf("main : API listening on %s with TLS", api.Addr)
+		serverErrors <- api.ListenAndServeTLS("", "")
 	}()
 
+	if reloader != nil && cfg.Web.RedirectHost != "" {
+		redirect := http.Server{
+			Addr:         cfg.Web.RedirectHost,
+			Handler:      handlers.NewRedirect(log, cfg.Web.APIHost),
+			ReadTimeout:  cfg.Web.ReadTimeout,
+			WriteTimeout: cfg.Web.WriteTimeout,
+		}
+		servers = append(servers, &redirect)
+		go func() {
+			log.Printf("main : Redirect listening on %s", redirect.Addr)
+			serverErrors <- redirect.ListenAndServe()
+		}()
+	}
+
 	// =======================================================================================
 	// Configuration
 
-	select {
-	case err := <-serverErrors:
-		return errors.Wrap(err, "server error")
-	case sig := <-shutdown:
-		log.Printf("main : %v : Start shutdown", sig)
+	for {
+		select {
+		case err := <-serverErrors:
+			return errors.Wrap(err, "server error")
+		case <-reload:
+			reloadServer(log, logs, cfg, states, reloader)
+		case sig := <-shutdown:
+			log.Printf("main : %v : Start shutdown", sig)
 
-		// Give outstanding requests a deadline for completion.
-		ctx, cancel := context.WithTimeout(context.Background(), cfg.Web.ShutdownTimeout)
-		defer cancel()
+			// Give outstanding requests a deadline for completion.
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.Web.ShutdownTimeout)
+			defer cancel()
 
-		// Asking listener to shutdown and load shed.
-		err := api.Shutdown(ctx)
-		if err != nil {
-			log.Printf("main : Graceful shutdown did not complete in %v : %v", cfg.Web.ShutdownTimeout, err)
-			err = api.Close()
+			// Asking listeners to shutdown and load shed.
+			var err error
+			for _, srv := range servers {
+				if serr := srv.Shutdown(ctx); serr != nil {
+					log.Printf("main : Graceful shutdown did not complete in %v : %v", cfg.Web.ShutdownTimeout, serr)
+					err = srv.Close()
+				}
+			}
+
+			// log the status of this shutdown.
+			if err != nil {
+				return errors.Wrap(err, "could not stop server gracefully")
+			}
+			return nil
 		}
+	}
+}
 
-		// log the status of this shutdown.
-		switch {
-		case sig == syscall.SIGSTOP:
-			return errors.Wrap(err, "integrity issue caused shutdown")
-		case err != nil:
-			return errors.Wrap(err, "could not stop server gracefully")
+// reloadServer loads what the handlers serve and the server settings
+// again, then the certificate when serving HTTPS. Requests already running
+// finish with what they started with. Until everything loads the server
+// keeps serving what it had, so a broken template or settings file is only
+// logged.
+func reloadServer(log *loglevel.Logger, logs *loglevel.Writer, cfg config, states *handlers.States, reloader *certs.Reloader) {
+	st, err := loadState(cfg)
+	if err != nil {
+		log.Errorf("main : SIGHUP : Keeping the current state : ERROR %+v", err)
+		return
+	}
+	level, err := logLevel(cfg)
+	if err != nil {
+		log.Errorf("main : SIGHUP : Keeping the current state : ERROR %+v", err)
+		return
+	}
+	states.Store(st)
+	logs.SetLevel(level)
+	log.Printf("main : SIGHUP : Reloaded %d assets, %d duets and %d templates, logging at %s", len(st.Assets.Files()), st.Duets(), st.Templates.Len(), level)
+
+	if reloader == nil {
+		return
+	}
+	if err := reloader.Reload(); err != nil {
+		log.Errorf("main : SIGHUP : Keeping the current certificate : ERROR %+v", err)
+		return
+	}
+	log.Println("main : SIGHUP : Reloaded certificate")
+}
+
+// loadTLS loads the certificate the server is configured to serve HTTPS
+// with, generating a self-signed one in dev mode, or returns nil to serve
+// plain HTTP.
+func loadTLS(log *loglevel.Logger, cfg config) (*certs.Reloader, error) {
+	certFile, keyFile := cfg.Web.CertFile, cfg.Web.KeyFile
+	switch cfg.Web.TLS {
+	case "off":
+		return nil, nil
+	case "on":
+		if certFile == "" || keyFile == "" {
+			return nil, errors.New("serving TLS needs --web-cert-file and --web-key-file")
 		}
+	case "dev":
+		if certFile == "" || keyFile == "" {
+			certFile = filepath.Join(cfg.Data.Dir, "tls", "dev-cert.pem")
+			keyFile = filepath.Join(cfg.Data.Dir, "tls", "dev-key.pem")
+		}
+		host, _, _ := net.SplitHostPort(cfg.Web.APIHost)
+		generated, err := certs.EnsureSelfSigned(certFile, keyFile, []string{host}, time.Now())
+		if err != nil {
+			return nil, errors.Wrap(err, "generating development certificate")
+		}
+		if generated {
+			log.Printf("main : Generated self-signed certificate %s", certFile)
+		}
+	default:
+		return nil, errors.Errorf("unknown TLS mode %q, want off, on or dev", cfg.Web.TLS)
 	}
-	return nil
+
+	reloader, err := certs.NewReloader(certFile, keyFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "loading certificate")
+	}
+	return reloader, nil
 }
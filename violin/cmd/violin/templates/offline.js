@@ -0,0 +1,63 @@
+// Registers the GoViolin service worker and runs the toggles that keep keys
+// and duets for offline use. The items kept are remembered per browser, as
+// that is where their files are cached.
+(function () {
+  'use strict';
+
+  var KEPT = 'violin-offline';
+
+  if (!('serviceWorker' in navigator)) {
+    return;
+  }
+  navigator.serviceWorker.register('/sw.js');
+
+  function kept() {
+    try {
+      return JSON.parse(localStorage.getItem(KEPT)) || [];
+    } catch (e) {
+      return [];
+    }
+  }
+
+  function keep(items, done) {
+    navigator.serviceWorker.ready.then(function (registration) {
+      var channel = new MessageChannel();
+      channel.port1.onmessage = function (event) {
+        done(event.data.ok);
+      };
+      registration.active.postMessage({type: 'keep', items: items}, [channel.port2]);
+    });
+  }
+
+  document.addEventListener('DOMContentLoaded', function () {
+    var toggles = document.querySelectorAll('.offlinetoggle');
+    for (var i = 0; i < toggles.length; i++) {
+      (function (toggle) {
+        var box = toggle.querySelector('input');
+        var status = toggle.querySelector('.offlinestatus');
+        var item = toggle.getAttribute('data-item');
+
+        toggle.style.display = 'block';
+        box.checked = kept().indexOf(item) >= 0;
+        box.addEventListener('change', function () {
+          var items = kept().filter(function (it) {
+            return it !== item;
+          });
+          if (box.checked) {
+            items.push(item);
+          }
+          localStorage.setItem(KEPT, JSON.stringify(items));
+
+          status.textContent = box.checked ? toggle.getAttribute('data-saving') : '';
+          keep(items, function (ok) {
+            if (!ok) {
+              status.textContent = toggle.getAttribute('data-failed');
+            } else if (box.checked) {
+              status.textContent = toggle.getAttribute('data-saved');
+            }
+          });
+        });
+      })(toggles[i]);
+    }
+  });
+})();
@@ -0,0 +1,199 @@
+// GoViolin service worker. It keeps the pages of the site and the images
+// of the asset index cached, plus the pages and recordings of the keys and
+// duets marked for offline use, so practice goes on without a connection.
+'use strict';
+
+// config is written by the server from its asset index: the index version,
+// the pages to precache and the images with the version of each.
+const config = {{.}};
+
+const PAGES = 'violin-pages-' + config.version;
+const ASSETS = 'violin-assets';
+const KEPT = 'violin-kept';
+const STATE = '/offline/state';
+
+// Pages whose form posts are answered from the cache when offline, with
+// the fields that pick what they show.
+const FORMS = {
+  '/scaleshow': ['Key', 'Octave', 'Pitch', 'Scale'],
+  '/duetshow': ['Duet'],
+};
+
+self.addEventListener('install', (event) => {
+  event.waitUntil(
+    caches.open(PAGES).then((cache) => cache.addAll(config.pages)).then(() => self.skipWaiting())
+  );
+});
+
+self.addEventListener('activate', (event) => {
+  event.waitUntil(
+    caches.keys()
+      .then((names) => Promise.all(
+        names.filter((name) => name.startsWith('violin-pages-') && name !== PAGES).map((name) => caches.delete(name))
+      ))
+      .then(() => self.clients.claim())
+      .then(() => readState())
+      .then((state) => queue(state.items))
+      .catch(() => {})
+  );
+});
+
+self.addEventListener('message', (event) => {
+  if (!event.data || event.data.type !== 'keep') {
+    return;
+  }
+  const port = event.ports[0];
+  event.waitUntil(
+    queue(event.data.items).then(
+      () => port && port.postMessage({ok: true}),
+      (err) => port && port.postMessage({ok: false, error: String(err)})
+    )
+  );
+});
+
+self.addEventListener('fetch', (event) => {
+  const request = event.request;
+  const url = new URL(request.url);
+  if (url.origin !== self.location.origin) {
+    return;
+  }
+  // Images and recordings only change with their version, so the cached
+  // copy is served first.
+  if (request.method === 'GET' && (url.pathname.startsWith('/img/') || url.pathname.startsWith('/audio/'))) {
+    event.respondWith(caches.match(url.pathname + url.search).then((hit) => hit || fetch(request)));
+    return;
+  }
+  if (request.mode === 'navigate' || (request.method === 'GET' && config.pages.includes(url.pathname))) {
+    event.respondWith(fromNetwork(request));
+  }
+});
+
+// fromNetwork fetches a page, falling back to the cached copy of what it
+// shows when there is no connection.
+async function fromNetwork(request) {
+  const copy = request.clone();
+  try {
+    return await fetch(request);
+  } catch (err) {
+    const hit = await caches.match(await cacheKey(copy));
+    if (hit) {
+      return hit;
+    }
+    throw err;
+  }
+}
+
+// cacheKey returns the url a page is cached under. Scale and duet pages
+// are kept under the link to them with only the fields that pick the
+// exercise, in the order the server writes them, whether they were
+// linked to or posted.
+async function cacheKey(request) {
+  const url = new URL(request.url);
+  const fields = FORMS[url.pathname];
+  if (!fields) {
+    return url.pathname + url.search;
+  }
+  const form = request.method === 'POST' ? new URLSearchParams(await request.text()) : url.searchParams;
+  const query = new URLSearchParams();
+  for (const field of fields) {
+    if (form.has(field)) {
+      query.set(field, form.get(field));
+    }
+  }
+  return url.pathname + '?' + query.toString();
+}
+
+let syncing = Promise.resolve();
+
+// queue runs sync after any sync already running, one at a time.
+function queue(items) {
+  syncing = syncing.catch(() => {}).then(() => sync(items || []));
+  return syncing;
+}
+
+// sync brings the caches in line with the images of the index and the
+// asset bundles of the items kept offline. Files are fetched only when
+// their version differs from the one cached, files no longer needed are
+// dropped, and the pages of the items are fetched again as they are small
+// and follow the language settings.
+async function sync(items) {
+  const state = await readState();
+  const wanted = new Map();
+  for (const f of config.files) {
+    wanted.set(f.url, f.version);
+  }
+  const pages = [];
+  if (items.length > 0) {
+    const query = items.map((item) => 'item=' + encodeURIComponent(item)).join('&');
+    const response = await fetch('/api/v1/bundle?' + query, {cache: 'no-cache'});
+    if (!response.ok) {
+      throw new Error('fetching bundle: ' + response.status);
+    }
+    const bundle = await response.json();
+    for (const item of bundle.items) {
+      pages.push(...item.pages);
+      for (const f of item.files) {
+        wanted.set(f.url, f.version);
+      }
+    }
+  }
+
+  const assets = await caches.open(ASSETS);
+  const versions = {};
+  try {
+    for (const [url, version] of wanted) {
+      if (state.versions[url] !== version || !(await assets.match(url))) {
+        const response = await fetch(url, {cache: 'reload'});
+        if (!response.ok) {
+          throw new Error('fetching ' + url + ': ' + response.status);
+        }
+        await assets.put(url, response);
+      }
+      versions[url] = version;
+    }
+    for (const url of Object.keys(state.versions)) {
+      if (!wanted.has(url)) {
+        await assets.delete(url);
+      }
+    }
+
+    const kept = await caches.open(KEPT);
+    for (const request of await kept.keys()) {
+      const url = new URL(request.url);
+      if (!pages.includes(url.pathname + url.search)) {
+        await kept.delete(request);
+      }
+    }
+    for (const page of pages) {
+      const response = await fetch(page, {cache: 'reload'});
+      if (response.ok) {
+        await kept.put(page, response);
+      }
+    }
+  } finally {
+    // Files fetched before a failure are kept, the others are fetched
+    // again by the next sync.
+    for (const [url, version] of wanted) {
+      if (!(url in versions) && state.versions[url] === version) {
+        versions[url] = version;
+      }
+    }
+    await writeState({items: items, versions: versions});
+  }
+}
+
+// readState returns the items kept offline and the version of each cached
+// file.
+async function readState() {
+  const response = await caches.match(STATE);
+  if (!response) {
+    return {items: [], versions: {}};
+  }
+  return response.json();
+}
+
+// writeState saves what readState returns.
+async function writeState(state) {
+  const assets = await caches.open(ASSETS);
+  await assets.put(STATE, new Response(JSON.stringify(state), {headers: {'Content-Type': 'application/json'}}));
+}
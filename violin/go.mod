@@ -4,5 +4,14 @@ go 1.18
 
 require (
 	github.com/ardanlabs/conf v1.5.0
+	github.com/hajimehoshi/go-mp3 v0.3.4
+	github.com/mewkiz/flac v1.0.10
 	github.com/pkg/errors v0.9.1
+	golang.org/x/crypto v0.14.0
+	golang.org/x/sync v0.6.0
+)
+
+require (
+	github.com/icza/bitio v1.1.0 // indirect
+	github.com/mewkiz/pkg v0.0.0-20230226050401-4010bf0fec14 // indirect
 )
@@ -0,0 +1,81 @@
+// Package assets indexes the static image and audio files served by
+// GoViolin.
+package assets
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// File describes one indexed asset.
+type File struct {
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	ModTime  time.Time `json:"mod_time"`
+	Loudness *Loudness `json:"loudness,omitempty"`
+}
+
+// Index is the set of asset files found under a root directory, keyed by
+// slash separated paths relative to the root such as "mp3/drone/a1.mp3".
+type Index struct {
+	root  string
+	files map[string]File
+}
+
+// Build walks each of the dirs below root and indexes the files found.
+func Build(root string, dirs ...string) (*Index, error) {
+	ix := Index{
+		root:  root,
+		files: make(map[string]File),
+	}
+
+	for _, dir := range dirs {
+		err := filepath.WalkDir(filepath.Join(root, dir), func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+			ix.files[rel] = File{Path: rel, Size: info.Size(), ModTime: info.ModTime().UTC()}
+			return nil
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return nil, errors.Wrapf(err, "indexing %s", dir)
+		}
+	}
+
+	return &ix, nil
+}
+
+// Has reports whether the asset at path exists.
+func (ix *Index) Has(path string) bool {
+	_, ok := ix.files[path]
+	return ok
+}
+
+// Files returns every indexed file ordered by path.
+func (ix *Index) Files() []File {
+	files := make([]File, 0, len(ix.files))
+	for _, f := range ix.files {
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return files
+}
@@ -0,0 +1,35 @@
+package assets
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+)
+
+// Version identifies the content of the file by its size, modification
+// time and loudness, it changes whenever the file is replaced or measured
+// again, as that changes the gain it is served with.
+func (f File) Version() string {
+	h := sha1.New()
+	fmt.Fprintf(h, "%d %d", f.Size, f.ModTime.UnixNano())
+	if f.Loudness != nil {
+		fmt.Fprintf(h, " %g %g", f.Loudness.Integrated, f.Loudness.Peak)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// File returns the indexed file at path.
+func (ix *Index) File(path string) (File, bool) {
+	f, ok := ix.files[path]
+	return f, ok
+}
+
+// Version identifies the whole index, it changes whenever a file is added,
+// removed or replaced.
+func (ix *Index) Version() string {
+	h := sha1.New()
+	for _, f := range ix.Files() {
+		fmt.Fprintf(h, "%s %s\n", f.Path, f.Version())
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
@@ -0,0 +1,137 @@
+package assets
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"violin/internal/jsonfile"
+
+	"github.com/pkg/errors"
+)
+
+// OutlierLU is how far, in loudness units, a recording may sit from the
+// median of its family before it is reported to be checked, since it may
+// be badly recorded or in the wrong family.
+const OutlierLU = 3
+
+// PeakCeiling is the highest sample peak, in dBFS, normalization may raise
+// a recording to.
+const PeakCeiling = -1.0
+
+// Silence is the loudness recorded for recordings too quiet or too short to
+// measure, the absolute gate of BS.1770. They are never normalized.
+const Silence = -70.0
+
+// Loudness is the EBU R128 measurement of an audio asset.
+type Loudness struct {
+	Integrated float64 `json:"integrated"` // LUFS
+	Peak       float64 `json:"peak"`       // sample peak in dBFS
+}
+
+// Normalization is the gain that brings a recording to the target loudness.
+type Normalization struct {
+	File
+	Family         string
+	FamilyLoudness float64 // median integrated loudness of the family
+	Gain           float64 // dB
+	Outlier        bool    // too far from the family median
+	Limited        bool    // the peak ceiling kept it below the target
+}
+
+// Load reads an index saved by Save. A missing file gives an empty index.
+func Load(path string) (*Index, error) {
+	var files []File
+	if err := jsonfile.Load(path, &files); err != nil {
+		return nil, errors.Wrap(err, "loading asset index")
+	}
+
+	ix := Index{files: make(map[string]File)}
+	for _, f := range files {
+		ix.files[f.Path] = f
+	}
+	return &ix, nil
+}
+
+// Save writes the index, including loudness measurements, to path.
+func (ix *Index) Save(path string) error {
+	if err := jsonfile.Save(path, ix.Files()); err != nil {
+		return errors.Wrap(err, "saving asset index")
+	}
+	return nil
+}
+
+// Merge copies the loudness measurements of saved into the index for every
+// file that has not changed since it was measured.
+func (ix *Index) Merge(saved *Index) {
+	for path, f := range ix.files {
+		old, ok := saved.files[path]
+		if !ok || old.Loudness == nil || old.Size != f.Size || !old.ModTime.Equal(f.ModTime) {
+			continue
+		}
+		f.Loudness = old.Loudness
+		ix.files[path] = f
+	}
+}
+
+// SetLoudness records the measured loudness of the asset at path.
+func (ix *Index) SetLoudness(path string, l Loudness) {
+	f, ok := ix.files[path]
+	if !ok {
+		return
+	}
+	f.Loudness = &l
+	ix.files[path] = f
+}
+
+// Family returns the family of an asset, the directory below the top level
+// such as "drone" for "mp3/drone/a1.mp3".
+func Family(path string) string {
+	parts := strings.Split(path, "/")
+	if len(parts) < 3 {
+		return ""
+	}
+	return parts[1]
+}
+
+// Normalize works out the gain for every measured recording, which brings
+// it from its own integrated loudness to the target so that switching
+// between recordings does not change the volume. Recordings far from the
+// median of their family are marked as outliers. No recording is raised
+// above the peak ceiling.
+func (ix *Index) Normalize(target float64) []Normalization {
+	families := make(map[string][]float64)
+	for _, f := range ix.Files() {
+		if f.Loudness != nil && f.Loudness.Integrated > Silence {
+			fam := Family(f.Path)
+			families[fam] = append(families[fam], f.Loudness.Integrated)
+		}
+	}
+	medians := make(map[string]float64)
+	for fam, values := range families {
+		sort.Float64s(values)
+		mid := len(values) / 2
+		medians[fam] = values[mid]
+		if len(values)%2 == 0 {
+			medians[fam] = (values[mid-1] + values[mid]) / 2
+		}
+	}
+
+	var list []Normalization
+	for _, f := range ix.Files() {
+		if f.Loudness == nil || f.Loudness.Integrated <= Silence {
+			continue
+		}
+
+		n := Normalization{File: f, Family: Family(f.Path)}
+		n.FamilyLoudness = medians[n.Family]
+		n.Gain = target - f.Loudness.Integrated
+		n.Outlier = math.Abs(f.Loudness.Integrated-n.FamilyLoudness) > OutlierLU
+		if ceiling := PeakCeiling - f.Loudness.Peak; n.Gain > ceiling {
+			n.Gain = ceiling
+			n.Limited = true
+		}
+		list = append(list, n)
+	}
+	return list
+}
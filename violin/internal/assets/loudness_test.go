@@ -0,0 +1,51 @@
+package assets
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	ix := Index{files: make(map[string]File)}
+	for path, l := range map[string]Loudness{
+		"mp3/scale/major/a1.mp3": {Integrated: -20, Peak: -20},
+		"mp3/scale/major/b1.mp3": {Integrated: -19, Peak: -20},
+		"mp3/scale/major/c1.mp3": {Integrated: -22.5, Peak: -20},
+		"mp3/scale/major/d1.mp3": {Integrated: -28, Peak: -20},
+		"mp3/drone/a1.mp3":       {Integrated: -30, Peak: -10},
+		"mp3/drone/silent.mp3":   {Integrated: Silence, Peak: -90},
+	} {
+		ix.files[path] = File{Path: path, Loudness: &Loudness{Integrated: l.Integrated, Peak: l.Peak}}
+	}
+	ix.files["mp3/scale/major/e1.mp3"] = File{Path: "mp3/scale/major/e1.mp3"}
+
+	want := map[string]struct {
+		gain             float64
+		outlier, limited bool
+	}{
+		// Each recording is brought to the target from its own loudness,
+		// even within 3 LU of its family.
+		"mp3/scale/major/a1.mp3": {gain: 4},
+		"mp3/scale/major/b1.mp3": {gain: 3},
+		"mp3/scale/major/c1.mp3": {gain: 6.5},
+		// Far from the family median of -21.25 LUFS.
+		"mp3/scale/major/d1.mp3": {gain: 12, outlier: true},
+		// Raising the drone 14dB would take its peak above -1dBFS.
+		"mp3/drone/a1.mp3": {gain: 9, limited: true},
+	}
+
+	list := ix.Normalize(-16)
+	if len(list) != len(want) {
+		t.Errorf("normalized %d recordings, want %d without the silent and unmeasured ones", len(list), len(want))
+	}
+	for _, n := range list {
+		w, ok := want[n.Path]
+		if !ok {
+			t.Errorf("normalized %s", n.Path)
+			continue
+		}
+		if math.Abs(n.Gain-w.gain) > 1e-9 || n.Outlier != w.outlier || n.Limited != w.limited {
+			t.Errorf("%s: gain %+.2f dB outlier %v limited %v, want %+.2f dB outlier %v limited %v", n.Path, n.Gain, n.Outlier, n.Limited, w.gain, w.outlier, w.limited)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Package audio decodes, mixes, time stretches and encodes the practice
+// recordings.
+package audio
+
+import (
+	"math"
+	"time"
+)
+
+// Buffer holds decoded audio as interleaved samples in the range [-1, 1].
+type Buffer struct {
+	SampleRate int
+	Channels   int
+	Samples    []float32
+}
+
+// NewBuffer allocates a silent buffer holding the given number of frames.
+func NewBuffer(sampleRate, channels, frames int) *Buffer {
+	return &Buffer{
+		SampleRate: sampleRate,
+		Channels:   channels,
+		Samples:    make([]float32, frames*channels),
+	}
+}
+
+// Frames returns the number of sample frames, one sample per channel, in
+// the buffer.
+func (b *Buffer) Frames() int {
+	if b.Channels == 0 {
+		return 0
+	}
+	return len(b.Samples) / b.Channels
+}
+
+// Duration returns the playing time of the buffer.
+func (b *Buffer) Duration() time.Duration {
+	if b.SampleRate == 0 {
+		return 0
+	}
+	return time.Duration(b.Frames()) * time.Second / time.Duration(b.SampleRate)
+}
+
+// Peak returns the largest absolute sample value in the buffer.
+func (b *Buffer) Peak() float32 {
+	var peak float32
+	for _, s := range b.Samples {
+		if s < 0 {
+			s = -s
+		}
+		if s > peak {
+			peak = s
+		}
+	}
+	return peak
+}
+
+// Amplify changes the level of the buffer by db decibels.
+func (b *Buffer) Amplify(db float64) {
+	g := float32(math.Pow(10, db/20))
+	for i := range b.Samples {
+		b.Samples[i] *= g
+	}
+}
@@ -0,0 +1,252 @@
+package audio
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Cache keeps converted audio on disk. Entries are keyed by the content
+// hash of their source files and the parameters of the conversion, so an
+// entry is rebuilt when a recording is replaced. Once the entries take more
+// than the cache's size the least recently used are removed, which also
+// clears out stale ones nobody asks for again.
+type Cache struct {
+	dir     string
+	maxSize int64
+
+	mu       sync.Mutex
+	hashes   map[string]sourceHash
+	building map[string]*sync.WaitGroup
+
+	// evicting serializes evictions, so two builds finishing together do
+	// not both remove entries.
+	evicting sync.Mutex
+}
+
+// sourceHash remembers the hash of a file along with the size and
+// modification time it had when it was hashed.
+type sourceHash struct {
+	size    int64
+	modTime time.Time
+	sum     string
+}
+
+// NewCache constructs a Cache storing up to maxSize bytes of entries in
+// dir, creating it if needed. A maxSize of 0 does not limit it.
+func NewCache(dir string, maxSize int64) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, errors.Wrap(err, "creating audio cache directory")
+	}
+	c := Cache{
+		dir:      dir,
+		maxSize:  maxSize,
+		hashes:   make(map[string]sourceHash),
+		building: make(map[string]*sync.WaitGroup),
+	}
+	if err := c.evict(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// SourceHash returns the SHA-256 of the file at path. The file is only read
+// again when its size or modification time changes.
+func (c *Cache) SourceHash(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", errors.Wrap(err, "reading source")
+	}
+
+	c.mu.Lock()
+	h, ok := c.hashes[path]
+	c.mu.Unlock()
+	if ok && h.size == info.Size() && h.modTime.Equal(info.ModTime()) {
+		return h.sum, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", errors.Wrap(err, "opening source")
+	}
+	defer f.Close()
+
+	sum := sha256.New()
+	if _, err := io.Copy(sum, f); err != nil {
+		return "", errors.Wrap(err, "hashing source")
+	}
+	h = sourceHash{size: info.Size(), modTime: info.ModTime(), sum: hex.EncodeToString(sum.Sum(nil))}
+
+	c.mu.Lock()
+	c.hashes[path] = h
+	c.mu.Unlock()
+	return h.sum, nil
+}
+
+// Key returns the cache key of a conversion of the source files with the
+// given parameters.
+func (c *Cache) Key(sources []string, params string) (string, error) {
+	key := sha256.New()
+	for _, path := range sources {
+		sum, err := c.SourceHash(path)
+		if err != nil {
+			return "", errors.Wrapf(err, "hashing %s", path)
+		}
+		io.WriteString(key, sum+"\n")
+	}
+	io.WriteString(key, params)
+	return hex.EncodeToString(key.Sum(nil)), nil
+}
+
+// Lookup returns the entry for key if it is cached, without building it.
+func (c *Cache) Lookup(key, ext string) (*os.File, bool) {
+	f, err := c.open(filepath.Join(c.dir, key+ext))
+	if err != nil {
+		return nil, false
+	}
+	return f, true
+}
+
+// Open returns the entry for key, calling build to write it when it is not
+// cached yet. The entry is written to a temporary file that is renamed into
+// place once build succeeds, so a failed or interrupted build leaves nothing
+// behind. Concurrent calls for the same key wait for a single build. Each
+// build evicts the least recently used entries once the cache is full.
+func (c *Cache) Open(key, ext string, build func(f *os.File) error) (*os.File, error) {
+	path := filepath.Join(c.dir, key+ext)
+
+	for {
+		if f, err := c.open(path); err == nil {
+			return f, nil
+		}
+
+		c.mu.Lock()
+		wg, busy := c.building[key]
+		if !busy {
+			wg = new(sync.WaitGroup)
+			wg.Add(1)
+			c.building[key] = wg
+		}
+		c.mu.Unlock()
+
+		if busy {
+			// Someone else is building it, wait and look again.
+			wg.Wait()
+			if _, err := os.Stat(path); err != nil {
+				return nil, errors.New("building cache entry failed")
+			}
+			continue
+		}
+
+		err := c.build(path, build)
+
+		c.mu.Lock()
+		delete(c.building, key)
+		c.mu.Unlock()
+		wg.Done()
+
+		if err != nil {
+			return nil, err
+		}
+
+		// Open the entry before evicting, so it is there to return even
+		// when it is bigger than the whole cache.
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, errors.Wrap(err, "opening cache entry")
+		}
+		if err := c.evict(); err != nil {
+			f.Close()
+			return nil, err
+		}
+		return f, nil
+	}
+}
+
+// open opens a cache entry and marks it as used now.
+func (c *Cache) open(path string) (*os.File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return f, nil
+}
+
+// evict removes the least recently used entries while the cache holds more
+// than its size, down to nine tenths of it so that it is not run again on
+// the next build. Entries are used when they are opened, which updates
+// their modification time.
+func (c *Cache) evict() error {
+	if c.maxSize <= 0 {
+		return nil
+	}
+
+	c.evicting.Lock()
+	defer c.evicting.Unlock()
+
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return errors.Wrap(err, "listing cache entries")
+	}
+	var infos []os.FileInfo
+	var total int64
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".tmp") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		infos = append(infos, info)
+		total += info.Size()
+	}
+	if total <= c.maxSize {
+		return nil
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ModTime().Before(infos[j].ModTime())
+	})
+	for _, info := range infos {
+		if total <= c.maxSize/10*9 {
+			break
+		}
+		if err := os.Remove(filepath.Join(c.dir, info.Name())); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "evicting cache entry")
+		}
+		total -= info.Size()
+	}
+	return nil
+}
+
+// build writes a cache entry through a temporary file.
+func (c *Cache) build(path string, build func(f *os.File) error) error {
+	tmp, err := os.CreateTemp(c.dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "creating cache entry")
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := build(tmp); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "building cache entry")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "writing cache entry")
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return errors.Wrap(err, "storing cache entry")
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package audio
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	dir := t.TempDir()
+	c, err := NewCache(dir, 3500)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var builds int
+	entry := func(key string) {
+		t.Helper()
+		f, err := c.Open(key, ".bin", func(f *os.File) error {
+			builds++
+			_, err := f.Write(make([]byte, 1000))
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+	}
+	// Entries used in the same instant would tie, so age them apart.
+	age := func(key string, d time.Duration) {
+		t.Helper()
+		at := time.Now().Add(-d)
+		if err := os.Chtimes(filepath.Join(dir, key+".bin"), at, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entry("a")
+	age("a", 3*time.Minute)
+	entry("b")
+	age("b", 2*time.Minute)
+	entry("c")
+	age("c", time.Minute)
+
+	// Using a makes b the least recently used, which goes when d fills the
+	// cache past its size, leaving it below nine tenths of it.
+	entry("a")
+	entry("d")
+	if builds != 4 {
+		t.Errorf("built %d entries, want 4", builds)
+	}
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true, "d": true} {
+		_, ok := c.Lookup(key, ".bin")
+		if ok != want {
+			t.Errorf("entry %s cached %v, want %v", key, ok, want)
+		}
+	}
+}
+
+func TestCacheUnlimited(t *testing.T) {
+	c, err := NewCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a", "b", "c"} {
+		f, err := c.Open(key, ".bin", func(f *os.File) error {
+			_, err := f.Write(make([]byte, 1<<16))
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+	}
+	for _, key := range []string{"a", "b", "c"} {
+		if _, ok := c.Lookup(key, ".bin"); !ok {
+			t.Errorf("entry %s evicted from an unlimited cache", key)
+		}
+	}
+}
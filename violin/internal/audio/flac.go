@@ -0,0 +1,494 @@
+package audio
+
+import (
+	"bufio"
+	"crypto/md5"
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// flacBlockSize is the number of samples per channel in each FLAC frame.
+const flacBlockSize = 4096
+
+// flacMaxPartitionOrder limits how finely the residual of a block is split
+// into partitions with their own Rice parameter.
+const flacMaxPartitionOrder = 6
+
+// FLAC stereo channel assignments.
+const (
+	flacIndependent = 0
+	flacLeftSide    = 8
+	flacSideRight   = 9
+	flacMidSide     = 10
+)
+
+// WriteFLAC encodes a stream as a 16 bit FLAC file. Each channel of a block
+// is coded with the fixed linear predictor that leaves the smallest Rice
+// coded residual, and stereo blocks use whichever of left/right, left/side,
+// side/right or mid/side is smallest. The length and MD5 signature in the
+// header are filled in when w can seek.
+func WriteFLAC(w io.Writer, s Stream) error {
+	f := s.Format()
+	if f.Channels < 1 || f.Channels > 8 {
+		return errors.Errorf("flac supports 1 to 8 channels, not %d", f.Channels)
+	}
+	if f.SampleRate <= 0 || f.SampleRate >= 1<<20 {
+		return errors.Errorf("flac does not support a sample rate of %d", f.SampleRate)
+	}
+
+	ws, seekable := w.(io.WriteSeeker)
+	var start int64
+	if seekable {
+		var err error
+		if start, err = ws.Seek(0, io.SeekCurrent); err != nil {
+			seekable = false
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	info := flacStreamInfo{format: f}
+	if _, err := bw.WriteString("fLaC"); err != nil {
+		return errors.Wrap(err, "writing flac header")
+	}
+	if _, err := bw.Write(info.block()); err != nil {
+		return errors.Wrap(err, "writing flac header")
+	}
+
+	sum := md5.New()
+	raw := make([]byte, flacBlockSize*f.Channels*2)
+	channels := make([][]int64, f.Channels)
+	for frame := uint64(0); ; frame++ {
+		n, err := io.ReadFull(s, raw)
+		if err == io.EOF {
+			break
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return errors.Wrap(err, "reading samples to encode")
+		}
+
+		samples := n / 2 / f.Channels
+		if samples == 0 {
+			break
+		}
+		sum.Write(raw[:samples*f.Channels*2])
+		for c := range channels {
+			channels[c] = channels[c][:0]
+			for i := 0; i < samples; i++ {
+				v := int16(binary.LittleEndian.Uint16(raw[(i*f.Channels+c)*2:]))
+				channels[c] = append(channels[c], int64(v))
+			}
+		}
+
+		data := encodeFLACFrame(frame, channels)
+		if _, err := bw.Write(data); err != nil {
+			return errors.Wrap(err, "writing flac frame")
+		}
+		info.add(samples, len(data))
+
+		if err == io.ErrUnexpectedEOF {
+			break
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return errors.Wrap(err, "writing flac frame")
+	}
+
+	if !seekable {
+		return nil
+	}
+
+	// Go back and record the length and signature now they are known.
+	copy(info.md5[:], sum.Sum(nil))
+	if _, err := ws.Seek(start+4, io.SeekStart); err != nil {
+		return errors.Wrap(err, "rewinding to flac header")
+	}
+	if _, err := ws.Write(info.block()); err != nil {
+		return errors.Wrap(err, "writing flac header")
+	}
+	if _, err := ws.Seek(0, io.SeekEnd); err != nil {
+		return errors.Wrap(err, "seeking to end of flac")
+	}
+	return nil
+}
+
+// flacStreamInfo collects the fields of the STREAMINFO metadata block.
+type flacStreamInfo struct {
+	format       Format
+	samples      uint64
+	minFrameSize int
+	maxFrameSize int
+	md5          [16]byte
+}
+
+// add records an encoded frame.
+func (si *flacStreamInfo) add(samples, size int) {
+	si.samples += uint64(samples)
+	if si.minFrameSize == 0 || size < si.minFrameSize {
+		si.minFrameSize = size
+	}
+	if size > si.maxFrameSize {
+		si.maxFrameSize = size
+	}
+}
+
+// block returns the STREAMINFO metadata block, marked as the last block.
+func (si *flacStreamInfo) block() []byte {
+	var b bitWriter
+	b.write(1, 1) // last metadata block
+	b.write(0, 7) // STREAMINFO
+	b.write(34, 24)
+	b.write(flacBlockSize, 16)
+	b.write(flacBlockSize, 16)
+	b.write(uint64(si.minFrameSize), 24)
+	b.write(uint64(si.maxFrameSize), 24)
+	b.write(uint64(si.format.SampleRate), 20)
+	b.write(uint64(si.format.Channels-1), 3)
+	b.write(16-1, 5)
+	b.write(si.samples, 36)
+	for _, v := range si.md5 {
+		b.write(uint64(v), 8)
+	}
+	return b.buf
+}
+
+// encodeFLACFrame encodes one block of samples, one slice per channel.
+func encodeFLACFrame(number uint64, channels [][]int64) []byte {
+	samples := len(channels[0])
+
+	// Pick the channel assignment with the smallest subframes.
+	assignment := len(channels) - 1
+	plans := make([]flacSubframe, len(channels))
+	for c, x := range channels {
+		plans[c] = planFLACSubframe(x, 16)
+	}
+	if len(channels) == 2 {
+		left, right := channels[0], channels[1]
+		side := make([]int64, samples)
+		mid := make([]int64, samples)
+		for i := range side {
+			side[i] = left[i] - right[i]
+			mid[i] = (left[i] + right[i]) >> 1
+		}
+		sidePlan := planFLACSubframe(side, 17)
+		midPlan := planFLACSubframe(mid, 16)
+
+		best := plans[0].bits + plans[1].bits
+		if bits := plans[0].bits + sidePlan.bits; bits < best {
+			best, assignment = bits, flacLeftSide
+		}
+		if bits := sidePlan.bits + plans[1].bits; bits < best {
+			best, assignment = bits, flacSideRight
+		}
+		if bits := midPlan.bits + sidePlan.bits; bits < best {
+			assignment = flacMidSide
+		}
+
+		switch assignment {
+		case flacLeftSide:
+			plans[1] = sidePlan
+		case flacSideRight:
+			plans[0] = sidePlan
+		case flacMidSide:
+			plans[0], plans[1] = midPlan, sidePlan
+		}
+	}
+
+	var b bitWriter
+	b.write(0x3ffe, 14) // sync code
+	b.write(0, 1)       // reserved
+	b.write(0, 1)       // fixed block size
+	if samples == flacBlockSize {
+		b.write(12, 4) // 256 * 2^(12-8) = 4096 samples
+	} else {
+		b.write(7, 4) // 16 bit block size at the end of the header
+	}
+	b.write(0, 4) // sample rate from STREAMINFO
+	b.write(uint64(assignment), 4)
+	b.write(4, 3) // 16 bits per sample
+	b.write(0, 1) // reserved
+	b.writeUTF8(number)
+	if samples != flacBlockSize {
+		b.write(uint64(samples-1), 16)
+	}
+	b.write(uint64(crc8(b.buf)), 8)
+
+	for _, p := range plans {
+		p.write(&b)
+	}
+	b.align()
+	crc := crc16(b.buf)
+	b.write(uint64(crc), 16)
+	return b.buf
+}
+
+// FLAC subframe types.
+const (
+	flacConstant = iota
+	flacVerbatim
+	flacFixed
+)
+
+// flacSubframe is the chosen coding of one channel of a block.
+type flacSubframe struct {
+	kind       int
+	order      int
+	bps        uint
+	samples    []int64
+	residual   []int64
+	partitions int // partition order
+	params     []uint
+	bits       int
+}
+
+// planFLACSubframe chooses how to code the samples of one channel.
+func planFLACSubframe(x []int64, bps uint) flacSubframe {
+	constant := true
+	for _, v := range x[1:] {
+		if v != x[0] {
+			constant = false
+			break
+		}
+	}
+	if constant {
+		return flacSubframe{kind: flacConstant, bps: bps, samples: x, bits: 8 + int(bps)}
+	}
+
+	best := flacSubframe{kind: flacVerbatim, bps: bps, samples: x, bits: 8 + int(bps)*len(x)}
+	for order := 0; order <= 4 && order < len(x); order++ {
+		residual := fixedResidual(x, order)
+		partitions, params, bits := riceParameters(residual, len(x), order)
+		bits += 8 + order*int(bps) + 6
+		if bits < best.bits {
+			best = flacSubframe{
+				kind:       flacFixed,
+				order:      order,
+				bps:        bps,
+				samples:    x,
+				residual:   residual,
+				partitions: partitions,
+				params:     params,
+				bits:       bits,
+			}
+		}
+	}
+	return best
+}
+
+// write writes the subframe.
+func (p flacSubframe) write(b *bitWriter) {
+	b.write(0, 1) // padding
+	switch p.kind {
+	case flacConstant:
+		b.write(0, 6)
+		b.write(0, 1) // no wasted bits
+		b.writeSigned(p.samples[0], p.bps)
+
+	case flacVerbatim:
+		b.write(1, 6)
+		b.write(0, 1)
+		for _, v := range p.samples {
+			b.writeSigned(v, p.bps)
+		}
+
+	case flacFixed:
+		b.write(uint64(8|p.order), 6)
+		b.write(0, 1)
+		for _, v := range p.samples[:p.order] {
+			b.writeSigned(v, p.bps)
+		}
+
+		b.write(0, 2) // Rice coding with 4 bit parameters
+		b.write(uint64(p.partitions), 4)
+		size := len(p.samples) >> p.partitions
+		i := 0
+		for part, k := range p.params {
+			n := size
+			if part == 0 {
+				n -= p.order
+			}
+			b.write(uint64(k), 4)
+			for _, v := range p.residual[i : i+n] {
+				u := zigzag(v)
+				b.writeUnary(u >> k)
+				b.write(u&(1<<k-1), k)
+			}
+			i += n
+		}
+	}
+}
+
+// fixedResidual returns the error of the fixed polynomial predictor of the
+// given order for every sample after the first order samples.
+func fixedResidual(x []int64, order int) []int64 {
+	r := make([]int64, 0, len(x)-order)
+	for i := order; i < len(x); i++ {
+		var e int64
+		switch order {
+		case 0:
+			e = x[i]
+		case 1:
+			e = x[i] - x[i-1]
+		case 2:
+			e = x[i] - 2*x[i-1] + x[i-2]
+		case 3:
+			e = x[i] - 3*x[i-1] + 3*x[i-2] - x[i-3]
+		case 4:
+			e = x[i] - 4*x[i-1] + 6*x[i-2] - 4*x[i-3] + x[i-4]
+		}
+		r = append(r, e)
+	}
+	return r
+}
+
+// riceParameters chooses the partition order and the Rice parameter of each
+// partition for a residual, returning them with the estimated size in bits.
+func riceParameters(residual []int64, blockSize, order int) (int, []uint, int) {
+	bestOrder, bestBits := 0, math.MaxInt64
+	var bestParams []uint
+
+	for p := 0; p <= flacMaxPartitionOrder; p++ {
+		parts := 1 << p
+		if blockSize%parts != 0 || blockSize>>p <= order {
+			break
+		}
+
+		size := blockSize >> p
+		params := make([]uint, parts)
+		bits := 0
+		i := 0
+		for part := range params {
+			n := size
+			if part == 0 {
+				n -= order
+			}
+			var sum uint64
+			for _, v := range residual[i : i+n] {
+				sum += zigzag(v)
+			}
+			i += n
+
+			k, cost := riceParameter(sum, n)
+			params[part] = k
+			bits += 4 + cost
+		}
+
+		if bits < bestBits {
+			bestOrder, bestBits, bestParams = p, bits, params
+		}
+	}
+	return bestOrder, bestParams, bestBits
+}
+
+// riceParameter picks the Rice parameter for n values adding up to sum and
+// estimates their coded size.
+func riceParameter(sum uint64, n int) (uint, int) {
+	best, bestBits := uint(0), math.MaxInt64
+	for k := uint(0); k <= 14; k++ {
+		bits := n*int(k+1) + int(sum>>k)
+		if bits < bestBits {
+			best, bestBits = k, bits
+		}
+	}
+	return best, bestBits
+}
+
+// zigzag maps signed values to unsigned ones, 0, -1, 1, -2 to 0, 1, 2, 3.
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+// bitWriter packs values into bytes, most significant bit first.
+type bitWriter struct {
+	buf  []byte
+	acc  uint64
+	bits uint
+}
+
+// write writes the low n bits of v, n at most 56.
+func (b *bitWriter) write(v uint64, n uint) {
+	if n == 0 {
+		return
+	}
+	b.acc = b.acc<<n | v&(1<<n-1)
+	b.bits += n
+	for b.bits >= 8 {
+		b.bits -= 8
+		b.buf = append(b.buf, byte(b.acc>>b.bits))
+	}
+}
+
+// writeSigned writes v as an n bit two's complement number.
+func (b *bitWriter) writeSigned(v int64, n uint) {
+	b.write(uint64(v), n)
+}
+
+// writeUnary writes q zero bits followed by a one bit.
+func (b *bitWriter) writeUnary(q uint64) {
+	for ; q >= 32; q -= 32 {
+		b.write(0, 32)
+	}
+	b.write(1, uint(q)+1)
+}
+
+// writeUTF8 writes v in the UTF-8 like coding FLAC uses for frame numbers.
+// An n byte code holds 5n+1 bits: the first byte starts with n one bits and
+// a zero bit, each following byte with the bits 10.
+func (b *bitWriter) writeUTF8(v uint64) {
+	if v < 0x80 {
+		b.write(v, 8)
+		return
+	}
+
+	n := uint(2)
+	for n < 7 && v >= 1<<(5*n+1) {
+		n++
+	}
+	b.write(uint64(0xff<<(8-n))&0xff|v>>(6*(n-1)), 8)
+	for i := int(n) - 2; i >= 0; i-- {
+		b.write(0x80|v>>(6*uint(i))&0x3f, 8)
+	}
+}
+
+// align pads the output with zero bits to a whole byte.
+func (b *bitWriter) align() {
+	if b.bits > 0 {
+		b.write(0, 8-b.bits)
+	}
+}
+
+// crc8 returns the CRC-8 of data with polynomial x^8 + x^2 + x + 1, used
+// for FLAC frame headers.
+func crc8(data []byte) uint8 {
+	var crc uint8
+	for _, d := range data {
+		crc ^= d
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x07
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// crc16 returns the CRC-16 of data with polynomial x^16 + x^15 + x^2 + 1,
+// used for whole FLAC frames.
+func crc16(data []byte) uint16 {
+	var crc uint16
+	for _, d := range data {
+		crc ^= uint16(d) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x8005
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,82 @@
+package audio
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mewkiz/flac"
+)
+
+func TestFLACDecodes(t *testing.T) {
+	tests := []struct {
+		name                     string
+		sampleRate, channels, fr int
+		seekable                 bool
+	}{
+		{"mono", 22050, 1, 3000, false},
+		{"stereo", 44100, 2, 3*flacBlockSize + 17, false},
+		{"stereo to a file", 48000, 2, flacBlockSize, true},
+	}
+	for _, tt := range tests {
+		in := testSignal(tt.sampleRate, tt.channels, tt.fr)
+
+		var data []byte
+		if tt.seekable {
+			path := filepath.Join(t.TempDir(), "test.flac")
+			f, err := os.Create(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := WriteFLAC(f, in.Stream()); err != nil {
+				t.Fatalf("%s: encoding: %v", tt.name, err)
+			}
+			f.Close()
+			if data, err = os.ReadFile(path); err != nil {
+				t.Fatal(err)
+			}
+		} else {
+			var b bytes.Buffer
+			if err := WriteFLAC(&b, in.Stream()); err != nil {
+				t.Fatalf("%s: encoding: %v", tt.name, err)
+			}
+			data = b.Bytes()
+		}
+
+		stream, err := flac.New(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s: parsing: %v", tt.name, err)
+		}
+		if int(stream.Info.SampleRate) != tt.sampleRate || int(stream.Info.NChannels) != tt.channels {
+			t.Errorf("%s: header says %d Hz with %d channels, want %d Hz with %d", tt.name, stream.Info.SampleRate, stream.Info.NChannels, tt.sampleRate, tt.channels)
+		}
+		if tt.seekable && int(stream.Info.NSamples) != tt.fr {
+			t.Errorf("%s: header says %d frames, want %d", tt.name, stream.Info.NSamples, tt.fr)
+		}
+
+		var frames int
+		for {
+			f, err := stream.ParseNext()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s: decoding frame after %d samples: %v", tt.name, frames, err)
+			}
+			for i := 0; i < int(f.BlockSize); i++ {
+				for c, sub := range f.Subframes {
+					want := toInt16(in.Samples[(frames+i)*tt.channels+c])
+					if got := int16(sub.Samples[i]); got != want {
+						t.Fatalf("%s: channel %d sample %d is %d, want %d", tt.name, c, frames+i, got, want)
+					}
+				}
+			}
+			frames += int(f.BlockSize)
+		}
+		if frames != tt.fr {
+			t.Errorf("%s: decoded %d frames, want %d", tt.name, frames, tt.fr)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package audio
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Loop cuts a seamless loop from the sustained middle of a recording such
+// as a held drone note. The attack and release are left out, the end of the
+// loop is nudged so its waveform lines up with the start, and the last
+// crossfade of the loop blends into the audio just before the loop start,
+// so playing it over and over has no click at the join.
+func Loop(b *Buffer, crossfade time.Duration) (*Buffer, error) {
+	ch := b.Channels
+	mono := make([]float32, b.Frames())
+	for f := range mono {
+		for c := 0; c < ch; c++ {
+			mono[f] += b.Samples[f*ch+c]
+		}
+	}
+
+	// Find the sustain: the 50ms windows within 6dB of the loudest one,
+	// less 100ms either side to stay clear of the attack and release.
+	window := b.SampleRate / 20
+	var levels []float64
+	for start := 0; start+window <= len(mono); start += window {
+		var sum float64
+		for _, v := range mono[start : start+window] {
+			sum += float64(v) * float64(v)
+		}
+		levels = append(levels, sum/float64(window))
+	}
+	var loudest float64
+	for _, l := range levels {
+		if l > loudest {
+			loudest = l
+		}
+	}
+	if loudest == 0 {
+		return nil, errors.New("recording is silent")
+	}
+	first, last := -1, -1
+	for i, l := range levels {
+		if l >= loudest/4 {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	margin := b.SampleRate / 10
+	start, end := first*window+margin, (last+1)*window-margin
+
+	fade := int(crossfade.Seconds() * float64(b.SampleRate))
+	if limit := (end - start) / 4; fade > limit {
+		fade = limit
+	}
+	if fade < b.SampleRate/50 {
+		return nil, errors.New("recording has too little sustain to loop")
+	}
+
+	// The loop plays from a and its last fade frames blend into the fade
+	// frames before a, which lead straight back into a.
+	a := start + fade
+	seek := b.SampleRate / 50
+	p := align(mono, a-fade, end-fade-seek, seek, fade)
+	length := p - a + fade
+
+	loop := NewBuffer(b.SampleRate, ch, length)
+	copy(loop.Samples, b.Samples[a*ch:(a+length)*ch])
+	for j := 0; j < fade; j++ {
+		in := float32(0.5 - 0.5*math.Cos(math.Pi*float64(j)/float64(fade)))
+		dst := (length - fade + j) * ch
+		src := (a - fade + j) * ch
+		for c := 0; c < ch; c++ {
+			loop.Samples[dst+c] = loop.Samples[dst+c]*(1-in) + b.Samples[src+c]*in
+		}
+	}
+	return loop, nil
+}
+
+// Transpose returns the buffer shifted by the given number of semitones,
+// made shorter or longer by the same ratio as a tape played faster or
+// slower. It suits steady tones that are looped afterwards.
+func Transpose(b *Buffer, semitones float64) (*Buffer, error) {
+	if semitones == 0 {
+		return b, nil
+	}
+	ratio := math.Pow(2, semitones/12)
+	s := NewStream(b.Stream(), Format{
+		SampleRate: int(math.Round(float64(b.SampleRate) * ratio)),
+		Channels:   b.Channels,
+	})
+	return ReadBuffer(Resample(s, b.SampleRate))
+}
+
+// Repeat returns a stream of the loops played together, each one repeating
+// for as long as it takes to fill the given duration. The sum is scaled so
+// it cannot clip, and it fades in and out over fade.
+func Repeat(loops []*Buffer, duration, fade time.Duration) (SeekStream, error) {
+	if len(loops) == 0 {
+		return nil, errors.New("nothing to repeat")
+	}
+	f := Format{SampleRate: loops[0].SampleRate, Channels: loops[0].Channels}
+	var peaks float32
+	for i, l := range loops {
+		if l.SampleRate != f.SampleRate || l.Channels != f.Channels || l.Frames() == 0 {
+			return nil, errors.Errorf("loop %d does not match the format of the first", i+1)
+		}
+		peaks += l.Peak()
+	}
+
+	r := repeater{
+		loops:  loops,
+		format: f,
+		frames: int(duration.Seconds() * float64(f.SampleRate)),
+		fade:   int(fade.Seconds() * float64(f.SampleRate)),
+		scale:  1,
+		frame:  make([]byte, 2*f.Channels),
+	}
+	if peaks > 1 {
+		r.scale = 1 / peaks
+	}
+	if r.fade > r.frames/2 {
+		r.fade = r.frames / 2
+	}
+	return &r, nil
+}
+
+// repeater is the Stream returned by Repeat.
+type repeater struct {
+	loops  []*Buffer
+	format Format
+	frames int
+	fade   int
+	scale  float32
+	off    int64
+	frame  []byte
+}
+
+// Format returns the format of the loops.
+func (r *repeater) Format() Format {
+	return r.format
+}
+
+// Length returns the number of bytes of samples in the stream.
+func (r *repeater) Length() int64 {
+	return int64(r.frames) * int64(r.format.Channels) * 2
+}
+
+// Read implements io.Reader.
+func (r *repeater) Read(p []byte) (int, error) {
+	size := r.Length()
+	if r.off >= size {
+		return 0, io.EOF
+	}
+
+	frameSize := int64(len(r.frame))
+	n := 0
+	for n < len(p) && r.off < size {
+		r.render(int(r.off / frameSize))
+		c := copy(p[n:], r.frame[r.off%frameSize:])
+		n += c
+		r.off += int64(c)
+	}
+	return n, nil
+}
+
+// Seek implements io.Seeker. Every sample is worked out from its position,
+// so seeking costs nothing.
+func (r *repeater) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.off
+	case io.SeekEnd:
+		offset += r.Length()
+	default:
+		return 0, errors.Errorf("invalid whence %d", whence)
+	}
+	if offset < 0 {
+		return 0, errors.New("seeking before the start")
+	}
+	r.off = offset
+	return offset, nil
+}
+
+// render fills r.frame with the samples of frame pos.
+func (r *repeater) render(pos int) {
+	gain := r.scale
+	if pos < r.fade {
+		gain *= float32(pos) / float32(r.fade)
+	}
+	if left := r.frames - pos; left < r.fade {
+		gain *= float32(left) / float32(r.fade)
+	}
+
+	ch := r.format.Channels
+	for c := 0; c < ch; c++ {
+		var v float32
+		for _, l := range r.loops {
+			v += l.Samples[(pos%l.Frames())*ch+c]
+		}
+		binary.LittleEndian.PutUint16(r.frame[2*c:], uint16(toInt16(v*gain)))
+	}
+}
@@ -0,0 +1,226 @@
+package audio
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// Loudness gating thresholds from ITU-R BS.1770-4, as used by EBU R128.
+const (
+	absoluteGate = -70 // LUFS
+	relativeGate = -10 // LU below the absolute gated loudness
+)
+
+// Loudness is an EBU R128 measurement of a recording.
+type Loudness struct {
+	Integrated float64 // integrated loudness in LUFS, -Inf for silence
+	Peak       float64 // sample peak in dBFS
+}
+
+// MeasureLoudness reads a stream to the end and measures its integrated
+// loudness following ITU-R BS.1770-4: the channels are K-weighted, their
+// mean square is taken over 400ms blocks overlapping by 75%, and blocks
+// quieter than the absolute and relative gates are left out.
+func MeasureLoudness(s Stream) (Loudness, error) {
+	f := s.Format()
+	if f.Channels < 1 || f.SampleRate <= 0 {
+		return Loudness{}, errors.New("stream has no channels or sample rate")
+	}
+
+	filters := make([]kWeighting, f.Channels)
+	for c := range filters {
+		filters[c] = newKWeighting(f.SampleRate)
+	}
+
+	// Blocks are built from four 100ms steps.
+	step := f.SampleRate / 10
+	var steps [4]float64
+	var blocks []float64
+	var stepSum float64
+	var peak float64
+	frame, inStep := 0, 0
+
+	chunk := make([]byte, 16*1024*f.Channels)
+	var carry []byte
+	for {
+		n, err := s.Read(chunk[len(carry):])
+		data := chunk[:len(carry)+n]
+		whole := len(data) / (2 * f.Channels) * 2 * f.Channels
+
+		for i := 0; i < whole; i += 2 * f.Channels {
+			for c := range filters {
+				v := float64(int16(binary.LittleEndian.Uint16(data[i+2*c:]))) / 32768
+				if a := math.Abs(v); a > peak {
+					peak = a
+				}
+				y := filters[c].process(v)
+				stepSum += y * y
+			}
+
+			inStep++
+			if inStep == step {
+				steps[frame%4] = stepSum
+				frame++
+				if frame >= 4 {
+					blocks = append(blocks, (steps[0]+steps[1]+steps[2]+steps[3])/float64(4*step))
+				}
+				stepSum, inStep = 0, 0
+			}
+		}
+		carry = append(chunk[:0], data[whole:]...)
+
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Loudness{}, errors.Wrap(err, "reading samples to measure")
+		}
+	}
+
+	return Loudness{
+		Integrated: gatedLoudness(blocks),
+		Peak:       20 * math.Log10(peak),
+	}, nil
+}
+
+// gatedLoudness returns the integrated loudness of the mean square block
+// energies, summed over channels, after gating.
+func gatedLoudness(blocks []float64) float64 {
+	loudness := func(energy float64) float64 {
+		return -0.691 + 10*math.Log10(energy)
+	}
+
+	mean := func(gate float64) float64 {
+		var sum float64
+		n := 0
+		for _, z := range blocks {
+			if loudness(z) > gate {
+				sum += z
+				n++
+			}
+		}
+		if n == 0 {
+			return 0
+		}
+		return sum / float64(n)
+	}
+
+	absolute := mean(absoluteGate)
+	if absolute == 0 {
+		return math.Inf(-1)
+	}
+	relative := mean(loudness(absolute) + relativeGate)
+	if relative == 0 {
+		return math.Inf(-1)
+	}
+	return loudness(relative)
+}
+
+// kWeighting is the two stage K-weighting filter of BS.1770: a high shelf
+// modelling the head followed by a high pass, as a pair of biquads.
+type kWeighting struct {
+	shelf, highPass biquad
+}
+
+// newKWeighting designs the K-weighting filter for a sample rate. The
+// analogue prototypes are those the BS.1770 48kHz coefficients come from,
+// so other rates get the same response.
+func newKWeighting(rate int) kWeighting {
+	var k kWeighting
+
+	// High shelf, +4dB above about 1.7kHz.
+	f0, gain, q := 1681.974450955533, 3.999843853973347, 0.7071752369554196
+	K := math.Tan(math.Pi * f0 / float64(rate))
+	vh := math.Pow(10, gain/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + K/q + K*K
+	k.shelf = biquad{
+		b0: (vh + vb*K/q + K*K) / a0,
+		b1: 2 * (K*K - vh) / a0,
+		b2: (vh - vb*K/q + K*K) / a0,
+		a1: 2 * (K*K - 1) / a0,
+		a2: (1 - K/q + K*K) / a0,
+	}
+
+	// High pass at about 38Hz.
+	f0, q = 38.13547087602444, 0.5003270373238773
+	K = math.Tan(math.Pi * f0 / float64(rate))
+	a0 = 1 + K/q + K*K
+	k.highPass = biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (K*K - 1) / a0,
+		a2: (1 - K/q + K*K) / a0,
+	}
+
+	return k
+}
+
+// process filters one sample.
+func (k *kWeighting) process(x float64) float64 {
+	return k.highPass.process(k.shelf.process(x))
+}
+
+// biquad is a second order IIR filter in direct form I.
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+}
+
+// process filters one sample.
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.b1*f.x1 + f.b2*f.x2 - f.a1*f.y1 - f.a2*f.y2
+	f.x2, f.x1 = f.x1, x
+	f.y2, f.y1 = f.y1, y
+	return y
+}
+
+// Gain returns a stream of s with its level changed by db decibels.
+// Samples that would clip are held at full scale.
+func Gain(s Stream, db float64) Stream {
+	if db == 0 {
+		return s
+	}
+	return &gainStream{src: s, factor: math.Pow(10, db/20)}
+}
+
+// gainStream scales the samples of another stream.
+type gainStream struct {
+	src    Stream
+	factor float64
+	carry  []byte
+}
+
+// Format returns the format of the stream.
+func (g *gainStream) Format() Format {
+	return g.src.Format()
+}
+
+// Read implements io.Reader.
+func (g *gainStream) Read(p []byte) (int, error) {
+	if len(p) < 2 {
+		return 0, errors.New("read buffer too small for a sample")
+	}
+
+	n := copy(p, g.carry)
+	g.carry = g.carry[:0]
+	m, err := g.src.Read(p[n:])
+	n += m
+
+	even := n &^ 1
+	if even < n {
+		g.carry = append(g.carry, p[even])
+	}
+	for i := 0; i < even; i += 2 {
+		v := float64(int16(binary.LittleEndian.Uint16(p[i:]))) * g.factor
+		binary.LittleEndian.PutUint16(p[i:], uint16(toInt16(float32(v/32768))))
+	}
+	if even == 0 && err == nil {
+		return g.Read(p)
+	}
+	return even, err
+}
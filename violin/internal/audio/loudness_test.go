@@ -0,0 +1,102 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+// sine returns seconds of a sine tone of amplitude amp on each of the
+// channels, or silence when amp is 0.
+func sine(rate, channels int, freq, amp, seconds float64) *Buffer {
+	b := NewBuffer(rate, channels, int(seconds*float64(rate)))
+	for f := 0; f < b.Frames(); f++ {
+		v := float32(amp * math.Sin(2*math.Pi*freq*float64(f)/float64(rate)))
+		for c := 0; c < channels; c++ {
+			b.Samples[f*channels+c] = v
+		}
+	}
+	return b
+}
+
+// concat joins buffers of the same format end to end.
+func concat(buffers ...*Buffer) *Buffer {
+	b := &Buffer{SampleRate: buffers[0].SampleRate, Channels: buffers[0].Channels}
+	for _, x := range buffers {
+		b.Samples = append(b.Samples, x.Samples...)
+	}
+	return b
+}
+
+func measure(t *testing.T, b *Buffer) Loudness {
+	t.Helper()
+	l, err := MeasureLoudness(b.Stream())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestLoudnessSine(t *testing.T) {
+	// BS.1770 reads a 1kHz sine at 0dBFS on one channel as -3.01 LUFS, the
+	// K-weighting being calibrated to leave 1kHz unchanged. Each channel
+	// carrying it adds its power.
+	tests := []struct {
+		name     string
+		rate     int
+		channels int
+		amp      float64
+		want     float64
+	}{
+		{"mono -20dBFS at 48kHz", 48000, 1, 0.1, -23.01},
+		{"mono -20dBFS at 44.1kHz", 44100, 1, 0.1, -23.01},
+		{"stereo -20dBFS", 48000, 2, 0.1, -20.0},
+		{"stereo -6dBFS", 44100, 2, 0.5, -6.02},
+	}
+	for _, tt := range tests {
+		l := measure(t, sine(tt.rate, tt.channels, 1000, tt.amp, 5))
+		if math.Abs(l.Integrated-tt.want) > 0.1 {
+			t.Errorf("%s: %.2f LUFS, want %.2f", tt.name, l.Integrated, tt.want)
+		}
+		if want := 20 * math.Log10(tt.amp); math.Abs(l.Peak-want) > 0.01 {
+			t.Errorf("%s: peak %.2f dBFS, want %.2f", tt.name, l.Peak, want)
+		}
+	}
+}
+
+func TestLoudnessKWeighting(t *testing.T) {
+	ref := measure(t, sine(48000, 1, 1000, 0.1, 5)).Integrated
+
+	// The high shelf lifts the top by about 4dB and the high pass cuts the
+	// lowest notes.
+	if d := measure(t, sine(48000, 1, 10000, 0.1, 5)).Integrated - ref; d < 3 || d > 4.5 {
+		t.Errorf("10kHz reads %+.2f LU from 1kHz, want about +3.5", d)
+	}
+	if d := measure(t, sine(48000, 1, 20, 0.1, 5)).Integrated - ref; d > -10 {
+		t.Errorf("20Hz reads %+.2f LU from 1kHz, want well below", d)
+	}
+}
+
+func TestLoudnessGating(t *testing.T) {
+	tone := measure(t, sine(48000, 2, 1000, 0.1, 5)).Integrated
+
+	tests := []struct {
+		name string
+		b    *Buffer
+	}{
+		// Silence is below the absolute gate at -70 LUFS.
+		{"silence after", concat(sine(48000, 2, 1000, 0.1, 5), sine(48000, 2, 1000, 0, 5))},
+		// A tone at -60 LUFS passes the absolute gate but not the relative
+		// one, 10 LU below the loudness of the blocks passing the first.
+		{"quiet tone after", concat(sine(48000, 2, 1000, 0.1, 5), sine(48000, 2, 1000, 0.001, 5))},
+	}
+	for _, tt := range tests {
+		// Only the blocks straddling the change differ from the tone alone.
+		if l := measure(t, tt.b).Integrated; math.Abs(l-tone) > 0.3 {
+			t.Errorf("%s: %.2f LUFS, want %.2f of the tone alone", tt.name, l, tone)
+		}
+	}
+
+	if l := measure(t, sine(48000, 2, 1000, 0, 5)).Integrated; !math.IsInf(l, -1) {
+		t.Errorf("silence: %.2f LUFS, want -Inf", l)
+	}
+}
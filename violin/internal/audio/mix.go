@@ -0,0 +1,74 @@
+package audio
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// Track is one part of a mix with its level and position in the stereo
+// field.
+type Track struct {
+	Buffer *Buffer
+	Gain   float64 // linear gain, 1 leaves the level unchanged
+	Pan    float64 // -1 is hard left, 0 centre, 1 hard right
+	Mute   bool
+}
+
+// Mix sums the tracks into a stereo buffer as long as the longest track.
+// Each track is folded to mono and placed in the stereo field with an equal
+// power pan law, so a centred track keeps its level. The mix is scaled down
+// when it would otherwise clip.
+func Mix(tracks []Track) (*Buffer, error) {
+	if len(tracks) == 0 {
+		return nil, errors.New("nothing to mix")
+	}
+
+	rate, frames := 0, 0
+	for i, t := range tracks {
+		if t.Buffer == nil || t.Buffer.Channels == 0 {
+			return nil, errors.Errorf("track %d is empty", i+1)
+		}
+		if rate == 0 {
+			rate = t.Buffer.SampleRate
+		}
+		if t.Buffer.SampleRate != rate {
+			return nil, errors.Errorf("track %d has sample rate %d, want %d", i+1, t.Buffer.SampleRate, rate)
+		}
+		if n := t.Buffer.Frames(); n > frames {
+			frames = n
+		}
+	}
+
+	out := NewBuffer(rate, 2, frames)
+	for _, t := range tracks {
+		if t.Mute || t.Gain <= 0 {
+			continue
+		}
+
+		pan := math.Max(-1, math.Min(1, t.Pan))
+		angle := (pan + 1) * math.Pi / 4
+		left := float32(t.Gain * math.Cos(angle) * math.Sqrt2)
+		right := float32(t.Gain * math.Sin(angle) * math.Sqrt2)
+
+		src := t.Buffer
+		for f := 0; f < src.Frames(); f++ {
+			var mono float32
+			for c := 0; c < src.Channels; c++ {
+				mono += src.Samples[f*src.Channels+c]
+			}
+			mono /= float32(src.Channels)
+
+			out.Samples[2*f] += mono * left
+			out.Samples[2*f+1] += mono * right
+		}
+	}
+
+	if peak := out.Peak(); peak > 1 {
+		scale := 1 / peak
+		for i := range out.Samples {
+			out.Samples[i] *= scale
+		}
+	}
+	return out, nil
+}
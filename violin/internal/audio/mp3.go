@@ -0,0 +1,43 @@
+package audio
+
+import (
+	"io"
+
+	"github.com/hajimehoshi/go-mp3"
+	"github.com/pkg/errors"
+)
+
+// mp3Stream adapts the MP3 decoder to a Stream. The decoder always produces
+// 16 bit little endian stereo samples.
+type mp3Stream struct {
+	*mp3.Decoder
+}
+
+// Format returns the format of the decoded samples.
+func (s mp3Stream) Format() Format {
+	return Format{SampleRate: s.SampleRate(), Channels: 2}
+}
+
+// NewMP3Decoder returns a stream of the samples of an MP3 file, decoded as
+// they are read. When r is also an io.Seeker the stream knows its length.
+func NewMP3Decoder(r io.Reader) (Stream, error) {
+	d, err := mp3.NewDecoder(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading mp3 header")
+	}
+	return mp3Stream{d}, nil
+}
+
+// DecodeMP3 decodes a whole MP3 stream into a stereo buffer at the sample
+// rate of the stream.
+func DecodeMP3(r io.Reader) (*Buffer, error) {
+	s, err := NewMP3Decoder(r)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ReadBuffer(s)
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding mp3")
+	}
+	return b, nil
+}
@@ -0,0 +1,42 @@
+package audio
+
+import (
+	"os"
+	"testing"
+)
+
+func TestDecodeMP3(t *testing.T) {
+	f, err := os.Open("../../cmd/violin/mp3/scale/major/a1.mp3")
+	if err != nil {
+		t.Skip("recording not available:", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewMP3Decoder(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l, ok := s.(interface{ Length() int64 }); !ok || l.Length() <= 0 {
+		t.Error("stream of a file does not know its length")
+	}
+	b, err := ReadBuffer(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b.Channels != 2 || b.SampleRate != 44100 && b.SampleRate != 48000 {
+		t.Errorf("decoded %d Hz with %d channels, want 44100 or 48000 Hz stereo", b.SampleRate, b.Channels)
+	}
+	// The file's bit rate puts its length within a range of playing times.
+	seconds := b.Duration().Seconds()
+	if kbps := float64(info.Size()) * 8 / 1000 / seconds; kbps < 32 || kbps > 320 {
+		t.Errorf("decoded %.1f s from %d bytes, %.0f kbit/s", seconds, info.Size(), kbps)
+	}
+	if b.Peak() < 0.01 {
+		t.Errorf("decoded silence, peak %v", b.Peak())
+	}
+}
@@ -0,0 +1,153 @@
+package audio
+
+import (
+	"math"
+	"math/cmplx"
+	"sort"
+	"time"
+)
+
+// Onset detection settings, for notes as short as a fast semiquaver.
+const (
+	onsetWindow   = 2048 // samples analysed at a time, a power of two
+	onsetHop      = 512  // samples between analyses
+	onsetSpread   = 3    // analyses either side an onset must stand out of
+	onsetAverage  = 16   // analyses either side the threshold is averaged over
+	onsetDelta    = 0.05 // of the strongest flux, added to the threshold
+	onsetMinGap   = 80 * time.Millisecond
+	onsetCompress = 100.0 // log compression of magnitudes
+)
+
+// Onset is where a note starts in a recording, with the strength of the
+// change in sound that marks it.
+type Onset struct {
+	Time     time.Duration
+	Strength float64
+}
+
+// DetectOnsets finds where notes start in a recording by spectral flux: how
+// much louder each frequency gets from one moment to the next. A new bow
+// stroke and a change of note under a slur both light up frequencies that
+// were quiet, even when the overall level hardly changes.
+func DetectOnsets(b *Buffer) []Onset {
+	frames := b.Frames()
+	if frames < onsetWindow {
+		return nil
+	}
+
+	window := make([]float64, onsetWindow)
+	for i := range window {
+		window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(onsetWindow))
+	}
+
+	var flux []float64
+	prev := make([]float64, onsetWindow/2)
+	spectrum := make([]complex128, onsetWindow)
+	for start := 0; start+onsetWindow <= frames; start += onsetHop {
+		for i := range spectrum {
+			var v float64
+			for c := 0; c < b.Channels; c++ {
+				v += float64(b.Samples[(start+i)*b.Channels+c])
+			}
+			spectrum[i] = complex(v*window[i], 0)
+		}
+		fft(spectrum)
+
+		var f float64
+		for k := range prev {
+			m := math.Log1p(onsetCompress * cmplx.Abs(spectrum[k]))
+			if d := m - prev[k]; d > 0 && start > 0 {
+				f += d
+			}
+			prev[k] = m
+		}
+		flux = append(flux, f)
+	}
+
+	var strongest float64
+	for _, f := range flux {
+		strongest = math.Max(strongest, f)
+	}
+	if strongest == 0 {
+		return nil
+	}
+
+	hop := time.Duration(onsetHop) * time.Second / time.Duration(b.SampleRate)
+	var onsets []Onset
+	for i, f := range flux {
+		lo, hi := i-onsetAverage, i+onsetAverage
+		if lo < 0 {
+			lo = 0
+		}
+		if hi >= len(flux) {
+			hi = len(flux) - 1
+		}
+		var sum float64
+		peak := true
+		for j := lo; j <= hi; j++ {
+			sum += flux[j]
+			if j != i && j >= i-onsetSpread && j <= i+onsetSpread && flux[j] > f {
+				peak = false
+			}
+		}
+		if !peak || f < sum/float64(hi-lo+1)+onsetDelta*strongest {
+			continue
+		}
+
+		// The flux peaks as the new note fills the window, a little
+		// after it starts.
+		at := time.Duration(i)*hop + hop*onsetWindow/onsetHop/4
+		if n := len(onsets); n > 0 && at-onsets[n-1].Time < onsetMinGap {
+			if f > onsets[n-1].Strength {
+				onsets[n-1] = Onset{at, f}
+			}
+			continue
+		}
+		onsets = append(onsets, Onset{at, f})
+	}
+	return onsets
+}
+
+// StrongestOnsets returns the n strongest onsets in time order, or all of
+// them when there are fewer.
+func StrongestOnsets(onsets []Onset, n int) []Onset {
+	if len(onsets) <= n {
+		return onsets
+	}
+	strongest := append([]Onset(nil), onsets...)
+	sort.SliceStable(strongest, func(i, j int) bool {
+		return strongest[i].Strength > strongest[j].Strength
+	})
+	strongest = strongest[:n]
+	sort.Slice(strongest, func(i, j int) bool {
+		return strongest[i].Time < strongest[j].Time
+	})
+	return strongest
+}
+
+// fft replaces x, whose length is a power of two, with its discrete Fourier
+// transform.
+func fft(x []complex128) {
+	n := len(x)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+	for size := 2; size <= n; size <<= 1 {
+		w := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			wk := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				a, b := x[start+k], wk*x[start+k+size/2]
+				x[start+k], x[start+k+size/2] = a+b, a-b
+				wk *= w
+			}
+		}
+	}
+}
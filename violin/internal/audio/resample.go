@@ -0,0 +1,183 @@
+package audio
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// Resampler settings. Each output sample is interpolated from resampleTaps
+// input samples either side with a Blackman windowed sinc, evaluated from a
+// table of resamplePhases steps between two input samples.
+const (
+	resampleTaps   = 16
+	resamplePhases = 256
+)
+
+// resampleKernel holds the windowed sinc from 0 to resampleTaps input
+// samples, resamplePhases entries per sample.
+var resampleKernel = func() []float64 {
+	k := make([]float64, resampleTaps*resamplePhases+2)
+	for i := range k {
+		x := float64(i) / resamplePhases
+		if x >= resampleTaps {
+			continue
+		}
+		sinc := 1.0
+		if x != 0 {
+			sinc = math.Sin(math.Pi*x) / (math.Pi * x)
+		}
+		t := math.Pi * (x/resampleTaps + 1)
+		window := 0.42 - 0.5*math.Cos(t) + 0.08*math.Cos(2*t)
+		k[i] = sinc * window
+	}
+	return k
+}()
+
+// Resample returns a stream of s converted to the given sample rate. When
+// lowering the rate the signal is low pass filtered first so frequencies
+// the new rate cannot hold do not alias.
+func Resample(s Stream, rate int) Stream {
+	in := s.Format()
+	if rate <= 0 || rate == in.SampleRate {
+		return s
+	}
+
+	r := resampler{
+		src:    s,
+		format: Format{SampleRate: rate, Channels: in.Channels},
+		step:   float64(in.SampleRate) / float64(rate),
+		cutoff: 1,
+	}
+	if r.step > 1 {
+		r.cutoff = 1 / r.step
+	}
+	r.reach = int(math.Ceil(resampleTaps / r.cutoff))
+
+	// Start with silence before the first sample so the kernel has input on
+	// both sides from the beginning.
+	r.frames = make([]float32, r.reach*in.Channels)
+	r.pos = float64(r.reach)
+	return &r
+}
+
+// resampler is a Stream converting the sample rate of another stream.
+type resampler struct {
+	src    Stream
+	format Format
+	step   float64 // input samples per output sample
+	cutoff float64 // low pass cutoff relative to the input rate
+	reach  int     // input samples either side needed for one output
+
+	frames []float32 // buffered input, interleaved
+	pos    float64   // input position of the next output, in frames
+	eof    bool
+	end    int // frames of input including trailing silence, once at eof
+	carry  []byte
+	out    []byte
+}
+
+// Format returns the format of the resampled stream.
+func (r *resampler) Format() Format {
+	return r.format
+}
+
+// Read implements io.Reader.
+func (r *resampler) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if err := r.produce(len(p)/2/r.format.Channels + 1); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// produce resamples up to n frames into r.out, reading more input as
+// needed. It returns io.EOF once the input is used up.
+func (r *resampler) produce(n int) error {
+	ch := r.format.Channels
+	need := int(r.pos+float64(n)*r.step) + r.reach + 1
+	if err := r.fill(need); err != nil {
+		return err
+	}
+
+	buffered := len(r.frames) / ch
+	if r.eof && r.pos >= float64(r.end-r.reach) {
+		return io.EOF
+	}
+
+	out := make([]byte, 0, n*ch*2)
+	sums := make([]float64, ch)
+	for i := 0; i < n; i++ {
+		center := int(r.pos)
+		if center+r.reach >= buffered || (r.eof && r.pos >= float64(r.end-r.reach)) {
+			break
+		}
+
+		for c := range sums {
+			sums[c] = 0
+		}
+		for j := center - r.reach + 1; j <= center+r.reach; j++ {
+			x := math.Abs(float64(j)-r.pos) * r.cutoff
+			idx := x * resamplePhases
+			k := int(idx)
+			if k >= len(resampleKernel)-1 {
+				continue
+			}
+			w := resampleKernel[k] + (resampleKernel[k+1]-resampleKernel[k])*(idx-float64(k))
+			for c := 0; c < ch; c++ {
+				sums[c] += w * float64(r.frames[j*ch+c])
+			}
+		}
+
+		for c := 0; c < ch; c++ {
+			v := uint16(toInt16(float32(sums[c] * r.cutoff)))
+			out = append(out, byte(v), byte(v>>8))
+		}
+		r.pos += r.step
+	}
+	r.out = out
+
+	// Drop input no longer needed by the kernel.
+	if drop := int(r.pos) - r.reach; drop > 0 {
+		r.frames = r.frames[drop*ch:]
+		r.pos -= float64(drop)
+		r.end -= drop
+	}
+	if len(r.out) == 0 && r.eof {
+		return io.EOF
+	}
+	return nil
+}
+
+// fill reads input until at least n frames are buffered or the input ends,
+// padding the end with silence.
+func (r *resampler) fill(n int) error {
+	ch := r.format.Channels
+	chunk := make([]byte, 16*1024)
+	for !r.eof && len(r.frames)/ch < n {
+		m, err := r.src.Read(chunk)
+		data := append(r.carry, chunk[:m]...)
+		even := len(data) &^ 1
+		for i := 0; i < even; i += 2 {
+			r.frames = append(r.frames, float32(int16(binary.LittleEndian.Uint16(data[i:])))/32768)
+		}
+		r.carry = append(r.carry[:0], data[even:]...)
+
+		if err == io.EOF {
+			r.eof = true
+			r.frames = r.frames[:len(r.frames)/ch*ch]
+			r.end = len(r.frames)/ch + r.reach
+			r.frames = append(r.frames, make([]float32, (r.reach+1)*ch)...)
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "reading samples to resample")
+		}
+	}
+	return nil
+}
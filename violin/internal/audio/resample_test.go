@@ -0,0 +1,51 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+func TestResampleLength(t *testing.T) {
+	tests := []struct {
+		from, to, channels int
+	}{
+		{44100, 22050, 2},
+		{44100, 48000, 2},
+		{22050, 44100, 1},
+		{48000, 44100, 1},
+	}
+	for _, tt := range tests {
+		in := testSignal(tt.from, tt.channels, tt.from)
+
+		out, err := ReadBuffer(Resample(in.Stream(), tt.to))
+		if err != nil {
+			t.Fatalf("%d to %d: %v", tt.from, tt.to, err)
+		}
+		if out.SampleRate != tt.to || out.Channels != tt.channels {
+			t.Errorf("%d to %d: resampled to %d Hz with %d channels", tt.from, tt.to, out.SampleRate, out.Channels)
+		}
+
+		// A second of audio stays a second long, give or take a sample.
+		want := in.Frames() * tt.to / tt.from
+		if d := out.Frames() - want; d < -1 || d > 1 {
+			t.Errorf("%d to %d: %d frames, want %d", tt.from, tt.to, out.Frames(), want)
+		}
+
+		// The tones are well below either rate's limit and keep their
+		// level, away from the ends the kernel fades in and out over.
+		var peak float64
+		for f := out.Frames() / 4; f < out.Frames()*3/4; f++ {
+			peak = math.Max(peak, math.Abs(float64(out.Samples[f*tt.channels])))
+		}
+		if peak < 0.45 || peak > 0.56 {
+			t.Errorf("%d to %d: peak of the first channel is %.3f, want about 0.5", tt.from, tt.to, peak)
+		}
+	}
+}
+
+func TestResampleSameRate(t *testing.T) {
+	s := testSignal(44100, 2, 100).Stream()
+	if Resample(s, 44100) != s {
+		t.Error("resampling to the same rate did not return the stream")
+	}
+}
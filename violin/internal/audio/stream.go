@@ -0,0 +1,117 @@
+package audio
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// Format describes interleaved PCM audio.
+type Format struct {
+	SampleRate int
+	Channels   int
+}
+
+// Stream is an io.Reader of interleaved signed 16 bit little endian PCM
+// samples in a known format. Decoders produce streams and encoders and the
+// resampler consume them, so audio can flow from one file to another
+// without being held in memory.
+type Stream interface {
+	io.Reader
+	Format() Format
+}
+
+// SeekStream is a Stream of known length which can seek to any byte of its
+// samples.
+type SeekStream interface {
+	Stream
+	io.Seeker
+	Length() int64
+}
+
+// NewStream wraps a reader of 16 bit PCM samples in the given format.
+func NewStream(r io.Reader, f Format) Stream {
+	return stream{Reader: r, format: f}
+}
+
+// stream is a reader of samples in a fixed format.
+type stream struct {
+	io.Reader
+	format Format
+}
+
+// Format returns the format of the samples.
+func (s stream) Format() Format {
+	return s.format
+}
+
+// Stream returns a stream of the samples in the buffer.
+func (b *Buffer) Stream() Stream {
+	return &bufferStream{b: b}
+}
+
+// bufferStream reads the samples of a buffer as 16 bit PCM.
+type bufferStream struct {
+	b   *Buffer
+	pos int
+}
+
+// Read implements io.Reader.
+func (s *bufferStream) Read(p []byte) (int, error) {
+	if s.pos == len(s.b.Samples) {
+		return 0, io.EOF
+	}
+	n := 0
+	for ; n+2 <= len(p) && s.pos < len(s.b.Samples); n += 2 {
+		binary.LittleEndian.PutUint16(p[n:], uint16(toInt16(s.b.Samples[s.pos])))
+		s.pos++
+	}
+	return n, nil
+}
+
+// Format returns the format of the buffer.
+func (s *bufferStream) Format() Format {
+	return Format{SampleRate: s.b.SampleRate, Channels: s.b.Channels}
+}
+
+// ReadBuffer reads the rest of a stream into a buffer.
+func ReadBuffer(s Stream) (*Buffer, error) {
+	f := s.Format()
+	b := Buffer{SampleRate: f.SampleRate, Channels: f.Channels}
+
+	// Decoders that know their length let us allocate once.
+	if l, ok := s.(interface{ Length() int64 }); ok && l.Length() > 0 {
+		b.Samples = make([]float32, 0, l.Length()/2)
+	}
+
+	chunk := make([]byte, 32*1024)
+	var carry []byte
+	for {
+		n, err := s.Read(chunk[len(carry):])
+		data := chunk[:len(carry)+n]
+		even := len(data) &^ 1
+		for i := 0; i < even; i += 2 {
+			b.Samples = append(b.Samples, float32(int16(binary.LittleEndian.Uint16(data[i:])))/32768)
+		}
+		carry = append(chunk[:0], data[even:]...)
+
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "reading samples")
+		}
+	}
+
+	// Drop a trailing partial frame.
+	if f.Channels > 0 {
+		b.Samples = b.Samples[:len(b.Samples)/f.Channels*f.Channels]
+	}
+	return &b, nil
+}
+
+// Length returns the number of bytes of samples in the buffer.
+func (s *bufferStream) Length() int64 {
+	return int64(len(s.b.Samples)) * 2
+}
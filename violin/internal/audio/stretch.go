@@ -0,0 +1,106 @@
+package audio
+
+import "math"
+
+// Stretch changes the tempo of the buffer without changing its pitch, so a
+// tempo of 0.5 plays at half speed and 2 at double speed. It uses waveform
+// similarity overlap-add (WSOLA): the output is built from windowed frames
+// of the input, each one nudged to line up with the waveform of the frame
+// before it so the joins do not cause phasing.
+func Stretch(b *Buffer, tempo float64) *Buffer {
+	in := b.Frames()
+	if tempo <= 0 || tempo == 1 || in == 0 {
+		return b
+	}
+
+	size := b.SampleRate * 40 / 1000 &^ 1
+	hop := size / 2
+	seek := b.SampleRate * 10 / 1000
+
+	window := make([]float32, size)
+	for i := range window {
+		window[i] = float32(0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(size)))
+	}
+
+	ch := b.Channels
+	mono := make([]float32, in)
+	for f := range mono {
+		for c := 0; c < ch; c++ {
+			mono[f] += b.Samples[f*ch+c]
+		}
+	}
+
+	frames := int(float64(in) / tempo)
+	out := NewBuffer(b.SampleRate, ch, frames+size)
+
+	prev := 0
+	for outPos := 0; outPos < frames; outPos += hop {
+		pos := int(float64(outPos) * tempo)
+		if outPos > 0 {
+			pos = align(mono, prev+hop, pos, seek, hop)
+		}
+
+		for i := 0; i < size && pos+i < in; i++ {
+			w := window[i]
+			if outPos == 0 && i < hop {
+				// Nothing precedes the first frame to overlap with.
+				w = 1
+			}
+			src, dst := (pos+i)*ch, (outPos+i)*ch
+			for c := 0; c < ch; c++ {
+				out.Samples[dst+c] += b.Samples[src+c] * w
+			}
+		}
+		prev = pos
+	}
+
+	out.Samples = out.Samples[:frames*ch]
+	return out
+}
+
+// align returns the position within seek of nominal whose next n samples
+// best match the n samples at target, the natural continuation of the
+// previous frame. It searches coarsely first and then refines around the
+// best coarse match.
+func align(mono []float32, target, nominal, seek, n int) int {
+	if target < 0 || target+n > len(mono) {
+		return nominal
+	}
+	ref := mono[target : target+n]
+
+	score := func(pos, stride int) float64 {
+		if pos < 0 || pos+n > len(mono) {
+			return math.Inf(-1)
+		}
+		var corr, energy float64
+		for i := 0; i < n; i += stride {
+			s := float64(mono[pos+i])
+			corr += s * float64(ref[i])
+			energy += s * s
+		}
+		if energy == 0 {
+			return 0
+		}
+		return corr / math.Sqrt(energy)
+	}
+
+	best, bestScore := nominal, math.Inf(-1)
+	for pos := nominal - seek; pos <= nominal+seek; pos += 4 {
+		if s := score(pos, 4); s > bestScore {
+			best, bestScore = pos, s
+		}
+	}
+
+	coarse := best
+	bestScore = math.Inf(-1)
+	for pos := coarse - 3; pos <= coarse+3; pos++ {
+		if s := score(pos, 1); s > bestScore {
+			best, bestScore = pos, s
+		}
+	}
+
+	if math.IsInf(bestScore, -1) {
+		return nominal
+	}
+	return best
+}
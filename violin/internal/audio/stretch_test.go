@@ -0,0 +1,34 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStretch(t *testing.T) {
+	in := testSignal(44100, 2, 44100)
+	for _, tempo := range []float64{0.5, 0.8, 1.25, 2} {
+		out := Stretch(in, tempo)
+		want := int(float64(in.Frames()) / tempo)
+		if out.Frames() != want || out.Channels != 2 || out.SampleRate != 44100 {
+			t.Errorf("tempo %v: %d frames of %d channels at %d Hz, want %d of 2 at 44100", tempo, out.Frames(), out.Channels, out.SampleRate, want)
+			continue
+		}
+
+		// The pitch stays put: the first channel's 440 Hz tone crosses
+		// zero upwards 440 times a second whatever the tempo.
+		var crossings int
+		for f := 1; f < out.Frames(); f++ {
+			if out.Samples[(f-1)*2] < 0 && out.Samples[f*2] >= 0 {
+				crossings++
+			}
+		}
+		perSecond := float64(crossings) / out.Duration().Seconds()
+		if math.Abs(perSecond-440) > 440*0.03 {
+			t.Errorf("tempo %v: tone at %.0f Hz, want 440", tempo, perSecond)
+		}
+	}
+	if out := Stretch(in, 1); out != in {
+		t.Error("stretching to the same tempo did not return the buffer")
+	}
+}
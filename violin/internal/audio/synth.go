@@ -0,0 +1,114 @@
+package audio
+
+import (
+	"math"
+	"time"
+)
+
+// Synthesize plays MIDI notes one after another in a plain bowed string
+// tone, each lasting noteLength, for scales and arpeggios nobody has
+// recorded. The tone is a stack of harmonics falling away in level, with a
+// soft attack and release and a gentle vibrato once the note has settled.
+func Synthesize(notes []int, noteLength time.Duration, sampleRate int) *Buffer {
+	tones := make([]Tone, len(notes))
+	for i, note := range notes {
+		tones[i] = Tone{Notes: []int{note}, Length: noteLength}
+	}
+	return SynthesizeTones(tones, sampleRate)
+}
+
+// Tone is a chord of MIDI notes the synthesizer plays together for Length.
+// A tone Slurred into the next one changes note without a new bow stroke.
+// Cents puts every note of the tone out of tune by that much.
+type Tone struct {
+	Notes   []int
+	Length  time.Duration
+	Slurred bool
+	Cents   float64
+}
+
+// SynthesizeTones plays tones one after another the way Synthesize plays
+// notes, each note of a tone in its own voice.
+func SynthesizeTones(tones []Tone, sampleRate int) *Buffer {
+	const tailLength = 0.5 // seconds of silence after the last note
+
+	frames := 0
+	for _, t := range tones {
+		frames += int(t.Length.Seconds() * float64(sampleRate))
+	}
+	tail := int(tailLength * float64(sampleRate))
+	b := NewBuffer(sampleRate, 2, frames+tail)
+
+	start := 0
+	for i, t := range tones {
+		n := int(t.Length.Seconds() * float64(sampleRate))
+		slurredIn := i > 0 && tones[i-1].Slurred
+		for _, note := range t.Notes {
+			freq := 440 * math.Pow(2, (float64(note-69)+t.Cents/100)/12)
+			b.addNote(freq, start, n, sampleRate, slurredIn, t.Slurred)
+		}
+		start += n
+	}
+	return b
+}
+
+// addNote adds a note of the frequency lasting n frames to the buffer from
+// frame start. Notes slurred in or out change over quickly instead of fading
+// in or out with the bow.
+func (b *Buffer) addNote(freq float64, start, n, sampleRate int, slurredIn, slurredOut bool) {
+	const (
+		bowAttack     = 0.06  // seconds
+		bowRelease    = 0.08  // seconds
+		slurChange    = 0.015 // seconds
+		vibratoDelay  = 0.2   // seconds
+		vibratoRate   = 5.5   // Hz
+		vibratoDepth  = 0.003 // of the frequency
+		maxHarmonics  = 20
+		level         = 0.25
+		harmonicSlope = 8.0 // harmonics lose 1/e of their level over this many
+	)
+
+	attack, release := bowAttack, bowRelease
+	if slurredIn {
+		attack = slurChange
+	}
+	if slurredOut {
+		release = slurChange
+	}
+
+	var amps []float64
+	for h := 1; h <= maxHarmonics && float64(h)*freq < 0.45*float64(sampleRate); h++ {
+		amps = append(amps, math.Exp(-float64(h-1)/harmonicSlope)/float64(h))
+	}
+
+	phase := 0.0
+	for f := 0; f < n; f++ {
+		t := float64(f) / float64(sampleRate)
+		left := float64(n-f) / float64(sampleRate)
+
+		env := 1.0
+		if t < attack {
+			env = t / attack
+		}
+		if left < release {
+			env *= left / release
+		}
+
+		vib := 0.0
+		if t > vibratoDelay {
+			depth := math.Min((t-vibratoDelay)/vibratoDelay, 1) * vibratoDepth
+			vib = depth * math.Sin(2*math.Pi*vibratoRate*(t-vibratoDelay))
+		}
+		phase += 2 * math.Pi * freq * (1 + vib) / float64(sampleRate)
+
+		var v float64
+		for h, a := range amps {
+			v += a * math.Sin(float64(h+1)*phase)
+		}
+		s := float32(level * env * v)
+
+		at := (start + f) * 2
+		b.Samples[at] += s
+		b.Samples[at+1] += s
+	}
+}
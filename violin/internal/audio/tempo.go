@@ -0,0 +1,106 @@
+package audio
+
+import (
+	"math"
+	"time"
+)
+
+// Beat detection settings, for music between 50 and 180 beats a minute.
+const (
+	beatGrid    = 10 * time.Millisecond // resolution onsets are placed on
+	beatSpread  = 2                     // grid steps either side an onset counts on
+	beatSlowest = 60 * time.Second / 50
+	beatFastest = 60 * time.Second / 180
+	beatCentre  = 60 * time.Second / 100 // the most likely beat
+)
+
+// DetectBeat returns how far apart the beats of a recording are, from the
+// onsets found in it, and where the first beat falls. The beat is the
+// spacing at which the onsets line up with themselves best, favouring
+// spacings near 100 beats a minute so that a piece in quavers is not taken
+// to be twice as fast. It returns a zero beat when there are too few onsets
+// to tell.
+func DetectBeat(onsets []Onset) (beat, first time.Duration) {
+	if len(onsets) < 4 {
+		return 0, 0
+	}
+
+	last := int(onsets[len(onsets)-1].Time / beatGrid)
+	envelope := make([]float64, last+beatSpread+1)
+	for _, o := range onsets {
+		at := int(o.Time / beatGrid)
+		for d := -beatSpread; d <= beatSpread; d++ {
+			if i := at + d; i >= 0 && i < len(envelope) {
+				envelope[i] += o.Strength * float64(beatSpread+1-abs(d)) / float64(beatSpread+1)
+			}
+		}
+	}
+
+	lag, best := 0, 0.0
+	for l := int(beatFastest / beatGrid); l <= int(beatSlowest/beatGrid) && l < len(envelope); l++ {
+		var sum float64
+		for i := 0; i+l < len(envelope); i++ {
+			sum += envelope[i] * envelope[i+l]
+		}
+		octaves := math.Log2(float64(time.Duration(l)*beatGrid) / float64(beatCentre))
+		score := sum / float64(len(envelope)-l) * math.Exp(-octaves*octaves/2)
+		if score > best {
+			lag, best = l, score
+		}
+	}
+	if lag == 0 {
+		return 0, 0
+	}
+
+	// The beats fall where the onsets on a grid of that spacing are
+	// strongest, from the first of them at or after the first onset.
+	phase, best := 0, -1.0
+	for p := 0; p < lag; p++ {
+		var sum float64
+		for i := p; i < len(envelope); i += lag {
+			sum += envelope[i]
+		}
+		if sum > best {
+			phase, best = p, sum
+		}
+	}
+	start := int(onsets[0].Time/beatGrid) - beatSpread
+	for phase < start {
+		phase += lag
+	}
+	beat, first = time.Duration(lag)*beatGrid, time.Duration(phase)*beatGrid
+	return fitBeat(onsets, beat, first)
+}
+
+// fitBeat refines a beat found on the grid, which is too coarse to follow
+// a long recording, by fitting a straight line through the onsets that fall
+// on a beat: their times against the counts of the beats.
+func fitBeat(onsets []Onset, beat, first time.Duration) (time.Duration, time.Duration) {
+	var n, sx, sy, sxx, sxy float64
+	for _, o := range onsets {
+		k := math.Round(float64(o.Time-first) / float64(beat))
+		if k < 0 || math.Abs(float64(o.Time-first)-k*float64(beat)) > float64(beat)/8 {
+			continue
+		}
+		t := o.Time.Seconds()
+		n, sx, sy, sxx, sxy = n+1, sx+k, sy+t, sxx+k*k, sxy+k*t
+	}
+	d := n*sxx - sx*sx
+	if n < 4 || d == 0 {
+		return beat, first
+	}
+	slope := (n*sxy - sx*sy) / d
+	intercept := (sy - slope*sx) / n
+	if slope <= 0 || intercept < 0 {
+		return beat, first
+	}
+	return time.Duration(slope * float64(time.Second)), time.Duration(intercept * float64(time.Second))
+}
+
+// abs returns the absolute value of n.
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,47 @@
+package audio
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDetectBeat(t *testing.T) {
+	tests := []struct {
+		name  string
+		bpm   int
+		beats []int // notes to each beat, repeated
+	}{
+		{"crotchets", 100, []int{1}},
+		{"quavers", 100, []int{2}},
+		{"mixed", 120, []int{1, 2, 2, 1}},
+		{"slow", 60, []int{1, 1, 2}},
+	}
+	const lead = 700 * time.Millisecond
+	for _, tt := range tests {
+		beat := time.Minute / time.Duration(tt.bpm)
+		var tones []Tone
+		note := 60
+		for i := 0; i < 32; i++ {
+			n := tt.beats[i%len(tt.beats)]
+			for j := 0; j < n; j++ {
+				tones = append(tones, Tone{Notes: []int{note}, Length: beat / time.Duration(n)})
+				note = 60 + (note-59)%12
+			}
+		}
+		played := SynthesizeTones(tones, 44100)
+		silence := int(lead.Seconds() * 44100)
+		b := NewBuffer(44100, played.Channels, silence+played.Frames())
+		copy(b.Samples[silence*b.Channels:], played.Samples)
+
+		got, first := DetectBeat(DetectOnsets(b))
+		if d := got - beat; d < -beat/200 || d > beat/200 {
+			t.Errorf("%s: beat of %v, want %v", tt.name, got, beat)
+		}
+		if d := first - lead; d < -30*time.Millisecond || d > 30*time.Millisecond {
+			t.Errorf("%s: first beat at %v, want %v", tt.name, first, lead)
+		}
+	}
+	if beat, _ := DetectBeat(nil); beat != 0 {
+		t.Errorf("beat of %v with no onsets, want 0", beat)
+	}
+}
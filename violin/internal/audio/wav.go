@@ -0,0 +1,277 @@
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// unknownSize is written as the chunk sizes of a WAV file whose length is
+// not known when the header is written. Most players then read to the end.
+const unknownSize = math.MaxUint32
+
+// EncodeWAV writes the buffer as a 16 bit PCM WAV file. Samples outside the
+// range [-1, 1] are clipped.
+func EncodeWAV(w io.Writer, b *Buffer) error {
+	return WriteWAV(w, b.Stream())
+}
+
+// WriteWAV writes a stream as a 16 bit PCM WAV file. The chunk sizes in the
+// header are exact when the stream knows its length or w can seek, and are
+// left open otherwise.
+func WriteWAV(w io.Writer, s Stream) error {
+	size := int64(unknownSize)
+	if l, ok := s.(interface{ Length() int64 }); ok && l.Length() >= 0 {
+		size = l.Length()
+	}
+
+	ws, seekable := w.(io.WriteSeeker)
+	var start int64
+	if seekable {
+		var err error
+		if start, err = ws.Seek(0, io.SeekCurrent); err != nil {
+			seekable = false
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	if err := writeWAVHeader(bw, s.Format(), size); err != nil {
+		return err
+	}
+	n, err := io.Copy(bw, s)
+	if err != nil {
+		return errors.Wrap(err, "writing wav samples")
+	}
+	if err := bw.Flush(); err != nil {
+		return errors.Wrap(err, "writing wav samples")
+	}
+
+	if n == size || !seekable {
+		return nil
+	}
+
+	// Go back and fix the header now the length is known.
+	if _, err := ws.Seek(start, io.SeekStart); err != nil {
+		return errors.Wrap(err, "rewinding to wav header")
+	}
+	if err := writeWAVHeader(ws, s.Format(), n); err != nil {
+		return err
+	}
+	if _, err := ws.Seek(0, io.SeekEnd); err != nil {
+		return errors.Wrap(err, "seeking to end of wav")
+	}
+	return nil
+}
+
+// NewWAVReader returns a WAV file of a seekable stream. The file can seek
+// too, so long generated audio can be served in ranges with
+// http.ServeContent without ever being written out.
+func NewWAVReader(s SeekStream) (io.ReadSeeker, error) {
+	var header bytes.Buffer
+	if err := writeWAVHeader(&header, s.Format(), s.Length()); err != nil {
+		return nil, err
+	}
+	return &wavReader{header: header.Bytes(), s: s}, nil
+}
+
+// wavReader is the io.ReadSeeker returned by NewWAVReader.
+type wavReader struct {
+	header []byte
+	s      SeekStream
+	off    int64
+}
+
+// Read implements io.Reader.
+func (r *wavReader) Read(p []byte) (int, error) {
+	h := int64(len(r.header))
+	if r.off < h {
+		n := copy(p, r.header[r.off:])
+		r.off += int64(n)
+		return n, nil
+	}
+
+	if _, err := r.s.Seek(r.off-h, io.SeekStart); err != nil {
+		return 0, err
+	}
+	n, err := r.s.Read(p)
+	r.off += int64(n)
+	return n, err
+}
+
+// Seek implements io.Seeker.
+func (r *wavReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.off
+	case io.SeekEnd:
+		offset += int64(len(r.header)) + r.s.Length()
+	default:
+		return 0, errors.Errorf("invalid whence %d", whence)
+	}
+	if offset < 0 {
+		return 0, errors.New("seeking before the start")
+	}
+	r.off = offset
+	return offset, nil
+}
+
+// writeWAVHeader writes the RIFF header and format chunk of a 16 bit PCM
+// WAV file with size bytes of samples.
+func writeWAVHeader(w io.Writer, f Format, size int64) error {
+	const bitsPerSample = 16
+	blockAlign := f.Channels * bitsPerSample / 8
+
+	riffSize := uint32(unknownSize)
+	if size < unknownSize-36 {
+		riffSize = uint32(36 + size)
+	}
+	dataSize := uint32(unknownSize)
+	if size < unknownSize {
+		dataSize = uint32(size)
+	}
+
+	header := []interface{}{
+		[4]byte{'R', 'I', 'F', 'F'},
+		riffSize,
+		[4]byte{'W', 'A', 'V', 'E'},
+		[4]byte{'f', 'm', 't', ' '},
+		uint32(16),
+		uint16(1), // PCM
+		uint16(f.Channels),
+		uint32(f.SampleRate),
+		uint32(f.SampleRate * blockAlign),
+		uint16(blockAlign),
+		uint16(bitsPerSample),
+		[4]byte{'d', 'a', 't', 'a'},
+		dataSize,
+	}
+	for _, v := range header {
+		if err := binary.Write(w, binary.LittleEndian, v); err != nil {
+			return errors.Wrap(err, "writing wav header")
+		}
+	}
+	return nil
+}
+
+// wavStream reads the samples of the data chunk of a WAV file.
+type wavStream struct {
+	io.Reader
+	format Format
+	size   int64
+}
+
+// Format returns the format of the samples.
+func (s wavStream) Format() Format {
+	return s.format
+}
+
+// Length returns the number of bytes of samples, or -1 when the file did
+// not record it.
+func (s wavStream) Length() int64 {
+	return s.size
+}
+
+// NewWAVDecoder reads the header of a 16 bit PCM WAV file and returns a
+// stream of its samples.
+func NewWAVDecoder(r io.Reader) (Stream, error) {
+	var riff struct {
+		ID   [4]byte
+		Size uint32
+		Wave [4]byte
+	}
+	if err := binary.Read(r, binary.LittleEndian, &riff); err != nil {
+		return nil, errors.Wrap(err, "reading riff header")
+	}
+	if string(riff.ID[:]) != "RIFF" || string(riff.Wave[:]) != "WAVE" {
+		return nil, errors.New("not a wav file")
+	}
+
+	var format Format
+	for {
+		var chunk struct {
+			ID   [4]byte
+			Size uint32
+		}
+		if err := binary.Read(r, binary.LittleEndian, &chunk); err != nil {
+			return nil, errors.Wrap(err, "reading wav chunk")
+		}
+
+		switch string(chunk.ID[:]) {
+		case "fmt ":
+			var fmtChunk struct {
+				AudioFormat   uint16
+				Channels      uint16
+				SampleRate    uint32
+				ByteRate      uint32
+				BlockAlign    uint16
+				BitsPerSample uint16
+			}
+			if chunk.Size < 16 {
+				return nil, errors.Errorf("wav format chunk is %d bytes", chunk.Size)
+			}
+			if err := binary.Read(r, binary.LittleEndian, &fmtChunk); err != nil {
+				return nil, errors.Wrap(err, "reading wav format")
+			}
+			if fmtChunk.AudioFormat != 1 || fmtChunk.BitsPerSample != 16 {
+				return nil, errors.Errorf("unsupported wav encoding %d with %d bit samples", fmtChunk.AudioFormat, fmtChunk.BitsPerSample)
+			}
+			if fmtChunk.Channels == 0 || fmtChunk.SampleRate == 0 {
+				return nil, errors.New("wav file has no channels or sample rate")
+			}
+			format = Format{SampleRate: int(fmtChunk.SampleRate), Channels: int(fmtChunk.Channels)}
+			if err := skip(r, int64(chunk.Size-16)+int64(chunk.Size&1)); err != nil {
+				return nil, errors.Wrap(err, "reading wav format")
+			}
+
+		case "data":
+			if format.Channels == 0 {
+				return nil, errors.New("wav data before format")
+			}
+			if chunk.Size == unknownSize {
+				return wavStream{Reader: r, format: format, size: -1}, nil
+			}
+			return wavStream{Reader: io.LimitReader(r, int64(chunk.Size)), format: format, size: int64(chunk.Size)}, nil
+
+		default:
+			if err := skip(r, int64(chunk.Size)+int64(chunk.Size&1)); err != nil {
+				return nil, errors.Wrapf(err, "skipping %q chunk", chunk.ID[:])
+			}
+		}
+	}
+}
+
+// DecodeWAV decodes a whole 16 bit PCM WAV file into a buffer.
+func DecodeWAV(r io.Reader) (*Buffer, error) {
+	s, err := NewWAVDecoder(r)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ReadBuffer(s)
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding wav")
+	}
+	return b, nil
+}
+
+// skip discards n bytes from r.
+func skip(r io.Reader, n int64) error {
+	_, err := io.CopyN(io.Discard, r, n)
+	return err
+}
+
+// toInt16 converts a sample to 16 bit, clipping it to the valid range.
+func toInt16(s float32) int16 {
+	v := math.Round(float64(s) * 32768)
+	switch {
+	case v > math.MaxInt16:
+		return math.MaxInt16
+	case v < math.MinInt16:
+		return math.MinInt16
+	}
+	return int16(v)
+}
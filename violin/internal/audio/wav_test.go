@@ -0,0 +1,79 @@
+package audio
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// testSignal returns a buffer of tones and a little noise, quantized to 16
+// bits so that encoding it loses nothing.
+func testSignal(sampleRate, channels, frames int) *Buffer {
+	rnd := rand.New(rand.NewSource(1))
+	b := NewBuffer(sampleRate, channels, frames)
+	for f := 0; f < frames; f++ {
+		for c := 0; c < channels; c++ {
+			freq := 440 * float64(c+1)
+			v := 0.5*math.Sin(2*math.Pi*freq*float64(f)/float64(sampleRate)) + 0.01*rnd.NormFloat64()
+			b.Samples[f*channels+c] = float32(toInt16(float32(v))) / 32768
+		}
+	}
+	return b
+}
+
+func TestWAVRoundTrip(t *testing.T) {
+	tests := []struct {
+		name                     string
+		sampleRate, channels, fr int
+	}{
+		{"mono", 22050, 1, 1000},
+		{"stereo", 44100, 2, 12345},
+		{"empty", 48000, 2, 0},
+	}
+	for _, tt := range tests {
+		in := testSignal(tt.sampleRate, tt.channels, tt.fr)
+
+		var wav bytes.Buffer
+		if err := EncodeWAV(&wav, in); err != nil {
+			t.Fatalf("%s: encoding: %v", tt.name, err)
+		}
+		if got, want := wav.Len(), 44+2*len(in.Samples); got != want {
+			t.Errorf("%s: file is %d bytes, want %d", tt.name, got, want)
+		}
+
+		out, err := DecodeWAV(&wav)
+		if err != nil {
+			t.Fatalf("%s: decoding: %v", tt.name, err)
+		}
+		if out.SampleRate != tt.sampleRate || out.Channels != tt.channels {
+			t.Errorf("%s: decoded %d Hz with %d channels, want %d Hz with %d", tt.name, out.SampleRate, out.Channels, tt.sampleRate, tt.channels)
+		}
+		if out.Frames() != tt.fr {
+			t.Fatalf("%s: decoded %d frames, want %d", tt.name, out.Frames(), tt.fr)
+		}
+		for i := range in.Samples {
+			if out.Samples[i] != in.Samples[i] {
+				t.Fatalf("%s: sample %d is %v, want %v", tt.name, i, out.Samples[i], in.Samples[i])
+			}
+		}
+	}
+}
+
+func TestWAVUnknownLength(t *testing.T) {
+	in := testSignal(44100, 2, 5000)
+
+	// A stream that does not know its length is written with open chunk
+	// sizes, and read until it ends.
+	var wav bytes.Buffer
+	if err := WriteWAV(&wav, NewStream(in.Stream(), Format{SampleRate: 44100, Channels: 2})); err != nil {
+		t.Fatal(err)
+	}
+	out, err := DecodeWAV(&wav)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Frames() != in.Frames() {
+		t.Errorf("decoded %d frames, want %d", out.Frames(), in.Frames())
+	}
+}
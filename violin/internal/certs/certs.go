@@ -0,0 +1,63 @@
+// Package certs loads the TLS certificate GoViolin serves HTTPS with, and
+// generates a self-signed one for development.
+package certs
+
+import (
+	"crypto/tls"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Reloader holds the certificate loaded from a pair of PEM files and loads
+// it again on request, so a renewed certificate is served without
+// restarting the server. It is safe for concurrent use.
+type Reloader struct {
+	certFile string
+	keyFile  string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+// NewReloader constructs a Reloader serving the certificate and private key
+// in the PEM files at certFile and keyFile.
+func NewReloader(certFile, keyFile string) (*Reloader, error) {
+	r := Reloader{certFile: certFile, keyFile: keyFile}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Reload loads the certificate files again. When they cannot be loaded the
+// certificate already loaded stays in use.
+func (r *Reloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return errors.Wrapf(err, "loading certificate %s", r.certFile)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	return nil
+}
+
+// GetCertificate returns the certificate loaded last. It is meant for
+// tls.Config.GetCertificate.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// Config returns a TLS configuration serving the certificate loaded last,
+// offering HTTP/2 ahead of HTTP/1.1.
+func (r *Reloader) Config() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}
+}
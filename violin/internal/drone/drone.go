@@ -0,0 +1,164 @@
+// Package drone builds the sustained drone notes students tune against:
+// a single tonic, an open fifth or a tonic with its third, in any key and
+// for as long as needed.
+package drone
+
+import (
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"violin/internal/audio"
+	"violin/internal/theory"
+
+	"github.com/pkg/errors"
+)
+
+// Voicings a drone can have.
+const (
+	VoicingSingle = "single"
+	VoicingFifth  = "fifth"
+	VoicingThird  = "third"
+)
+
+// lowestNote is the MIDI note of the lowest recorded drone, the G3 of
+// mp3/drone/g1.mp3. Recordings numbered 1 run from G3 up to F#4 and each
+// higher number is an octave above.
+const lowestNote = 55
+
+// fade is how long a drone takes to fade in and out.
+const fade = time.Second
+
+// crossfade is the length of the blend at the join of a loop.
+const crossfade = 250 * time.Millisecond
+
+// ErrInvalid is returned for unknown keys, pitches, octaves or voicings.
+var ErrInvalid = errors.New("invalid drone")
+
+// Recording is a recorded drone note.
+type Recording struct {
+	Path string
+	Note int // MIDI note number
+}
+
+// Library returns the drone recordings among the asset paths, whose names
+// are a note and a number such as "mp3/drone/cs1.mp3" for C#3 and
+// "mp3/drone/g3.mp3" for G5. Other paths are ignored.
+func Library(paths []string) []Recording {
+	var lib []Recording
+	for _, p := range paths {
+		if !strings.HasPrefix(p, "mp3/drone/") || path.Ext(p) != ".mp3" {
+			continue
+		}
+		name := strings.TrimSuffix(path.Base(p), ".mp3")
+		if len(name) < 2 {
+			continue
+		}
+
+		octave, err := strconv.Atoi(name[len(name)-1:])
+		if err != nil || octave < 1 {
+			continue
+		}
+		note := strings.Replace(name[:len(name)-1], "s", "#", 1)
+		pc, err := theory.PitchClass(note)
+		if err != nil {
+			continue
+		}
+		lib = append(lib, Recording{Path: p, Note: noteNumber(pc, octave)})
+	}
+
+	sort.Slice(lib, func(i, j int) bool {
+		if lib[i].Note != lib[j].Note {
+			return lib[i].Note < lib[j].Note
+		}
+		return lib[i].Path < lib[j].Path
+	})
+	return lib
+}
+
+// Notes returns the MIDI notes of a drone on the key, such as "C#/Db", in
+// the octave of the scale it goes with. Pitch is "Major" or "Minor" and
+// picks the third.
+func Notes(key, pitch string, octave int, voicing string) ([]int, error) {
+	if i := strings.Index(key, "/"); i >= 0 {
+		key = key[:i]
+	}
+	pc, err := theory.PitchClass(key)
+	if err != nil || octave < 1 || octave > 3 {
+		return nil, ErrInvalid
+	}
+	tonic := noteNumber(pc, octave)
+
+	switch voicing {
+	case VoicingSingle, "":
+		return []int{tonic}, nil
+	case VoicingFifth:
+		return []int{tonic, tonic + 7}, nil
+	case VoicingThird:
+		switch pitch {
+		case "Major":
+			return []int{tonic, tonic + 4}, nil
+		case "Minor":
+			return []int{tonic, tonic + 3}, nil
+		}
+	}
+	return nil, ErrInvalid
+}
+
+// Closest returns the recording nearest to the note and how many semitones
+// it must be moved to match it.
+func Closest(lib []Recording, note int) (Recording, int, error) {
+	if len(lib) == 0 {
+		return Recording{}, 0, errors.New("no drone recordings")
+	}
+	best := lib[0]
+	for _, r := range lib[1:] {
+		if abs(r.Note-note) < abs(best.Note-note) {
+			best = r
+		}
+	}
+	return best, note - best.Note, nil
+}
+
+// Build returns a stream of the notes sounding together for the duration.
+// Each note is cut into a seamless loop from the closest recording, which
+// load decodes, transposed when no recording has the exact pitch.
+func Build(lib []Recording, notes []int, duration time.Duration, load func(path string) (*audio.Buffer, error)) (audio.SeekStream, error) {
+	var loops []*audio.Buffer
+	for _, note := range notes {
+		rec, shift, err := Closest(lib, note)
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := load(rec.Path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "loading %s", rec.Path)
+		}
+		if b, err = audio.Transpose(b, float64(shift)); err != nil {
+			return nil, errors.Wrapf(err, "transposing %s", rec.Path)
+		}
+		loop, err := audio.Loop(b, crossfade)
+		if err != nil {
+			return nil, errors.Wrapf(err, "looping %s", rec.Path)
+		}
+		loops = append(loops, loop)
+	}
+
+	return audio.Repeat(loops, duration, fade)
+}
+
+// noteNumber returns the MIDI note of a pitch class in a recording octave.
+func noteNumber(pc, octave int) int {
+	return lowestNote + (pc-lowestNote%12+12)%12 + 12*(octave-1)
+}
+
+// abs returns the absolute value of n.
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
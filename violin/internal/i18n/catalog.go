@@ -0,0 +1,883 @@
+package i18n
+
+// catalogs maps each language onto its translations of the English
+// messages. Messages with a format take the same arguments in every
+// language.
+var catalogs = map[string]map[string]string{
+	German: {
+		// Navigation
+		"Home":               "Startseite",
+		"Scales & Arpeggios": "Tonleitern & Arpeggien",
+		"Duets":              "Duette",
+		"Syllabus":           "Prüfungsstoff",
+		"Today's Practice":   "Heutiges Üben",
+		"Ear Training":       "Gehörbildung",
+		"Sight-Reading":      "Vom-Blatt-Spiel",
+		"Keys":               "Tonarten",
+		"Progress":           "Fortschritt",
+		"Studio":             "Studio",
+		"Settings":           "Einstellungen",
+		"Log In":             "Anmelden",
+		"Sign Up":            "Registrieren",
+		// Page titles and headings
+		"Practice Scales and Arpeggios": "Tonleitern und Arpeggien üben",
+		"Practice Scales & Arpeggios":   "Tonleitern & Arpeggien üben",
+		"Practice Duets":                "Duette üben",
+		"Exam Syllabus":                 "Prüfungsanforderungen",
+		"Practice Progress":             "Übefortschritt",
+		// Introductions
+		"GoViolin is a helpful way to practice violin written in Go.":                                                                                                                         "GoViolin hilft dir beim Geigeüben und ist in Go geschrieben.",
+		"Listen to any scale or arpeggio with a few mouse clicks.":                                                                                                                            "Höre dir jede Tonleiter und jedes Arpeggio mit wenigen Klicks an.",
+		"Play along to improve your intonation.":                                                                                                                                              "Spiele mit, um deine Intonation zu verbessern.",
+		"Improve your intonation by practicing scales and arpeggios":                                                                                                                          "Verbessere deine Intonation mit Tonleitern und Arpeggien",
+		"Franz Wohlfahrt (7 March 1833 - 14 February 1884) was a violin teacher in Leipzig Germany. He wrote the following duets around scales.":                                              "Franz Wohlfahrt (7. März 1833 - 14. Februar 1884) war Geigenlehrer in Leipzig. Er schrieb die folgenden Duette über Tonleitern.",
+		"Scales and arpeggios required for each grade of the major exam boards.":                                                                                                              "Tonleitern und Arpeggien, die jede Stufe der großen Prüfungsinstitute verlangt.",
+		"Work through today's items and rate how each one went. Items you find hard come back sooner.":                                                                                        "Arbeite die heutigen Übungen durch und bewerte, wie jede lief. Was dir schwerfällt, kommt früher wieder.",
+		"Train your ear to hear intervals, scale types and tuning. Questions get harder as you get them right.":                                                                               "Schule dein Gehör für Intervalle, Tonleiterarten und Stimmung. Die Fragen werden schwerer, je mehr du richtig beantwortest.",
+		"Read a new melody every day. Choose a key and a level, or pick the range, leaps and rhythms yourself.":                                                                               "Lies jeden Tag eine neue Melodie. Wähle Tonart und Stufe oder stelle Umfang, Sprünge und Rhythmen selbst ein.",
+		"Log in to keep your practice log with your account. Practice logged on this browser before you log in is kept.":                                                                      "Melde dich an, um dein Übetagebuch in deinem Konto zu speichern. Was du vorher in diesem Browser geübt hast, bleibt erhalten.",
+		"Create an account to keep your practice log across browsers. Practice logged on this browser so far is kept.":                                                                        "Erstelle ein Konto, um dein Übetagebuch in allen Browsern zu nutzen. Was du bisher in diesem Browser geübt hast, bleibt erhalten.",
+		"Invite your students, set their weekly work and see how much they have practiced.":                                                                                                   "Lade deine Schüler ein, gib ihnen wöchentliche Aufgaben und sieh, wie viel sie geübt haben.",
+		"Join your teacher's studio and keep track of the work they set you.":                                                                                                                 "Tritt dem Studio deiner Lehrkraft bei und behalte die gestellten Aufgaben im Blick.",
+		"Each step clockwise round the circle of fifths adds a sharp, each step anticlockwise a flat. Minor keys share the signature of their relative major. Click a key to hear its scale.": "Jeder Schritt im Uhrzeigersinn um den Quintenzirkel fügt ein Kreuz hinzu, jeder Schritt gegen den Uhrzeigersinn ein B. Molltonarten teilen die Vorzeichen ihrer Dur-Paralleltonart. Klicke auf eine Tonart, um ihre Tonleiter zu hören.",
+		"Choose the language of the pages and how note names are written.":                                                                                                                    "Wähle die Sprache der Seiten und wie Notennamen geschrieben werden.",
+		// Scale page options
+		"Scales":                "Tonleitern",
+		"Arpeggios":             "Arpeggien",
+		"1st Inversions":        "1. Umkehrungen",
+		"2nd Inversions":        "2. Umkehrungen",
+		"Dominant 7ths":         "Dominantseptakkorde",
+		"Diminished 7ths":       "Verminderte Septakkorde",
+		"Augmented":             "Übermäßig",
+		"Thirds":                "Terzen",
+		"Sixths":                "Sexten",
+		"Octaves":               "Oktaven",
+		"Fingered Octaves":      "Fingersatzoktaven",
+		"Tenths":                "Dezimen",
+		"Major":                 "Dur",
+		"Minor":                 "Moll",
+		"1 Octave":              "1 Oktave",
+		"2 Octave":              "2 Oktaven",
+		"3 Octave":              "3 Oktaven",
+		"Spelling":              "Schreibweise",
+		"Rhythm":                "Rhythmus",
+		"Drone":                 "Bordunton",
+		"Metronome":             "Metronom",
+		"Loop":                  "Wiederholen",
+		"Follow the notes":      "Noten mitverfolgen",
+		"Download MIDI":         "MIDI herunterladen",
+		"Download MusicXML":     "MusicXML herunterladen",
+		"Link to this exercise": "Link zu dieser Übung",
+		"Pin to daily practice": "Zum täglichen Üben anheften",
+		// Music labels
+		"Listen to %s":                    "%s anhören",
+		"%s with metronome":               "%s mit Metronom",
+		"Harmonic Minor Scale":            "Harmonische Moll-Tonleiter",
+		"Melodic Minor Scale":             "Melodische Moll-Tonleiter",
+		"Major Scale":                     "Dur-Tonleiter",
+		"Minor Scale":                     "Moll-Tonleiter",
+		"Major Arpeggio":                  "Dur-Arpeggio",
+		"Minor Arpeggio":                  "Moll-Arpeggio",
+		"Major Arpeggio, 1st Inversion":   "Dur-Arpeggio, 1. Umkehrung",
+		"Minor Arpeggio, 1st Inversion":   "Moll-Arpeggio, 1. Umkehrung",
+		"Major Arpeggio, 2nd Inversion":   "Dur-Arpeggio, 2. Umkehrung",
+		"Minor Arpeggio, 2nd Inversion":   "Moll-Arpeggio, 2. Umkehrung",
+		"Dominant 7th":                    "Dominantseptakkord",
+		"Diminished 7th":                  "Verminderter Septakkord",
+		"Augmented Arpeggio":              "Übermäßiges Arpeggio",
+		"Major Scale in Thirds":           "Dur-Tonleiter in Terzen",
+		"Minor Scale in Thirds":           "Moll-Tonleiter in Terzen",
+		"Major Scale in Sixths":           "Dur-Tonleiter in Sexten",
+		"Minor Scale in Sixths":           "Moll-Tonleiter in Sexten",
+		"Major Scale in Octaves":          "Dur-Tonleiter in Oktaven",
+		"Minor Scale in Octaves":          "Moll-Tonleiter in Oktaven",
+		"Major Scale in Fingered Octaves": "Dur-Tonleiter in Fingersatzoktaven",
+		"Minor Scale in Fingered Octaves": "Moll-Tonleiter in Fingersatzoktaven",
+		"Major Scale in Tenths":           "Dur-Tonleiter in Dezimen",
+		"Minor Scale in Tenths":           "Moll-Tonleiter in Dezimen",
+		// Key reference
+		"Circle of Fifths":   "Quintenzirkel",
+		"Signature":          "Vorzeichen",
+		"Sharps or flats":    "Kreuze oder Bs",
+		"%s major":           "%s-Dur",
+		"%s minor":           "%s-Moll",
+		"No sharps or flats": "Keine Vorzeichen",
+		"1 sharp":            "1 Kreuz",
+		"%d sharps":          "%d Kreuze",
+		"1 flat":             "1 B",
+		"%d flats":           "%d Bs",
+		"none":               "keine",
+		// Settings page
+		"Language":                    "Sprache",
+		"Note names":                  "Notennamen",
+		"Automatic":                   "Automatisch",
+		"Letter names (C D E)":        "Buchstaben (C D E)",
+		"German names (C D E … H)":    "Deutsche Namen (C D E … H)",
+		"Fixed-do solfège (Do Ré Mi)": "Feste Solmisation (Do Ré Mi)",
+		"Save":                        "Speichern",
+		// Offline use
+		"Keep %s offline":                "%s offline verfügbar halten",
+		"Saving for offline use…":        "Wird für die Offline-Nutzung gespeichert…",
+		"Available offline":              "Offline verfügbar",
+		"Could not save for offline use": "Konnte nicht für die Offline-Nutzung gespeichert werden",
+		// Players
+		"Your browser does not support the audio element.": "Dein Browser unterstützt das Audio-Element nicht.",
+		// Accounts and progress
+		"Name":                     "Name",
+		"Password":                 "Passwort",
+		"No account yet?":          "Noch kein Konto?",
+		"Already have an account?": "Schon ein Konto?",
+		"Logged in as %s.":         "Angemeldet als %s.",
+		"Log Out":                  "Abmelden",
+		"Your practice is logged on this browser. Log in or sign up to keep it with your account.": "Dein Üben wird in diesem Browser aufgezeichnet. Melde dich an oder registriere dich, um es in deinem Konto zu speichern.",
+		"Total: %d min":           "Gesamt: %d Min.",
+		"Current streak: %d days": "Aktuelle Serie: %d Tage",
+		"Longest streak: %d days": "Längste Serie: %d Tage",
+		"%d min":                  "%d Min.",
+		"Week":                    "Woche",
+		"Day":                     "Tag",
+		"Item":                    "Übung",
+		"Minutes":                 "Minuten",
+		"No practice logged yet":  "Noch nichts geübt",
+		// Daily practice
+		"pinned":                           "angeheftet",
+		"new":                              "neu",
+		"%d min today":                     "heute %d Min.",
+		"Nothing to practice today.":       "Heute gibt es nichts zu üben.",
+		"All done for today, well played!": "Alles erledigt für heute, gut gespielt!",
+		"How did it go?":                   "Wie lief es?",
+		"Couldn't play it":                 "Ging gar nicht",
+		"Poor":                             "Schlecht",
+		"Shaky":                            "Wackelig",
+		"Passable":                         "Passabel",
+		"Good":                             "Gut",
+		"Perfect":                          "Perfekt",
+		"Unpin from daily practice":        "Vom täglichen Üben lösen",
+		// Studio
+		"New invite code": "Neuer Einladungscode",
+		"until %s":        "bis %s",
+		"Students":        "Schüler",
+		"Set work":        "Aufgaben stellen",
+		"Student":         "Schüler",
+		"Choose an item":  "Übung wählen",
+		"target bpm":      "Ziel-BPM",
+		"Due":             "Fällig",
+		"Notes":           "Hinweise",
+		"Assign":          "Zuweisen",
+		"No students yet. Give them an invite code to join.": "Noch keine Schüler. Gib ihnen einen Einladungscode zum Beitreten.",
+		"Assignments":                 "Aufgaben",
+		"Work":                        "Aufgabe",
+		"Practiced":                   "Geübt",
+		"at %d bpm":                   "mit %d BPM",
+		"Done":                        "Erledigt",
+		"Overdue":                     "Überfällig",
+		"overdue":                     "überfällig",
+		"New studio":                  "Neues Studio",
+		"Studio name":                 "Name des Studios",
+		"Create":                      "Erstellen",
+		"Member of %s.":               "Mitglied von %s.",
+		"Member of %s, taught by %s.": "Mitglied von %s, unterrichtet von %s.",
+		"Invite code":                 "Einladungscode",
+		"Join studio":                 "Studio beitreten",
+		"Reopen":                      "Wieder öffnen",
+		"Mark as done":                "Als erledigt markieren",
+		"No work set yet.":            "Noch keine Aufgaben gestellt.",
+		// Duets
+		"Play-along mixer":     "Mitspiel-Mischpult",
+		"Volume":               "Lautstärke",
+		"Pan":                  "Panorama",
+		"Mute":                 "Stumm",
+		"Part 1":               "Stimme 1",
+		"Part 2":               "Stimme 2",
+		"Tempo":                "Tempo",
+		"Mix":                  "Mischen",
+		"Listen to both parts": "Beide Stimmen anhören",
+		"Listen to part 1":     "Stimme 1 anhören",
+		"Listen to part 2":     "Stimme 2 anhören",
+		"Listen to your mix":   "Deine Mischung anhören",
+		"(mixing…)":            "(wird gemischt…)",
+		"(the mixer is busy…)": "(das Mischpult ist beschäftigt…)",
+		"(the mix failed)":     "(das Mischen ist fehlgeschlagen)",
+		// Ear training, syllabus and sight-reading
+		"level %d":                       "Stufe %d",
+		"Level":                          "Stufe",
+		"Answered":                       "Beantwortet",
+		"Right":                          "Richtig",
+		"Requirement":                    "Anforderung",
+		"Bowing":                         "Bogenführung",
+		"Audio":                          "Audio",
+		"Notation":                       "Notation",
+		"Recorded":                       "Aufgenommen",
+		"Synthesized":                    "Synthetisiert",
+		"Image":                          "Bild",
+		"Drawn":                          "Gezeichnet",
+		"Missing":                        "Fehlt",
+		"Not yet available: no audio":    "Noch nicht verfügbar: kein Audio",
+		"Not yet available: no notation": "Noch nicht verfügbar: keine Notation",
+		"Not yet available: no audio or notation": "Noch nicht verfügbar: weder Audio noch Notation",
+		"Pin this grade to daily practice":        "Diese Stufe zum täglichen Üben anheften",
+		"bars":                                    "Takte",
+		"Range":                                   "Umfang",
+		"Largest leap":                            "Größter Sprung",
+		"Rhythms":                                 "Rhythmen",
+		"New melody":                              "Neue Melodie",
+		"Link to this melody":                     "Link zu dieser Melodie",
+	},
+	French: {
+		// Navigation
+		"Home":               "Accueil",
+		"Scales & Arpeggios": "Gammes et arpèges",
+		"Duets":              "Duos",
+		"Syllabus":           "Programme",
+		"Today's Practice":   "Travail du jour",
+		"Ear Training":       "Formation de l'oreille",
+		"Sight-Reading":      "Déchiffrage",
+		"Keys":               "Tonalités",
+		"Progress":           "Progrès",
+		"Studio":             "Studio",
+		"Settings":           "Réglages",
+		"Log In":             "Connexion",
+		"Sign Up":            "Inscription",
+		// Page titles and headings
+		"Practice Scales and Arpeggios": "Travailler les gammes et arpèges",
+		"Practice Scales & Arpeggios":   "Travailler les gammes et arpèges",
+		"Practice Duets":                "Travailler les duos",
+		"Exam Syllabus":                 "Programme d'examen",
+		"Practice Progress":             "Progrès du travail",
+		// Introductions
+		"GoViolin is a helpful way to practice violin written in Go.":                                                                                                                         "GoViolin vous aide à travailler le violon et est écrit en Go.",
+		"Listen to any scale or arpeggio with a few mouse clicks.":                                                                                                                            "Écoutez n'importe quelle gamme ou arpège en quelques clics.",
+		"Play along to improve your intonation.":                                                                                                                                              "Jouez en même temps pour améliorer votre justesse.",
+		"Improve your intonation by practicing scales and arpeggios":                                                                                                                          "Améliorez votre justesse en travaillant gammes et arpèges",
+		"Franz Wohlfahrt (7 March 1833 - 14 February 1884) was a violin teacher in Leipzig Germany. He wrote the following duets around scales.":                                              "Franz Wohlfahrt (7 mars 1833 - 14 février 1884) était professeur de violon à Leipzig, en Allemagne. Il a écrit les duos suivants autour des gammes.",
+		"Scales and arpeggios required for each grade of the major exam boards.":                                                                                                              "Les gammes et arpèges demandés à chaque niveau par les principaux organismes d'examen.",
+		"Work through today's items and rate how each one went. Items you find hard come back sooner.":                                                                                        "Travaillez les exercices du jour et notez comment chacun s'est passé. Ceux qui vous posent problème reviennent plus tôt.",
+		"Train your ear to hear intervals, scale types and tuning. Questions get harder as you get them right.":                                                                               "Entraînez votre oreille aux intervalles, aux types de gammes et à la justesse. Les questions deviennent plus difficiles à mesure que vous réussissez.",
+		"Read a new melody every day. Choose a key and a level, or pick the range, leaps and rhythms yourself.":                                                                               "Lisez une nouvelle mélodie chaque jour. Choisissez une tonalité et un niveau, ou réglez vous-même l'étendue, les sauts et les rythmes.",
+		"Log in to keep your practice log with your account. Practice logged on this browser before you log in is kept.":                                                                      "Connectez-vous pour conserver votre journal de travail dans votre compte. Le travail enregistré sur ce navigateur avant la connexion est conservé.",
+		"Create an account to keep your practice log across browsers. Practice logged on this browser so far is kept.":                                                                        "Créez un compte pour retrouver votre journal de travail sur tous vos navigateurs. Le travail déjà enregistré sur ce navigateur est conservé.",
+		"Invite your students, set their weekly work and see how much they have practiced.":                                                                                                   "Invitez vos élèves, donnez-leur le travail de la semaine et voyez combien ils ont travaillé.",
+		"Join your teacher's studio and keep track of the work they set you.":                                                                                                                 "Rejoignez le studio de votre professeur et suivez le travail qui vous est donné.",
+		"Each step clockwise round the circle of fifths adds a sharp, each step anticlockwise a flat. Minor keys share the signature of their relative major. Click a key to hear its scale.": "Chaque pas dans le sens horaire sur le cycle des quintes ajoute un dièse, chaque pas dans le sens inverse un bémol. Les tonalités mineures partagent l'armure de leur relatif majeur. Cliquez sur une tonalité pour entendre sa gamme.",
+		"Choose the language of the pages and how note names are written.":                                                                                                                    "Choisissez la langue des pages et la façon d'écrire le nom des notes.",
+		// Scale page options
+		"Scales":                "Gammes",
+		"Arpeggios":             "Arpèges",
+		"1st Inversions":        "1ers renversements",
+		"2nd Inversions":        "2es renversements",
+		"Dominant 7ths":         "Septièmes de dominante",
+		"Diminished 7ths":       "Septièmes diminuées",
+		"Augmented":             "Augmentés",
+		"Thirds":                "Tierces",
+		"Sixths":                "Sixtes",
+		"Octaves":               "Octaves",
+		"Fingered Octaves":      "Octaves doigtées",
+		"Tenths":                "Dixièmes",
+		"Major":                 "Majeur",
+		"Minor":                 "Mineur",
+		"1 Octave":              "1 octave",
+		"2 Octave":              "2 octaves",
+		"3 Octave":              "3 octaves",
+		"Spelling":              "Orthographe",
+		"Rhythm":                "Rythme",
+		"Drone":                 "Bourdon",
+		"Metronome":             "Métronome",
+		"Loop":                  "En boucle",
+		"Follow the notes":      "Suivre les notes",
+		"Download MIDI":         "Télécharger le MIDI",
+		"Download MusicXML":     "Télécharger le MusicXML",
+		"Link to this exercise": "Lien vers cet exercice",
+		"Pin to daily practice": "Épingler au travail quotidien",
+		// Music labels
+		"Listen to %s":                    "Écouter : %s",
+		"%s with metronome":               "%s avec métronome",
+		"Harmonic Minor Scale":            "Gamme mineure harmonique",
+		"Melodic Minor Scale":             "Gamme mineure mélodique",
+		"Major Scale":                     "Gamme majeure",
+		"Minor Scale":                     "Gamme mineure",
+		"Major Arpeggio":                  "Arpège majeur",
+		"Minor Arpeggio":                  "Arpège mineur",
+		"Major Arpeggio, 1st Inversion":   "Arpège majeur, 1er renversement",
+		"Minor Arpeggio, 1st Inversion":   "Arpège mineur, 1er renversement",
+		"Major Arpeggio, 2nd Inversion":   "Arpège majeur, 2e renversement",
+		"Minor Arpeggio, 2nd Inversion":   "Arpège mineur, 2e renversement",
+		"Dominant 7th":                    "Septième de dominante",
+		"Diminished 7th":                  "Septième diminuée",
+		"Augmented Arpeggio":              "Arpège augmenté",
+		"Major Scale in Thirds":           "Gamme majeure en tierces",
+		"Minor Scale in Thirds":           "Gamme mineure en tierces",
+		"Major Scale in Sixths":           "Gamme majeure en sixtes",
+		"Minor Scale in Sixths":           "Gamme mineure en sixtes",
+		"Major Scale in Octaves":          "Gamme majeure en octaves",
+		"Minor Scale in Octaves":          "Gamme mineure en octaves",
+		"Major Scale in Fingered Octaves": "Gamme majeure en octaves doigtées",
+		"Minor Scale in Fingered Octaves": "Gamme mineure en octaves doigtées",
+		"Major Scale in Tenths":           "Gamme majeure en dixièmes",
+		"Minor Scale in Tenths":           "Gamme mineure en dixièmes",
+		// Key reference
+		"Circle of Fifths":   "Cycle des quintes",
+		"Signature":          "Armure",
+		"Sharps or flats":    "Dièses ou bémols",
+		"%s major":           "%s majeur",
+		"%s minor":           "%s mineur",
+		"No sharps or flats": "Sans altération",
+		"1 sharp":            "1 dièse",
+		"%d sharps":          "%d dièses",
+		"1 flat":             "1 bémol",
+		"%d flats":           "%d bémols",
+		"none":               "aucune",
+		// Settings page
+		"Language":                    "Langue",
+		"Note names":                  "Noms des notes",
+		"Automatic":                   "Automatique",
+		"Letter names (C D E)":        "Lettres (C D E)",
+		"German names (C D E … H)":    "Noms allemands (C D E … H)",
+		"Fixed-do solfège (Do Ré Mi)": "Solfège à do fixe (Do Ré Mi)",
+		"Save":                        "Enregistrer",
+		// Offline use
+		"Keep %s offline":                "Garder %s hors ligne",
+		"Saving for offline use…":        "Enregistrement pour une utilisation hors ligne…",
+		"Available offline":              "Disponible hors ligne",
+		"Could not save for offline use": "Impossible d'enregistrer pour une utilisation hors ligne",
+		// Players
+		"Your browser does not support the audio element.": "Votre navigateur ne prend pas en charge l'élément audio.",
+		// Accounts and progress
+		"Name":                     "Nom",
+		"Password":                 "Mot de passe",
+		"No account yet?":          "Pas encore de compte ?",
+		"Already have an account?": "Vous avez déjà un compte ?",
+		"Logged in as %s.":         "Connecté en tant que %s.",
+		"Log Out":                  "Déconnexion",
+		"Your practice is logged on this browser. Log in or sign up to keep it with your account.": "Votre travail est enregistré sur ce navigateur. Connectez-vous ou inscrivez-vous pour le conserver dans votre compte.",
+		"Total: %d min":           "Total : %d min",
+		"Current streak: %d days": "Série en cours : %d jours",
+		"Longest streak: %d days": "Plus longue série : %d jours",
+		"%d min":                  "%d min",
+		"Week":                    "Semaine",
+		"Day":                     "Jour",
+		"Item":                    "Exercice",
+		"Minutes":                 "Minutes",
+		"No practice logged yet":  "Aucun travail enregistré",
+		// Daily practice
+		"pinned":                           "épinglé",
+		"new":                              "nouveau",
+		"%d min today":                     "%d min aujourd'hui",
+		"Nothing to practice today.":       "Rien à travailler aujourd'hui.",
+		"All done for today, well played!": "Tout est fait pour aujourd'hui, bien joué !",
+		"How did it go?":                   "Comment ça s'est passé ?",
+		"Couldn't play it":                 "Impossible à jouer",
+		"Poor":                             "Mauvais",
+		"Shaky":                            "Hésitant",
+		"Passable":                         "Passable",
+		"Good":                             "Bien",
+		"Perfect":                          "Parfait",
+		"Unpin from daily practice":        "Retirer du travail quotidien",
+		// Studio
+		"New invite code": "Nouveau code d'invitation",
+		"until %s":        "jusqu'au %s",
+		"Students":        "Élèves",
+		"Set work":        "Donner du travail",
+		"Student":         "Élève",
+		"Choose an item":  "Choisir un exercice",
+		"target bpm":      "BPM visé",
+		"Due":             "À rendre le",
+		"Notes":           "Remarques",
+		"Assign":          "Attribuer",
+		"No students yet. Give them an invite code to join.": "Pas encore d'élèves. Donnez-leur un code d'invitation pour rejoindre le studio.",
+		"Assignments":                 "Devoirs",
+		"Work":                        "Travail",
+		"Practiced":                   "Travaillé",
+		"at %d bpm":                   "à %d BPM",
+		"Done":                        "Fait",
+		"Overdue":                     "En retard",
+		"overdue":                     "en retard",
+		"New studio":                  "Nouveau studio",
+		"Studio name":                 "Nom du studio",
+		"Create":                      "Créer",
+		"Member of %s.":               "Membre de %s.",
+		"Member of %s, taught by %s.": "Membre de %s, avec %s comme professeur.",
+		"Invite code":                 "Code d'invitation",
+		"Join studio":                 "Rejoindre le studio",
+		"Reopen":                      "Rouvrir",
+		"Mark as done":                "Marquer comme fait",
+		"No work set yet.":            "Aucun travail donné pour l'instant.",
+		// Duets
+		"Play-along mixer":     "Mixeur d'accompagnement",
+		"Volume":               "Volume",
+		"Pan":                  "Panoramique",
+		"Mute":                 "Muet",
+		"Part 1":               "Partie 1",
+		"Part 2":               "Partie 2",
+		"Tempo":                "Tempo",
+		"Mix":                  "Mixer",
+		"Listen to both parts": "Écouter les deux parties",
+		"Listen to part 1":     "Écouter la partie 1",
+		"Listen to part 2":     "Écouter la partie 2",
+		"Listen to your mix":   "Écouter votre mixage",
+		"(mixing…)":            "(mixage…)",
+		"(the mixer is busy…)": "(le mixeur est occupé…)",
+		"(the mix failed)":     "(le mixage a échoué)",
+		// Ear training, syllabus and sight-reading
+		"level %d":                       "niveau %d",
+		"Level":                          "Niveau",
+		"Answered":                       "Répondues",
+		"Right":                          "Réussite",
+		"Requirement":                    "Exigence",
+		"Bowing":                         "Coup d'archet",
+		"Audio":                          "Audio",
+		"Notation":                       "Partition",
+		"Recorded":                       "Enregistré",
+		"Synthesized":                    "Synthétisé",
+		"Image":                          "Image",
+		"Drawn":                          "Dessinée",
+		"Missing":                        "Manquant",
+		"Not yet available: no audio":    "Pas encore disponible : pas d'audio",
+		"Not yet available: no notation": "Pas encore disponible : pas de partition",
+		"Not yet available: no audio or notation": "Pas encore disponible : ni audio ni partition",
+		"Pin this grade to daily practice":        "Épingler ce niveau au travail quotidien",
+		"bars":                                    "mesures",
+		"Range":                                   "Étendue",
+		"Largest leap":                            "Plus grand saut",
+		"Rhythms":                                 "Rythmes",
+		"New melody":                              "Nouvelle mélodie",
+		"Link to this melody":                     "Lien vers cette mélodie",
+	},
+	Spanish: {
+		// Navigation
+		"Home":               "Inicio",
+		"Scales & Arpeggios": "Escalas y arpegios",
+		"Duets":              "Dúos",
+		"Syllabus":           "Programa",
+		"Today's Practice":   "Práctica de hoy",
+		"Ear Training":       "Entrenamiento auditivo",
+		"Sight-Reading":      "Lectura a primera vista",
+		"Keys":               "Tonalidades",
+		"Progress":           "Progreso",
+		"Studio":             "Estudio",
+		"Settings":           "Ajustes",
+		"Log In":             "Iniciar sesión",
+		"Sign Up":            "Registrarse",
+		// Page titles and headings
+		"Practice Scales and Arpeggios": "Practicar escalas y arpegios",
+		"Practice Scales & Arpeggios":   "Practicar escalas y arpegios",
+		"Practice Duets":                "Practicar dúos",
+		"Exam Syllabus":                 "Programa de examen",
+		"Practice Progress":             "Progreso de la práctica",
+		// Introductions
+		"GoViolin is a helpful way to practice violin written in Go.":                                                                                                                         "GoViolin es una forma útil de practicar violín, escrita en Go.",
+		"Listen to any scale or arpeggio with a few mouse clicks.":                                                                                                                            "Escucha cualquier escala o arpegio con unos pocos clics.",
+		"Play along to improve your intonation.":                                                                                                                                              "Toca a la vez para mejorar tu afinación.",
+		"Improve your intonation by practicing scales and arpeggios":                                                                                                                          "Mejora tu afinación practicando escalas y arpegios",
+		"Franz Wohlfahrt (7 March 1833 - 14 February 1884) was a violin teacher in Leipzig Germany. He wrote the following duets around scales.":                                              "Franz Wohlfahrt (7 de marzo de 1833 - 14 de febrero de 1884) fue profesor de violín en Leipzig, Alemania. Escribió los siguientes dúos sobre escalas.",
+		"Scales and arpeggios required for each grade of the major exam boards.":                                                                                                              "Escalas y arpegios que exige cada grado de los principales tribunales de examen.",
+		"Work through today's items and rate how each one went. Items you find hard come back sooner.":                                                                                        "Trabaja los ejercicios de hoy y valora cómo te ha ido cada uno. Los que te cuestan vuelven antes.",
+		"Train your ear to hear intervals, scale types and tuning. Questions get harder as you get them right.":                                                                               "Entrena tu oído para reconocer intervalos, tipos de escala y afinación. Las preguntas se vuelven más difíciles a medida que aciertas.",
+		"Read a new melody every day. Choose a key and a level, or pick the range, leaps and rhythms yourself.":                                                                               "Lee una melodía nueva cada día. Elige una tonalidad y un nivel, o escoge la extensión, los saltos y los ritmos.",
+		"Log in to keep your practice log with your account. Practice logged on this browser before you log in is kept.":                                                                      "Inicia sesión para guardar tu registro de práctica en tu cuenta. Se conserva la práctica registrada en este navegador antes de iniciar sesión.",
+		"Create an account to keep your practice log across browsers. Practice logged on this browser so far is kept.":                                                                        "Crea una cuenta para tener tu registro de práctica en cualquier navegador. Se conserva la práctica registrada hasta ahora en este navegador.",
+		"Invite your students, set their weekly work and see how much they have practiced.":                                                                                                   "Invita a tus alumnos, asígnales el trabajo semanal y mira cuánto han practicado.",
+		"Join your teacher's studio and keep track of the work they set you.":                                                                                                                 "Únete al estudio de tu docente y lleva el control del trabajo asignado.",
+		"Each step clockwise round the circle of fifths adds a sharp, each step anticlockwise a flat. Minor keys share the signature of their relative major. Click a key to hear its scale.": "Cada paso en el sentido de las agujas del reloj por el círculo de quintas añade un sostenido, cada paso en sentido contrario un bemol. Las tonalidades menores comparten la armadura de su relativa mayor. Haz clic en una tonalidad para escuchar su escala.",
+		"Choose the language of the pages and how note names are written.":                                                                                                                    "Elige el idioma de las páginas y cómo se escriben los nombres de las notas.",
+		// Scale page options
+		"Scales":                "Escalas",
+		"Arpeggios":             "Arpegios",
+		"1st Inversions":        "Primeras inversiones",
+		"2nd Inversions":        "Segundas inversiones",
+		"Dominant 7ths":         "Séptimas de dominante",
+		"Diminished 7ths":       "Séptimas disminuidas",
+		"Augmented":             "Aumentados",
+		"Thirds":                "Terceras",
+		"Sixths":                "Sextas",
+		"Octaves":               "Octavas",
+		"Fingered Octaves":      "Octavas digitadas",
+		"Tenths":                "Décimas",
+		"Major":                 "Mayor",
+		"Minor":                 "Menor",
+		"1 Octave":              "1 octava",
+		"2 Octave":              "2 octavas",
+		"3 Octave":              "3 octavas",
+		"Spelling":              "Grafía",
+		"Rhythm":                "Ritmo",
+		"Drone":                 "Bordón",
+		"Metronome":             "Metrónomo",
+		"Loop":                  "Repetir",
+		"Follow the notes":      "Seguir las notas",
+		"Download MIDI":         "Descargar MIDI",
+		"Download MusicXML":     "Descargar MusicXML",
+		"Link to this exercise": "Enlace a este ejercicio",
+		"Pin to daily practice": "Fijar en la práctica diaria",
+		// Music labels
+		"Listen to %s":                    "Escuchar: %s",
+		"%s with metronome":               "%s con metrónomo",
+		"Harmonic Minor Scale":            "Escala menor armónica",
+		"Melodic Minor Scale":             "Escala menor melódica",
+		"Major Scale":                     "Escala mayor",
+		"Minor Scale":                     "Escala menor",
+		"Major Arpeggio":                  "Arpegio mayor",
+		"Minor Arpeggio":                  "Arpegio menor",
+		"Major Arpeggio, 1st Inversion":   "Arpegio mayor, primera inversión",
+		"Minor Arpeggio, 1st Inversion":   "Arpegio menor, primera inversión",
+		"Major Arpeggio, 2nd Inversion":   "Arpegio mayor, segunda inversión",
+		"Minor Arpeggio, 2nd Inversion":   "Arpegio menor, segunda inversión",
+		"Dominant 7th":                    "Séptima de dominante",
+		"Diminished 7th":                  "Séptima disminuida",
+		"Augmented Arpeggio":              "Arpegio aumentado",
+		"Major Scale in Thirds":           "Escala mayor en terceras",
+		"Minor Scale in Thirds":           "Escala menor en terceras",
+		"Major Scale in Sixths":           "Escala mayor en sextas",
+		"Minor Scale in Sixths":           "Escala menor en sextas",
+		"Major Scale in Octaves":          "Escala mayor en octavas",
+		"Minor Scale in Octaves":          "Escala menor en octavas",
+		"Major Scale in Fingered Octaves": "Escala mayor en octavas digitadas",
+		"Minor Scale in Fingered Octaves": "Escala menor en octavas digitadas",
+		"Major Scale in Tenths":           "Escala mayor en décimas",
+		"Minor Scale in Tenths":           "Escala menor en décimas",
+		// Key reference
+		"Circle of Fifths":   "Círculo de quintas",
+		"Signature":          "Armadura",
+		"Sharps or flats":    "Sostenidos o bemoles",
+		"%s major":           "%s mayor",
+		"%s minor":           "%s menor",
+		"No sharps or flats": "Sin alteraciones",
+		"1 sharp":            "1 sostenido",
+		"%d sharps":          "%d sostenidos",
+		"1 flat":             "1 bemol",
+		"%d flats":           "%d bemoles",
+		"none":               "ninguna",
+		// Settings page
+		"Language":                    "Idioma",
+		"Note names":                  "Nombres de las notas",
+		"Automatic":                   "Automático",
+		"Letter names (C D E)":        "Letras (C D E)",
+		"German names (C D E … H)":    "Nombres alemanes (C D E … H)",
+		"Fixed-do solfège (Do Ré Mi)": "Solfeo de do fijo (Do Re Mi)",
+		"Save":                        "Guardar",
+		// Offline use
+		"Keep %s offline":                "Guardar %s sin conexión",
+		"Saving for offline use…":        "Guardando para usar sin conexión…",
+		"Available offline":              "Disponible sin conexión",
+		"Could not save for offline use": "No se pudo guardar para usar sin conexión",
+		// Players
+		"Your browser does not support the audio element.": "Tu navegador no admite el elemento de audio.",
+		// Accounts and progress
+		"Name":                     "Nombre",
+		"Password":                 "Contraseña",
+		"No account yet?":          "¿Aún no tienes cuenta?",
+		"Already have an account?": "¿Ya tienes cuenta?",
+		"Logged in as %s.":         "Sesión iniciada como %s.",
+		"Log Out":                  "Cerrar sesión",
+		"Your practice is logged on this browser. Log in or sign up to keep it with your account.": "Tu práctica se registra en este navegador. Inicia sesión o regístrate para guardarla en tu cuenta.",
+		"Total: %d min":           "Total: %d min",
+		"Current streak: %d days": "Racha actual: %d días",
+		"Longest streak: %d days": "Racha más larga: %d días",
+		"%d min":                  "%d min",
+		"Week":                    "Semana",
+		"Day":                     "Día",
+		"Item":                    "Ejercicio",
+		"Minutes":                 "Minutos",
+		"No practice logged yet":  "Aún no hay práctica registrada",
+		// Daily practice
+		"pinned":                           "fijado",
+		"new":                              "nuevo",
+		"%d min today":                     "%d min hoy",
+		"Nothing to practice today.":       "Hoy no hay nada que practicar.",
+		"All done for today, well played!": "¡Todo hecho por hoy, bien tocado!",
+		"How did it go?":                   "¿Cómo ha ido?",
+		"Couldn't play it":                 "No pude tocarlo",
+		"Poor":                             "Mal",
+		"Shaky":                            "Inseguro",
+		"Passable":                         "Aceptable",
+		"Good":                             "Bien",
+		"Perfect":                          "Perfecto",
+		"Unpin from daily practice":        "Quitar de la práctica diaria",
+		// Studio
+		"New invite code": "Nuevo código de invitación",
+		"until %s":        "hasta el %s",
+		"Students":        "Alumnos",
+		"Set work":        "Poner tareas",
+		"Student":         "Alumno",
+		"Choose an item":  "Elige un ejercicio",
+		"target bpm":      "BPM objetivo",
+		"Due":             "Para el",
+		"Notes":           "Notas",
+		"Assign":          "Asignar",
+		"No students yet. Give them an invite code to join.": "Aún no hay alumnos. Dales un código de invitación para unirse.",
+		"Assignments":                 "Tareas",
+		"Work":                        "Trabajo",
+		"Practiced":                   "Practicado",
+		"at %d bpm":                   "a %d BPM",
+		"Done":                        "Hecho",
+		"Overdue":                     "Atrasado",
+		"overdue":                     "atrasado",
+		"New studio":                  "Nuevo estudio",
+		"Studio name":                 "Nombre del estudio",
+		"Create":                      "Crear",
+		"Member of %s.":               "Miembro de %s.",
+		"Member of %s, taught by %s.": "Miembro de %s, con %s como profesor.",
+		"Invite code":                 "Código de invitación",
+		"Join studio":                 "Unirse al estudio",
+		"Reopen":                      "Reabrir",
+		"Mark as done":                "Marcar como hecho",
+		"No work set yet.":            "Aún no hay tareas.",
+		// Duets
+		"Play-along mixer":     "Mezclador para tocar junto",
+		"Volume":               "Volumen",
+		"Pan":                  "Panorama",
+		"Mute":                 "Silenciar",
+		"Part 1":               "Parte 1",
+		"Part 2":               "Parte 2",
+		"Tempo":                "Tempo",
+		"Mix":                  "Mezclar",
+		"Listen to both parts": "Escuchar las dos partes",
+		"Listen to part 1":     "Escuchar la parte 1",
+		"Listen to part 2":     "Escuchar la parte 2",
+		"Listen to your mix":   "Escuchar tu mezcla",
+		"(mixing…)":            "(mezclando…)",
+		"(the mixer is busy…)": "(el mezclador está ocupado…)",
+		"(the mix failed)":     "(la mezcla ha fallado)",
+		// Ear training, syllabus and sight-reading
+		"level %d":                       "nivel %d",
+		"Level":                          "Nivel",
+		"Answered":                       "Respondidas",
+		"Right":                          "Aciertos",
+		"Requirement":                    "Requisito",
+		"Bowing":                         "Arcada",
+		"Audio":                          "Audio",
+		"Notation":                       "Partitura",
+		"Recorded":                       "Grabado",
+		"Synthesized":                    "Sintetizado",
+		"Image":                          "Imagen",
+		"Drawn":                          "Dibujada",
+		"Missing":                        "Falta",
+		"Not yet available: no audio":    "Aún no disponible: sin audio",
+		"Not yet available: no notation": "Aún no disponible: sin partitura",
+		"Not yet available: no audio or notation": "Aún no disponible: sin audio ni partitura",
+		"Pin this grade to daily practice":        "Fijar este grado a la práctica diaria",
+		"bars":                                    "compases",
+		"Range":                                   "Extensión",
+		"Largest leap":                            "Salto más grande",
+		"Rhythms":                                 "Ritmos",
+		"New melody":                              "Nueva melodía",
+		"Link to this melody":                     "Enlace a esta melodía",
+	},
+	Chinese: {
+		// Navigation
+		"Home":               "首页",
+		"Scales & Arpeggios": "音阶与琶音",
+		"Duets":              "二重奏",
+		"Syllabus":           "考级大纲",
+		"Today's Practice":   "今日练习",
+		"Ear Training":       "练耳",
+		"Sight-Reading":      "视奏",
+		"Keys":               "调性",
+		"Progress":           "进度",
+		"Studio":             "工作室",
+		"Settings":           "设置",
+		"Log In":             "登录",
+		"Sign Up":            "注册",
+		// Page titles and headings
+		"Practice Scales and Arpeggios": "练习音阶与琶音",
+		"Practice Scales & Arpeggios":   "练习音阶与琶音",
+		"Practice Duets":                "练习二重奏",
+		"Exam Syllabus":                 "考级大纲",
+		"Practice Progress":             "练习进度",
+		// Introductions
+		"GoViolin is a helpful way to practice violin written in Go.":                                                                                                                         "GoViolin 是一个用 Go 编写的小提琴练习助手。",
+		"Listen to any scale or arpeggio with a few mouse clicks.":                                                                                                                            "只需点击几下，即可聆听任意音阶或琶音。",
+		"Play along to improve your intonation.":                                                                                                                                              "跟着一起演奏，提高你的音准。",
+		"Improve your intonation by practicing scales and arpeggios":                                                                                                                          "通过练习音阶和琶音提高音准",
+		"Franz Wohlfahrt (7 March 1833 - 14 February 1884) was a violin teacher in Leipzig Germany. He wrote the following duets around scales.":                                              "弗朗茨·沃尔法特（1833年3月7日 - 1884年2月14日）是德国莱比锡的小提琴教师。他围绕音阶创作了以下二重奏。",
+		"Scales and arpeggios required for each grade of the major exam boards.":                                                                                                              "各主要考级机构每个级别要求的音阶和琶音。",
+		"Work through today's items and rate how each one went. Items you find hard come back sooner.":                                                                                        "完成今天的练习项目，并为每一项打分。觉得难的项目会更早再次出现。",
+		"Train your ear to hear intervals, scale types and tuning. Questions get harder as you get them right.":                                                                               "训练你的耳朵辨别音程、音阶类型和音准。答对越多，题目越难。",
+		"Read a new melody every day. Choose a key and a level, or pick the range, leaps and rhythms yourself.":                                                                               "每天读一段新旋律。选择调性和级别，或自行设定音域、跳进和节奏。",
+		"Log in to keep your practice log with your account. Practice logged on this browser before you log in is kept.":                                                                      "登录后，练习记录会保存在你的账户中。登录前在此浏览器上记录的练习会被保留。",
+		"Create an account to keep your practice log across browsers. Practice logged on this browser so far is kept.":                                                                        "创建账户，即可在不同浏览器间同步练习记录。此浏览器上已有的练习记录会被保留。",
+		"Invite your students, set their weekly work and see how much they have practiced.":                                                                                                   "邀请你的学生，布置每周作业，并查看他们的练习量。",
+		"Join your teacher's studio and keep track of the work they set you.":                                                                                                                 "加入老师的工作室，跟进布置给你的作业。",
+		"Each step clockwise round the circle of fifths adds a sharp, each step anticlockwise a flat. Minor keys share the signature of their relative major. Click a key to hear its scale.": "在五度圈上每顺时针走一步增加一个升号，每逆时针走一步增加一个降号。小调与其关系大调共用调号。点击一个调即可聆听其音阶。",
+		"Choose the language of the pages and how note names are written.":                                                                                                                    "选择页面语言以及音名的写法。",
+		// Scale page options
+		"Scales":                "音阶",
+		"Arpeggios":             "琶音",
+		"1st Inversions":        "第一转位",
+		"2nd Inversions":        "第二转位",
+		"Dominant 7ths":         "属七和弦",
+		"Diminished 7ths":       "减七和弦",
+		"Augmented":             "增三和弦",
+		"Thirds":                "三度",
+		"Sixths":                "六度",
+		"Octaves":               "八度",
+		"Fingered Octaves":      "指法八度",
+		"Tenths":                "十度",
+		"Major":                 "大调",
+		"Minor":                 "小调",
+		"1 Octave":              "1个八度",
+		"2 Octave":              "2个八度",
+		"3 Octave":              "3个八度",
+		"Spelling":              "拼写",
+		"Rhythm":                "节奏",
+		"Drone":                 "持续音",
+		"Metronome":             "节拍器",
+		"Loop":                  "循环",
+		"Follow the notes":      "跟随音符",
+		"Download MIDI":         "下载 MIDI",
+		"Download MusicXML":     "下载 MusicXML",
+		"Link to this exercise": "本练习的链接",
+		"Pin to daily practice": "固定到每日练习",
+		// Music labels
+		"Listen to %s":                    "聆听%s",
+		"%s with metronome":               "%s（带节拍器）",
+		"Harmonic Minor Scale":            "和声小调音阶",
+		"Melodic Minor Scale":             "旋律小调音阶",
+		"Major Scale":                     "大调音阶",
+		"Minor Scale":                     "小调音阶",
+		"Major Arpeggio":                  "大调琶音",
+		"Minor Arpeggio":                  "小调琶音",
+		"Major Arpeggio, 1st Inversion":   "大调琶音，第一转位",
+		"Minor Arpeggio, 1st Inversion":   "小调琶音，第一转位",
+		"Major Arpeggio, 2nd Inversion":   "大调琶音，第二转位",
+		"Minor Arpeggio, 2nd Inversion":   "小调琶音，第二转位",
+		"Dominant 7th":                    "属七和弦",
+		"Diminished 7th":                  "减七和弦",
+		"Augmented Arpeggio":              "增三和弦琶音",
+		"Major Scale in Thirds":           "大调三度音阶",
+		"Minor Scale in Thirds":           "小调三度音阶",
+		"Major Scale in Sixths":           "大调六度音阶",
+		"Minor Scale in Sixths":           "小调六度音阶",
+		"Major Scale in Octaves":          "大调八度音阶",
+		"Minor Scale in Octaves":          "小调八度音阶",
+		"Major Scale in Fingered Octaves": "大调指法八度音阶",
+		"Minor Scale in Fingered Octaves": "小调指法八度音阶",
+		"Major Scale in Tenths":           "大调十度音阶",
+		"Minor Scale in Tenths":           "小调十度音阶",
+		// Key reference
+		"Circle of Fifths":   "五度圈",
+		"Signature":          "调号",
+		"Sharps or flats":    "升降号",
+		"%s major":           "%s大调",
+		"%s minor":           "%s小调",
+		"No sharps or flats": "无升降号",
+		"1 sharp":            "1个升号",
+		"%d sharps":          "%d个升号",
+		"1 flat":             "1个降号",
+		"%d flats":           "%d个降号",
+		"none":               "无",
+		// Settings page
+		"Language":                    "语言",
+		"Note names":                  "音名",
+		"Automatic":                   "自动",
+		"Letter names (C D E)":        "字母音名 (C D E)",
+		"German names (C D E … H)":    "德式音名 (C D E … H)",
+		"Fixed-do solfège (Do Ré Mi)": "固定唱名 (Do Ré Mi)",
+		"Save":                        "保存",
+		// Offline use
+		"Keep %s offline":                "离线保存%s",
+		"Saving for offline use…":        "正在保存以供离线使用…",
+		"Available offline":              "可离线使用",
+		"Could not save for offline use": "无法保存以供离线使用",
+		// Players
+		"Your browser does not support the audio element.": "你的浏览器不支持音频元素。",
+		// Accounts and progress
+		"Name":                     "用户名",
+		"Password":                 "密码",
+		"No account yet?":          "还没有账户？",
+		"Already have an account?": "已有账户？",
+		"Logged in as %s.":         "已登录为%s。",
+		"Log Out":                  "退出",
+		"Your practice is logged on this browser. Log in or sign up to keep it with your account.": "你的练习记录保存在此浏览器上。登录或注册即可将其保存到你的账户中。",
+		"Total: %d min":           "总计：%d 分钟",
+		"Current streak: %d days": "当前连续：%d 天",
+		"Longest streak: %d days": "最长连续：%d 天",
+		"%d min":                  "%d 分钟",
+		"Week":                    "周",
+		"Day":                     "日",
+		"Item":                    "练习项目",
+		"Minutes":                 "分钟",
+		"No practice logged yet":  "还没有练习记录",
+		// Daily practice
+		"pinned":                           "已固定",
+		"new":                              "新",
+		"%d min today":                     "今天 %d 分钟",
+		"Nothing to practice today.":       "今天没有需要练习的内容。",
+		"All done for today, well played!": "今天的练习都完成了，拉得好！",
+		"How did it go?":                   "练得怎么样？",
+		"Couldn't play it":                 "拉不下来",
+		"Poor":                             "差",
+		"Shaky":                            "不稳",
+		"Passable":                         "尚可",
+		"Good":                             "好",
+		"Perfect":                          "完美",
+		"Unpin from daily practice":        "从每日练习中取消固定",
+		// Studio
+		"New invite code": "新邀请码",
+		"until %s":        "有效期至%s",
+		"Students":        "学生",
+		"Set work":        "布置作业",
+		"Student":         "学生",
+		"Choose an item":  "选择练习项目",
+		"target bpm":      "目标速度",
+		"Due":             "截止",
+		"Notes":           "备注",
+		"Assign":          "布置",
+		"No students yet. Give them an invite code to join.": "还没有学生。给他们一个邀请码即可加入。",
+		"Assignments":                 "作业",
+		"Work":                        "内容",
+		"Practiced":                   "已练习",
+		"at %d bpm":                   "速度 %d",
+		"Done":                        "已完成",
+		"Overdue":                     "已逾期",
+		"overdue":                     "已逾期",
+		"New studio":                  "新建工作室",
+		"Studio name":                 "工作室名称",
+		"Create":                      "创建",
+		"Member of %s.":               "%s的成员。",
+		"Member of %s, taught by %s.": "%s的成员，由%s授课。",
+		"Invite code":                 "邀请码",
+		"Join studio":                 "加入工作室",
+		"Reopen":                      "重新打开",
+		"Mark as done":                "标记为已完成",
+		"No work set yet.":            "还没有布置作业。",
+		// Duets
+		"Play-along mixer":     "伴奏混音器",
+		"Volume":               "音量",
+		"Pan":                  "声像",
+		"Mute":                 "静音",
+		"Part 1":               "第一声部",
+		"Part 2":               "第二声部",
+		"Tempo":                "速度",
+		"Mix":                  "混音",
+		"Listen to both parts": "聆听两个声部",
+		"Listen to part 1":     "聆听第一声部",
+		"Listen to part 2":     "聆听第二声部",
+		"Listen to your mix":   "聆听你的混音",
+		"(mixing…)":            "（正在混音…）",
+		"(the mixer is busy…)": "（混音器繁忙…）",
+		"(the mix failed)":     "（混音失败）",
+		// Ear training, syllabus and sight-reading
+		"level %d":                       "第 %d 级",
+		"Level":                          "级别",
+		"Answered":                       "已答",
+		"Right":                          "正确率",
+		"Requirement":                    "要求",
+		"Bowing":                         "弓法",
+		"Audio":                          "音频",
+		"Notation":                       "乐谱",
+		"Recorded":                       "录音",
+		"Synthesized":                    "合成",
+		"Image":                          "图片",
+		"Drawn":                          "绘制",
+		"Missing":                        "缺失",
+		"Not yet available: no audio":    "暂不可用：没有音频",
+		"Not yet available: no notation": "暂不可用：没有乐谱",
+		"Not yet available: no audio or notation": "暂不可用：没有音频和乐谱",
+		"Pin this grade to daily practice":        "将此级别固定到每日练习",
+		"bars":                                    "小节",
+		"Range":                                   "音域",
+		"Largest leap":                            "最大跳进",
+		"Rhythms":                                 "节奏",
+		"New melody":                              "新旋律",
+		"Link to this melody":                     "此旋律的链接",
+	},
+}
@@ -0,0 +1,181 @@
+// Package i18n translates GoViolin's pages and writes note names in the
+// naming system a student reads: letter names, German names with H for B,
+// or fixed-do solfège.
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Languages the pages are translated into.
+const (
+	English = "en"
+	German  = "de"
+	French  = "fr"
+	Spanish = "es"
+	Chinese = "zh"
+)
+
+// Languages lists the languages, English first as the fallback of every
+// message.
+var Languages = []string{English, German, French, Spanish, Chinese}
+
+// LanguageNames maps each language onto its name in that language.
+var LanguageNames = map[string]string{
+	English: "English",
+	German:  "Deutsch",
+	French:  "Français",
+	Spanish: "Español",
+	Chinese: "中文",
+}
+
+// Note naming systems.
+const (
+	NotesLetter  = "letter"  // C D E F G A B, with Bb and F#
+	NotesGerman  = "german"  // C D E F G A H, with B and Fis
+	NotesSolfege = "solfege" // Do Ré Mi Fa Sol La Si
+)
+
+// Namings lists the note naming systems.
+var Namings = []string{NotesLetter, NotesGerman, NotesSolfege}
+
+// Locale is the language and note naming system a page is written in. The
+// zero value is English with letter names.
+type Locale struct {
+	Language string
+	Notes    string
+}
+
+// New returns the locale of a language and note naming system, English for
+// an unknown language and the usual naming system of the language for an
+// unknown one.
+func New(language, notes string) Locale {
+	if !supported(Languages, language) {
+		language = English
+	}
+	if !supported(Namings, notes) {
+		notes = DefaultNotes(language)
+	}
+	return Locale{Language: language, Notes: notes}
+}
+
+// DefaultNotes returns the naming system students of a language usually
+// read: German names for German, solfège for French and Spanish and letter
+// names otherwise.
+func DefaultNotes(language string) string {
+	switch language {
+	case German:
+		return NotesGerman
+	case French, Spanish:
+		return NotesSolfege
+	}
+	return NotesLetter
+}
+
+// Negotiate returns the language of an Accept-Language header the pages
+// are best translated into, English when it names none of them.
+func Negotiate(header string) string {
+	type choice struct {
+		language string
+		q        float64
+	}
+	var choices []choice
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if i := strings.IndexByte(tag, '-'); i >= 0 {
+			tag = tag[:i]
+		}
+		q := 1.0
+		for _, f := range fields[1:] {
+			f = strings.TrimSpace(f)
+			if strings.HasPrefix(f, "q=") {
+				if v, err := strconv.ParseFloat(f[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 && supported(Languages, tag) {
+			choices = append(choices, choice{tag, q})
+		}
+	}
+	if len(choices) == 0 {
+		return English
+	}
+	sort.SliceStable(choices, func(i, j int) bool { return choices[i].q > choices[j].q })
+	return choices[0].language
+}
+
+// T translates a message, formatting it with the args when there are any.
+// Messages with no translation are left in English.
+func (l Locale) T(msg string, args ...interface{}) string {
+	if t, ok := catalogs[l.Language][msg]; ok {
+		msg = t
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// Note writes note names in the locale's naming system. It takes a name
+// such as "F#" or "Bb", or a key option such as "C#/Db", and leaves anything
+// else as it is.
+func (l Locale) Note(name string) string {
+	parts := strings.Split(name, "/")
+	for i, p := range parts {
+		parts[i] = l.noteName(p)
+	}
+	return strings.Join(parts, "/")
+}
+
+// solfege maps note letters onto their fixed-do names, which Spanish
+// writes without the accent on Re.
+var solfege = map[byte]string{'C': "Do", 'D': "Ré", 'E': "Mi", 'F': "Fa", 'G': "Sol", 'A': "La", 'B': "Si"}
+
+// noteName writes a single note name in the locale's naming system.
+func (l Locale) noteName(name string) string {
+	if name == "" || name[0] < 'A' || name[0] > 'G' {
+		return name
+	}
+	sharps, flats := strings.Count(name[1:], "#"), strings.Count(name[1:], "b")
+	if sharps+flats != len(name)-1 || (sharps > 0 && flats > 0) {
+		return name
+	}
+
+	letter := name[0]
+	switch l.Notes {
+	case NotesGerman:
+		switch {
+		case letter == 'B' && flats == 0:
+			return "H" + strings.Repeat("is", sharps)
+		case letter == 'B' && flats == 1:
+			return "B"
+		case letter == 'B':
+			return "H" + strings.Repeat("es", flats)
+		case (letter == 'E' || letter == 'A') && flats > 0:
+			return string(letter) + "s" + strings.Repeat("es", flats-1)
+		}
+		return string(letter) + strings.Repeat("is", sharps) + strings.Repeat("es", flats)
+	case NotesSolfege:
+		syllable := solfege[letter]
+		if letter == 'D' && l.Language == Spanish {
+			syllable = "Re"
+		}
+		return syllable + strings.Repeat("♯", sharps) + strings.Repeat("♭", flats)
+	}
+	return name
+}
+
+// supported reports whether a value is one of the values.
+func supported(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
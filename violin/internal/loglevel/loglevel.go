@@ -0,0 +1,122 @@
+// Package loglevel filters the lines GoViolin logs by level. Each line is
+// logged at an explicit level through a Logger: request traces at debug,
+// what the server does at info and failures at error. A Writer shared by
+// the loggers writes the lines at its level or above.
+package loglevel
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// Level is how much a Writer lets through.
+type Level int32
+
+// Levels from the most to the least written. Debug writes every line, Info
+// leaves out request traces and Error writes errors only.
+const (
+	Debug Level = iota
+	Info
+	Error
+)
+
+var names = []string{"debug", "info", "error"}
+
+// Parse returns the level named s, one of debug, info or error.
+func Parse(s string) (Level, error) {
+	for i, name := range names {
+		if strings.EqualFold(s, name) {
+			return Level(i), nil
+		}
+	}
+	return Debug, errors.Errorf("unknown log level %q, want debug, info or error", s)
+}
+
+// String returns the name of the level.
+func (l Level) String() string {
+	if l < Debug || l > Error {
+		return "unknown"
+	}
+	return names[l]
+}
+
+// Writer writes the lines logged at its level or above to another writer.
+// Its level can be changed while loggers write to it.
+type Writer struct {
+	out   io.Writer
+	level int32
+}
+
+// NewWriter constructs a Writer passing the lines at level or above to out.
+func NewWriter(out io.Writer, level Level) *Writer {
+	return &Writer{out: out, level: int32(level)}
+}
+
+// SetLevel changes the level lines are written at from now on.
+func (w *Writer) SetLevel(l Level) {
+	atomic.StoreInt32(&w.level, int32(l))
+}
+
+// Level returns the level lines are written at.
+func (w *Writer) Level() Level {
+	return Level(atomic.LoadInt32(&w.level))
+}
+
+// at returns the writer of the lines logged at a level.
+func (w *Writer) at(l Level) io.Writer {
+	return levelWriter{w, l}
+}
+
+// levelWriter writes the lines of one level to a Writer.
+type levelWriter struct {
+	w     *Writer
+	level Level
+}
+
+// Write writes a line unless its level is below the Writer's. Lines left
+// out count as written.
+func (lw levelWriter) Write(p []byte) (int, error) {
+	if lw.level < lw.w.Level() {
+		return len(p), nil
+	}
+	return lw.w.out.Write(p)
+}
+
+// Logger logs lines at a level each. Its embedded log.Logger logs at info,
+// so a Logger can stand in for one, and Debugf and Errorf log at the other
+// levels.
+type Logger struct {
+	*log.Logger
+	debug *log.Logger
+	error *log.Logger
+}
+
+// New constructs a Logger writing to w, its lines starting with the prefix
+// and flags of log.New.
+func New(w *Writer, prefix string, flag int) *Logger {
+	return &Logger{
+		Logger: log.New(w.at(Info), prefix, flag),
+		debug:  log.New(w.at(Debug), prefix, flag),
+		error:  log.New(w.at(Error), prefix, flag),
+	}
+}
+
+// Discard returns a Logger that writes nothing, for tests.
+func Discard() *Logger {
+	return New(NewWriter(io.Discard, Error+1), "", 0)
+}
+
+// Debugf logs a line at debug, such as the trace of a request.
+func (l *Logger) Debugf(format string, v ...interface{}) {
+	l.debug.Output(2, fmt.Sprintf(format, v...))
+}
+
+// Errorf logs a line at error.
+func (l *Logger) Errorf(format string, v ...interface{}) {
+	l.error.Output(2, fmt.Sprintf(format, v...))
+}
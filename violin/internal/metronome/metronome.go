@@ -0,0 +1,298 @@
+// Package metronome generates click tracks to practise along with, on their
+// own or mixed under a recording.
+package metronome
+
+import (
+	"io"
+	"math"
+	"time"
+
+	"violin/internal/audio"
+
+	"github.com/pkg/errors"
+)
+
+// The accent patterns a metronome can play.
+const (
+	AccentMeter = "meter" // the strong and medium beats of the meter
+	AccentFirst = "first" // only the first beat of each bar
+	AccentNone  = "none"  // every beat alike
+)
+
+// The tempo range of a metronome, that of Maelzel's.
+const (
+	MinBPM = 40
+	MaxBPM = 208
+)
+
+// ErrInvalid is returned for settings a metronome cannot play.
+var ErrInvalid = errors.New("invalid metronome settings")
+
+// meters maps the supported meters to their accent patterns, one character
+// per beat: X for the downbeat, x for a secondary accent and . for the rest.
+var meters = map[string]string{
+	"2/4": "X.",
+	"3/4": "X..",
+	"4/4": "X.x.",
+	"6/8": "X..x..",
+}
+
+// Settings describe a click track. The beat is the note value of the
+// meter, so 6/8 at 120 BPM plays 120 eighth notes a minute.
+type Settings struct {
+	BPM         int
+	Meter       string
+	Accent      string
+	Subdivision int // clicks per beat, 1 to 4
+	CountIn     int // bars of clicks before the music starts
+	SilentEvery int // mute every Nth bar after the count-in, 0 for none
+}
+
+// Validate checks the settings are playable.
+func (s Settings) Validate() error {
+	if _, ok := meters[s.Meter]; !ok {
+		return errors.Wrapf(ErrInvalid, "meter %q", s.Meter)
+	}
+	switch {
+	case s.BPM < MinBPM || s.BPM > MaxBPM:
+		return errors.Wrapf(ErrInvalid, "%d BPM", s.BPM)
+	case s.Accent != AccentMeter && s.Accent != AccentFirst && s.Accent != AccentNone:
+		return errors.Wrapf(ErrInvalid, "accent %q", s.Accent)
+	case s.Subdivision < 1 || s.Subdivision > 4:
+		return errors.Wrapf(ErrInvalid, "subdivision %d", s.Subdivision)
+	case s.CountIn < 0 || s.CountIn > 4:
+		return errors.Wrapf(ErrInvalid, "count-in of %d bars", s.CountIn)
+	case s.SilentEvery < 0 || s.SilentEvery == 1:
+		return errors.Wrapf(ErrInvalid, "silent every %d bars", s.SilentEvery)
+	}
+	return nil
+}
+
+// CountInDuration returns how long the count-in lasts.
+func (s Settings) CountInDuration() time.Duration {
+	beats := s.CountIn * len(meters[s.Meter])
+	return time.Duration(beats) * time.Minute / time.Duration(s.BPM)
+}
+
+// pattern returns the accent of every beat of a bar.
+func (s Settings) pattern() string {
+	p := []byte(meters[s.Meter])
+	for i := range p {
+		switch {
+		case s.Accent == AccentNone, s.Accent == AccentFirst && i > 0:
+			p[i] = '.'
+		case s.Accent == AccentFirst:
+			p[i] = 'X'
+		}
+	}
+	return string(p)
+}
+
+// The click levels, from the downbeat to a subdivision.
+const (
+	levelDownbeat = iota
+	levelAccent
+	levelBeat
+	levelSubdivision
+	levelCount
+)
+
+// clickLength is how long each click rings.
+const clickLength = 25 * time.Millisecond
+
+// clickSounds returns the waveform of each click level: a short decaying
+// sine, higher and louder for stronger beats.
+func clickSounds(rate int) [levelCount][]float32 {
+	freqs := [levelCount]float64{1760, 1320, 880, 880}
+	gains := [levelCount]float64{0.9, 0.7, 0.5, 0.25}
+
+	n := int(clickLength.Seconds() * float64(rate))
+	attack := rate / 1000
+	var sounds [levelCount][]float32
+	for l := range sounds {
+		sounds[l] = make([]float32, n)
+		for i := range sounds[l] {
+			t := float64(i) / float64(rate)
+			env := math.Exp(-t / (clickLength.Seconds() / 5))
+			if i < attack {
+				env *= float64(i) / float64(attack)
+			}
+			sounds[l][i] = float32(gains[l] * env * math.Sin(2*math.Pi*freqs[l]*t))
+		}
+	}
+	return sounds
+}
+
+// New returns a click track of the given duration, count-in included. Every
+// sample is worked out from its position, so the track can be any length
+// and seeks cost nothing.
+func New(s Settings, f audio.Format, duration time.Duration) (audio.SeekStream, error) {
+	return newClicks(s, f, func(tick float64) int {
+		return int(duration.Seconds() * float64(f.SampleRate))
+	})
+}
+
+// Loop returns a click track to be played over and over: whole bars, and
+// whole runs of bars up to the next silent one, lasting at least the given
+// duration. It has no count-in, which would come round again with every
+// repeat.
+func Loop(s Settings, f audio.Format, least time.Duration) (audio.SeekStream, error) {
+	s.CountIn = 0
+	run := 1
+	if s.SilentEvery > 0 {
+		run = s.SilentEvery
+	}
+	return newClicks(s, f, func(tick float64) int {
+		// Whole runs are counted in ticks, the length of a tick rarely
+		// being a whole number of frames.
+		ticks := run * len(meters[s.Meter]) * s.Subdivision
+		n := ticks
+		for float64(n)*tick < least.Seconds()*float64(f.SampleRate) {
+			n += ticks
+		}
+		return int(math.Round(float64(n) * tick))
+	})
+}
+
+// newClicks returns a click track of the settings lasting the number of
+// frames length works out from the frames between clicks.
+func newClicks(s Settings, f audio.Format, length func(tick float64) int) (audio.SeekStream, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	if f.SampleRate <= 0 || f.Channels <= 0 {
+		return nil, errors.Errorf("invalid format %+v", f)
+	}
+
+	c := clicks{
+		settings: s,
+		format:   f,
+		pattern:  s.pattern(),
+		sounds:   clickSounds(f.SampleRate),
+		tick:     float64(f.SampleRate) * 60 / float64(s.BPM*s.Subdivision),
+		frame:    make([]byte, 2*f.Channels),
+	}
+	c.frames = length(c.tick)
+	return &c, nil
+}
+
+// clicks is the stream returned by New.
+type clicks struct {
+	settings Settings
+	format   audio.Format
+	pattern  string
+	sounds   [levelCount][]float32
+	tick     float64 // frames between clicks
+	frames   int
+	off      int64
+	frame    []byte
+}
+
+// Format returns the format of the click track.
+func (c *clicks) Format() audio.Format {
+	return c.format
+}
+
+// Length returns the number of bytes of samples in the stream.
+func (c *clicks) Length() int64 {
+	return int64(c.frames) * int64(len(c.frame))
+}
+
+// Read implements io.Reader.
+func (c *clicks) Read(p []byte) (int, error) {
+	size := c.Length()
+	if c.off >= size {
+		return 0, io.EOF
+	}
+
+	frameSize := int64(len(c.frame))
+	n := 0
+	for n < len(p) && c.off < size {
+		v := c.sample(int(c.off / frameSize))
+		for ch := 0; ch < c.format.Channels; ch++ {
+			c.frame[2*ch] = byte(v)
+			c.frame[2*ch+1] = byte(v >> 8)
+		}
+		m := copy(p[n:], c.frame[c.off%frameSize:])
+		n += m
+		c.off += int64(m)
+	}
+	return n, nil
+}
+
+// Seek implements io.Seeker.
+func (c *clicks) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += c.off
+	case io.SeekEnd:
+		offset += c.Length()
+	default:
+		return 0, errors.Errorf("invalid whence %d", whence)
+	}
+	if offset < 0 {
+		return 0, errors.New("seeking before the start")
+	}
+	c.off = offset
+	return offset, nil
+}
+
+// sample returns the 16 bit sample of frame pos.
+func (c *clicks) sample(pos int) int16 {
+	tick := int(float64(pos) / c.tick)
+	since := pos - int(float64(tick)*c.tick)
+
+	level, ok := c.level(tick)
+	if !ok || since >= len(c.sounds[level]) {
+		return 0
+	}
+	return int16(c.sounds[level][since] * math.MaxInt16)
+}
+
+// level returns the click level of a tick, or false when its bar is a
+// silent one.
+func (c *clicks) level(tick int) (int, bool) {
+	s := c.settings
+	beat := tick / s.Subdivision
+	bar := beat / len(c.pattern)
+	if bar >= s.CountIn && s.SilentEvery > 0 && (bar-s.CountIn+1)%s.SilentEvery == 0 {
+		return 0, false
+	}
+
+	if tick%s.Subdivision != 0 {
+		return levelSubdivision, true
+	}
+	switch c.pattern[beat%len(c.pattern)] {
+	case 'X':
+		return levelDownbeat, true
+	case 'x':
+		return levelAccent, true
+	}
+	return levelBeat, true
+}
+
+// Overlay mixes a click track under a recording. The recording starts
+// after the count-in and the clicks carry on to its end, at the given
+// linear gain relative to the recording.
+func Overlay(b *audio.Buffer, s Settings, gain float64) (*audio.Buffer, error) {
+	countIn := int(s.CountInDuration().Seconds() * float64(b.SampleRate))
+	duration := time.Duration(countIn+b.Frames()) * time.Second / time.Duration(b.SampleRate)
+
+	c, err := New(s, audio.Format{SampleRate: b.SampleRate, Channels: 1}, duration)
+	if err != nil {
+		return nil, err
+	}
+	track, err := audio.ReadBuffer(c)
+	if err != nil {
+		return nil, errors.Wrap(err, "generating clicks")
+	}
+
+	delayed := audio.NewBuffer(b.SampleRate, b.Channels, countIn+b.Frames())
+	copy(delayed.Samples[countIn*b.Channels:], b.Samples)
+
+	return audio.Mix([]audio.Track{
+		{Buffer: delayed, Gain: 1},
+		{Buffer: track, Gain: gain},
+	})
+}
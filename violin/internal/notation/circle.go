@@ -0,0 +1,138 @@
+package notation
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"strings"
+
+	"violin/internal/theory"
+
+	"github.com/pkg/errors"
+)
+
+// Layout of the circle of fifths, in pixels from its centre.
+const (
+	circleSize     = 640
+	innerRadius    = 105 // inside of the ring of minor keys
+	minorRadius    = 175 // between the minor and major keys
+	majorRadius    = 255 // outside of the ring of major keys
+	signatureSpace = 30  // from the major keys to their signatures
+)
+
+// WriteCircle draws the circle of fifths with C major at the top, its
+// major keys around the outside, their relative minors inside them, the key
+// signature of each position beyond and the title in the middle. Each key
+// is written as the text name returns for it and its pitch, "Major" or
+// "Minor", and links to the url it returns unless that is empty.
+func WriteCircle(w io.Writer, title string, circle []theory.CirclePosition, name func(key, pitch string) (text, url string)) error {
+	if len(circle) != 12 {
+		return errors.Errorf("circle of %d positions", len(circle))
+	}
+
+	c := circleSize / 2
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", circleSize, circleSize, circleSize, circleSize)
+	b.WriteString(`<style>.key:hover path{fill:#c7d9ec}a text{fill:#17375e}a:hover text{text-decoration:underline}</style>` + "\n")
+	fmt.Fprintf(&b, `<text x="%d" y="%d" font-family="serif" font-size="20" text-anchor="middle">%s</text>`+"\n", c, c+6, html.EscapeString(title))
+
+	for _, p := range circle {
+		from := (float64(p.Position) - 0.5) * math.Pi / 6
+		to := (float64(p.Position) + 0.5) * math.Pi / 6
+		mid := float64(p.Position) * math.Pi / 6
+
+		b.WriteString(`<g class="key">` + "\n")
+		writeWedge(&b, c, majorRadius, minorRadius, from, to, "#f4f4f4")
+		writeWedge(&b, c, minorRadius, innerRadius, from, to, "#e4e4e4")
+
+		// Enharmonic signatures share a position, one above the other.
+		n := len(p.Signatures)
+		var sigs []string
+		for i, s := range p.Signatures {
+			offset := float64(n-1)*0.5 - float64(i)
+			major := float64(majorRadius+minorRadius)/2 + offset*24
+			minor := float64(minorRadius+innerRadius)/2 + offset*22
+			size := 22 - 5*(n-1)
+			text, url := name(s.Major, "Major")
+			writeKeyName(&b, c, major, mid, size, text, url)
+			text, url = name(s.Minor, "Minor")
+			writeKeyName(&b, c, minor, mid, size-2, text, url)
+			sigs = append(sigs, signatureName(s.Fifths))
+		}
+		x, y := polar(c, majorRadius+signatureSpace/2+4, mid)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" font-family="serif" font-size="13" text-anchor="middle" dominant-baseline="central" fill="#4b5786">%s</text>`+"\n", x, y, strings.Join(sigs, " / "))
+		b.WriteString("</g>\n")
+	}
+	b.WriteString("</svg>\n")
+
+	_, err := w.Write(b.Bytes())
+	return errors.Wrap(err, "writing svg")
+}
+
+// WriteKeySignature draws a short treble staff with the key signature of
+// keySig sharps, or flats when negative.
+func WriteKeySignature(w io.Writer, keySig int) error {
+	if keySig < -7 || keySig > 7 {
+		return errors.Errorf("invalid key signature %d", keySig)
+	}
+
+	const signatureWidth, signatureHeight = 160, 90
+	top := 25
+	bottom := top + 4*space
+	y := func(step int) int {
+		return bottom - (step-bottomLine)*space/2
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", signatureWidth, signatureHeight, signatureWidth, signatureHeight)
+	for l := 0; l < 5; l++ {
+		fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="black"/>`+"\n", 0, top+l*space, signatureWidth, top+l*space)
+	}
+	writeClef(&b, 0, bottom, keySig, y)
+	b.WriteString("</svg>\n")
+
+	_, err := w.Write(b.Bytes())
+	return errors.Wrap(err, "writing svg")
+}
+
+// writeWedge draws the part of a ring between two radii and two angles,
+// measured clockwise from the top.
+func writeWedge(b *bytes.Buffer, c, outer, inner int, from, to float64, fill string) {
+	x1, y1 := polar(c, float64(outer), from)
+	x2, y2 := polar(c, float64(outer), to)
+	x3, y3 := polar(c, float64(inner), to)
+	x4, y4 := polar(c, float64(inner), from)
+	fmt.Fprintf(b, `<path d="M %.1f %.1f A %d %d 0 0 1 %.1f %.1f L %.1f %.1f A %d %d 0 0 0 %.1f %.1f Z" fill="%s" stroke="#a49a87"/>`+"\n",
+		x1, y1, outer, outer, x2, y2, x3, y3, inner, inner, x4, y4, fill)
+}
+
+// writeKeyName writes the name of a key at a radius and angle from the
+// centre, linked to the url unless it is empty.
+func writeKeyName(b *bytes.Buffer, c int, radius, angle float64, size int, name, url string) {
+	x, y := polar(c, radius, angle)
+	text := fmt.Sprintf(`<text x="%.1f" y="%.1f" font-family="serif" font-size="%d" text-anchor="middle" dominant-baseline="central">%s</text>`, x, y, size, html.EscapeString(name))
+	if url != "" {
+		text = fmt.Sprintf(`<a href="%s" target="_top">%s</a>`, html.EscapeString(url), text)
+	}
+	b.WriteString(text + "\n")
+}
+
+// polar returns the point a radius from the centre at an angle measured
+// clockwise from the top.
+func polar(c int, radius, angle float64) (float64, float64) {
+	return float64(c) + radius*math.Sin(angle), float64(c) - radius*math.Cos(angle)
+}
+
+// signatureName names a key signature by its number of sharps or flats,
+// such as "3♯".
+func signatureName(fifths int) string {
+	switch {
+	case fifths > 0:
+		return fmt.Sprintf("%d%s", fifths, accidentals[1])
+	case fifths < 0:
+		return fmt.Sprintf("%d%s", -fifths, accidentals[-1])
+	}
+	return "0"
+}
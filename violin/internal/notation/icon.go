@@ -0,0 +1,30 @@
+package notation
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// iconSize is the width and height of the app icon, which scales to any
+// size as it is drawn in SVG.
+const iconSize = 512
+
+// WriteIcon draws the icon GoViolin is installed as an app with, a treble
+// clef on a staff in the colours of the site.
+func WriteIcon(w io.Writer) error {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", iconSize, iconSize, iconSize, iconSize)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" rx="%d" fill="#4b5786"/>`+"\n", iconSize, iconSize, iconSize/8)
+	top, gap := iconSize*3/10, iconSize/10
+	for l := 0; l < 5; l++ {
+		fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#f8f8f8" stroke-width="6"/>`+"\n", iconSize/8, top+l*gap, iconSize*7/8, top+l*gap)
+	}
+	fmt.Fprintf(&b, `<text x="%d" y="%d" font-family="serif" font-size="%d" text-anchor="middle" fill="#f8f8f8">𝄞</text>`+"\n", iconSize/2, top+4*gap+gap*3/2, gap*8)
+	b.WriteString("</svg>\n")
+
+	_, err := w.Write(b.Bytes())
+	return errors.Wrap(err, "writing svg")
+}
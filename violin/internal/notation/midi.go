@@ -0,0 +1,92 @@
+package notation
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"violin/internal/rhythm"
+
+	"github.com/pkg/errors"
+)
+
+// MIDI settings of exports.
+const (
+	ticksPerBeat  = 480
+	violinProgram = 40 // General MIDI violin, counting from 0
+	velocity      = 80
+	detache       = 0.9 // of its length a note in a bow of its own sounds for
+)
+
+// WriteMIDI writes the chords as a standard MIDI file of one violin track at
+// the tempo, in crotchet beats per minute. Notes slurred into the next one
+// sound for their whole length, others are slightly detached.
+func WriteMIDI(w io.Writer, keySig, bpm int, chords []Chord) error {
+	if keySig < -7 || keySig > 7 {
+		return errors.Errorf("invalid key signature %d", keySig)
+	}
+	if bpm <= 0 {
+		return errors.Errorf("invalid tempo %d", bpm)
+	}
+
+	var track bytes.Buffer
+	event := func(delta int, data ...byte) {
+		writeVarInt(&track, delta)
+		track.Write(data)
+	}
+
+	tempo := 60000000 / bpm // microseconds per beat
+	event(0, 0xff, 0x51, 3, byte(tempo>>16), byte(tempo>>8), byte(tempo))
+	event(0, 0xff, 0x58, 4, beatsPerBar, 2, 24, 8)
+	event(0, 0xff, 0x59, 2, byte(int8(keySig)), 0)
+	event(0, 0xc0, violinProgram)
+
+	rest := 0
+	for _, c := range chords {
+		ticks := c.Length() * ticksPerBeat / rhythm.Beat
+		sounding := ticks
+		if !c.Slurred {
+			sounding = int(float64(ticks) * detache)
+		}
+
+		for i, n := range c.Notes {
+			delta := 0
+			if i == 0 {
+				delta = rest
+			}
+			event(delta, 0x90, byte(n.MIDI()), velocity)
+		}
+		for i, n := range c.Notes {
+			delta := 0
+			if i == 0 {
+				delta = sounding
+			}
+			event(delta, 0x80, byte(n.MIDI()), 0)
+		}
+		rest = ticks - sounding
+	}
+	event(rest, 0xff, 0x2f, 0)
+
+	var b bytes.Buffer
+	b.WriteString("MThd")
+	binary.Write(&b, binary.BigEndian, uint32(6))
+	binary.Write(&b, binary.BigEndian, []uint16{0, 1, ticksPerBeat}) // format 0, one track
+	b.WriteString("MTrk")
+	binary.Write(&b, binary.BigEndian, uint32(track.Len()))
+	b.Write(track.Bytes())
+
+	_, err := w.Write(b.Bytes())
+	return errors.Wrap(err, "writing midi")
+}
+
+// writeVarInt writes n as a MIDI variable length quantity.
+func writeVarInt(b *bytes.Buffer, n int) {
+	var buf [4]byte
+	i := len(buf) - 1
+	buf[i] = byte(n & 0x7f)
+	for n >>= 7; n > 0; n >>= 7 {
+		i--
+		buf[i] = byte(n&0x7f) | 0x80
+	}
+	b.Write(buf[i:])
+}
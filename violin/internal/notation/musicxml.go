@@ -0,0 +1,108 @@
+package notation
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+
+	"violin/internal/rhythm"
+
+	"github.com/pkg/errors"
+)
+
+// beatsPerBar is the time signature exports are written in, 4/4.
+const beatsPerBar = 4
+
+// noteTypes maps written values, in divisions of a beat, to MusicXML note
+// types.
+var noteTypes = map[int]string{
+	4 * rhythm.Beat: "whole",
+	2 * rhythm.Beat: "half",
+	rhythm.Beat:     "quarter",
+	rhythm.Beat / 2: "eighth",
+	rhythm.Beat / 4: "16th",
+}
+
+// accidentalNames maps an accidental to its MusicXML name.
+var accidentalNames = map[int]string{-2: "flat-flat", -1: "flat", 0: "natural", 1: "sharp", 2: "double-sharp"}
+
+// WriteMusicXML writes the chords as a MusicXML score for violin in 4/4,
+// under the title, in a key signature of keySig sharps, or flats when
+// negative. Unplayable chords are coloured red.
+func WriteMusicXML(w io.Writer, title string, keySig int, chords []Chord) error {
+	if keySig < -7 || keySig > 7 {
+		return errors.Errorf("invalid key signature %d", keySig)
+	}
+	if len(chords) == 0 {
+		return errors.New("no notes to write")
+	}
+
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="no"?>` + "\n")
+	b.WriteString(`<!DOCTYPE score-partwise PUBLIC "-//Recordare//DTD MusicXML 4.0 Partwise//EN" "http://www.musicxml.org/dtds/partwise.dtd">` + "\n")
+	b.WriteString(`<score-partwise version="4.0">` + "\n")
+	fmt.Fprintf(&b, "  <work><work-title>%s</work-title></work>\n", html.EscapeString(title))
+	b.WriteString(`  <part-list><score-part id="P1"><part-name>Violin</part-name></score-part></part-list>` + "\n")
+	b.WriteString(`  <part id="P1">` + "\n")
+
+	bar, at := 0, 0
+	for _, c := range chords {
+		if at%(beatsPerBar*rhythm.Beat) == 0 {
+			if bar > 0 {
+				b.WriteString("    </measure>\n")
+			}
+			bar++
+			fmt.Fprintf(&b, "    <measure number=\"%d\">\n", bar)
+			if bar == 1 {
+				fmt.Fprintf(&b, "      <attributes><divisions>%d</divisions><key><fifths>%d</fifths></key><time><beats>%d</beats><beat-type>4</beat-type></time><clef><sign>G</sign><line>2</line></clef></attributes>\n", rhythm.Beat, keySig, beatsPerBar)
+			}
+		}
+
+		value, dotted := noteValue(c.Length())
+		typ, ok := noteTypes[value]
+		if !ok {
+			return errors.Errorf("no note type for a duration of %d", c.Length())
+		}
+		for i, n := range c.Notes {
+			if c.Unplayable {
+				b.WriteString(`      <note color="#CC0000">`)
+			} else {
+				b.WriteString("      <note>")
+			}
+			if i > 0 {
+				b.WriteString("<chord/>")
+			}
+			fmt.Fprintf(&b, "<pitch><step>%c</step>", n.Letter)
+			if n.Accidental != 0 {
+				fmt.Fprintf(&b, "<alter>%d</alter>", n.Accidental)
+			}
+			fmt.Fprintf(&b, "<octave>%d</octave></pitch><duration>%d</duration><type>%s</type>", n.Octave, c.Length(), typ)
+			if dotted {
+				b.WriteString("<dot/>")
+			}
+			if n.Accidental != signatureAccidental(keySig, n.Letter) {
+				fmt.Fprintf(&b, "<accidental>%s</accidental>", accidentalNames[n.Accidental])
+			}
+			if rhythm.IsTriplet(c.Length()) {
+				b.WriteString("<time-modification><actual-notes>3</actual-notes><normal-notes>2</normal-notes></time-modification>")
+			}
+			if i == 0 && (c.SlurStart || c.SlurEnd) {
+				b.WriteString("<notations>")
+				if c.SlurEnd {
+					b.WriteString(`<slur type="stop" number="1"/>`)
+				}
+				if c.SlurStart {
+					b.WriteString(`<slur type="start" number="1"/>`)
+				}
+				b.WriteString("</notations>")
+			}
+			b.WriteString("</note>\n")
+		}
+		at += c.Length()
+	}
+	b.WriteString("    </measure>\n  </part>\n</score-partwise>\n")
+
+	_, err := w.Write(b.Bytes())
+	return errors.Wrap(err, "writing musicxml")
+}
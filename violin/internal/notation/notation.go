@@ -0,0 +1,319 @@
+// Package notation draws scales and arpeggios on a treble staff as SVG, for
+// the ones GoViolin has no engraved image of.
+package notation
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+
+	"violin/internal/rhythm"
+	"violin/internal/theory"
+
+	"github.com/pkg/errors"
+)
+
+// Layout of the page, in pixels.
+const (
+	width          = 900
+	margin         = 20
+	space          = 10 // between staff lines
+	titleHeight    = 40
+	systemHeight   = 170
+	staffTop       = 90 // from the top of a system to the top staff line
+	notesPerSystem = 16
+	stemLength     = 35
+)
+
+// Staff positions, as theory.Note steps.
+const (
+	bottomLine = 30 // E4
+	middleLine = 34 // B4
+	lowLedger  = 28 // C4, the first ledger line below the staff
+	highLedger = 40 // A5, the first ledger line above the staff
+)
+
+// The order of the sharps and flats of key signatures with the staff step
+// each is written on.
+var (
+	sharpOrder = "FCGDAEB"
+	sharpSteps = []int{38, 35, 39, 36, 33, 37, 34}
+	flatOrder  = "BEADGCF"
+	flatSteps  = []int{34, 37, 33, 36, 32, 35, 31}
+)
+
+// accidentals maps an accidental to its symbol.
+var accidentals = map[int]string{-2: "𝄫", -1: "♭", 0: "♮", 1: "♯", 2: "𝄪"}
+
+// Chord is notes sounding together, such as the two notes of a double stop,
+// with their rhythm and bowing. Unplayable chords are drawn in red with a
+// cross above the staff, and a BarLine is drawn after chords that end a bar.
+type Chord struct {
+	Notes      []theory.Note
+	Unplayable bool
+	BarLine    bool
+	rhythm.Step
+}
+
+// WriteSVG draws the notes as quarter notes on as many treble staves as
+// they need, under the title, in a key signature of keySig sharps, or flats
+// when negative. Notes that differ from the key signature carry their
+// accidental.
+func WriteSVG(w io.Writer, title string, keySig int, notes []theory.Note) error {
+	chords := make([]Chord, len(notes))
+	for i, n := range notes {
+		chords[i] = Chord{Notes: []theory.Note{n}}
+	}
+	return WriteChords(w, title, keySig, chords)
+}
+
+// WriteChords draws the chords the way WriteSVG draws notes, each on one
+// stem, in the rhythm of their steps with their slurs.
+func WriteChords(w io.Writer, title string, keySig int, chords []Chord) error {
+	if keySig < -7 || keySig > 7 {
+		return errors.Errorf("invalid key signature %d", keySig)
+	}
+	if len(chords) == 0 {
+		return errors.New("no notes to draw")
+	}
+	for _, c := range chords {
+		if len(c.Notes) == 0 {
+			return errors.New("empty chord")
+		}
+	}
+
+	systems := (len(chords) + notesPerSystem - 1) / notesPerSystem
+	height := titleHeight + systems*systemHeight
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", width, height, width, height)
+	// Pages following playback mark the note being played as current.
+	b.WriteString(`<style>.current [fill]:not([fill="none"]){fill:#1a7fd4}.current [stroke]{stroke:#1a7fd4}</style>` + "\n")
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="white"/>`+"\n")
+	fmt.Fprintf(&b, `<text x="%d" y="28" font-family="serif" font-size="20">%s</text>`+"\n", margin, html.EscapeString(title))
+
+	var slur *slurMark
+	for s := 0; s < systems; s++ {
+		top := titleHeight + s*systemHeight + staffTop
+		bottom := top + 4*space
+		y := func(step int) int {
+			return bottom - (step-bottomLine)*space/2
+		}
+
+		for l := 0; l < 5; l++ {
+			fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="black"/>`+"\n", margin, top+l*space, width-margin, top+l*space)
+		}
+		x := writeClef(&b, margin, bottom, keySig, y) + 2*space
+
+		line := chords[s*notesPerSystem:]
+		if len(line) > notesPerSystem {
+			line = line[:notesPerSystem]
+		}
+		gap := (width - margin - x) / notesPerSystem
+		if slur != nil {
+			// A slur carried over from the last system.
+			slur.x = x
+		}
+		for i, c := range line {
+			nx := x + i*gap + gap/2
+			fmt.Fprintf(&b, `<g class="note" id="note-%d">`+"\n", s*notesPerSystem+i)
+			color := "black"
+			if c.Unplayable {
+				color = "#c00"
+				fmt.Fprintf(&b, `<text x="%d" y="%d" font-family="sans-serif" font-size="14" text-anchor="middle" fill="%s">✕</text>`+"\n", nx, top-3*space, color)
+			}
+
+			low, high := c.Notes[0].Step(), c.Notes[0].Step()
+			for _, n := range c.Notes {
+				if step := n.Step(); step < low {
+					low = step
+				} else if step > high {
+					high = step
+				}
+			}
+
+			for l := lowLedger; l >= low; l -= 2 {
+				fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="black"/>`+"\n", nx-11, y(l), nx+11, y(l))
+			}
+			for l := highLedger; l <= high; l += 2 {
+				fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="black"/>`+"\n", nx-11, y(l), nx+11, y(l))
+			}
+
+			// Accidentals of notes close above one another are staggered
+			// so they do not overlap.
+			ax, prev, drawn := nx-16, 0, false
+			for _, n := range c.Notes {
+				if n.Accidental == signatureAccidental(keySig, n.Letter) {
+					continue
+				}
+				step := n.Step()
+				if drawn && abs(step-prev) < 6 {
+					ax -= 12
+				} else {
+					ax = nx - 16
+				}
+				fmt.Fprintf(&b, `<text x="%d" y="%d" font-family="serif" font-size="20" text-anchor="middle" dominant-baseline="central" fill="%s">%s</text>`+"\n", ax, y(step), color, accidentals[n.Accidental])
+				prev, drawn = step, true
+			}
+
+			value, dotted := noteValue(c.Length())
+			fill := color
+			if value >= 2*rhythm.Beat {
+				fill = "none"
+			}
+			for _, n := range c.Notes {
+				ny := y(n.Step())
+				fmt.Fprintf(&b, `<ellipse cx="%d" cy="%d" rx="6.5" ry="4.5" transform="rotate(-20 %d %d)" fill="%s" stroke="%s"/>`+"\n", nx, ny, nx, ny, fill, color)
+				if dotted {
+					dy := ny
+					if n.Step()%2 == bottomLine%2 {
+						dy -= space / 2 // off the line
+					}
+					fmt.Fprintf(&b, `<circle cx="%d" cy="%d" r="1.8" fill="%s"/>`+"\n", nx+12, dy, color)
+				}
+			}
+
+			stemUp := low+high < 2*middleLine
+			sx, sy, flag := nx-6, y(low)+stemLength, -1
+			if stemUp {
+				sx, sy, flag = nx+6, y(high)-stemLength, 1
+				fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="%s" stroke-width="1.3"/>`+"\n", sx, y(low), sx, sy, color)
+			} else {
+				fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="%s" stroke-width="1.3"/>`+"\n", sx, y(high), sx, sy, color)
+			}
+			for f := 0; f < flags(value); f++ {
+				fy := sy + flag*7*f
+				fmt.Fprintf(&b, `<path d="M %d %d q 4 %d 10 %d" fill="none" stroke="%s" stroke-width="1.5"/>`+"\n", sx, fy, flag*10, flag*14, color)
+			}
+
+			if c.Triplet {
+				ty := top - 2*space
+				if hy := y(high) - stemLength - 6; hy < ty {
+					ty = hy
+				}
+				fmt.Fprintf(&b, `<text x="%d" y="%d" font-family="serif" font-style="italic" font-size="14" text-anchor="middle">3</text>`+"\n", nx+gap, ty)
+			}
+			b.WriteString("</g>\n")
+			if c.BarLine {
+				bx := nx + gap/2
+				fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="black"/>`+"\n", bx, top, bx, bottom)
+			}
+
+			if c.SlurStart {
+				slur = &slurMark{x: nx, below: stemUp}
+				slur.y = slur.end(y(low), y(high))
+			}
+			if c.SlurEnd && slur != nil {
+				writeSlur(&b, slur.x, slur.y, nx, slur.end(y(low), y(high)), slur.below)
+				slur = nil
+			}
+		}
+		if slur != nil {
+			writeSlur(&b, slur.x, slur.y, width-margin, slur.y, slur.below)
+		}
+	}
+	b.WriteString("</svg>\n")
+
+	_, err := w.Write(b.Bytes())
+	return errors.Wrap(err, "writing svg")
+}
+
+// writeClef draws a treble clef and the key signature of keySig sharps, or
+// flats when negative, on a staff from x, and returns where the signature
+// ends. The staff's bottom line is at the height bottom and y gives the
+// height of a staff step.
+func writeClef(b *bytes.Buffer, x, bottom, keySig int, y func(step int) int) int {
+	fmt.Fprintf(b, `<text x="%d" y="%d" font-family="serif" font-size="64">𝄞</text>`+"\n", x, bottom+12)
+
+	x += 45
+	steps, symbol := sharpSteps, accidentals[1]
+	if keySig < 0 {
+		steps, symbol = flatSteps, accidentals[-1]
+	}
+	for i := 0; i < abs(keySig); i++ {
+		fmt.Fprintf(b, `<text x="%d" y="%d" font-family="serif" font-size="20" text-anchor="middle" dominant-baseline="central">%s</text>`+"\n", x, y(steps[i]), symbol)
+		x += space
+	}
+	return x
+}
+
+// slurMark is where an unfinished slur starts, drawn below the notes when
+// their stems point up and above them when they point down.
+type slurMark struct {
+	x, y  int
+	below bool
+}
+
+// end returns the height a slur meets a chord spanning the heights low to
+// high at.
+func (m *slurMark) end(low, high int) int {
+	if m.below {
+		return low + 10
+	}
+	return high - 10
+}
+
+// writeSlur draws a slur from one point to another bending away from the
+// notes.
+func writeSlur(b *bytes.Buffer, x1, y1, x2, y2 int, below bool) {
+	my := y1
+	if below {
+		if y2 > my {
+			my = y2
+		}
+		my += 10
+	} else {
+		if y2 < my {
+			my = y2
+		}
+		my -= 10
+	}
+	fmt.Fprintf(b, `<path d="M %d %d Q %d %d %d %d" fill="none" stroke="black" stroke-width="1.2"/>`+"\n", x1, y1, (x1+x2)/2, my, x2, y2)
+}
+
+// noteValue returns the written value of a duration, in divisions of a
+// beat, and whether it is dotted. Triplet notes are written as the value
+// they are two thirds of.
+func noteValue(duration int) (int, bool) {
+	if rhythm.IsTriplet(duration) {
+		duration = duration * 3 / 2
+	}
+	if duration%9 == 0 && (duration/9)&(duration/9-1) == 0 {
+		return duration * 2 / 3, true
+	}
+	return duration, false
+}
+
+// flags returns how many flags the stem of a written value carries.
+func flags(value int) int {
+	n := 0
+	for v := value; v < rhythm.Beat; v *= 2 {
+		n++
+	}
+	return n
+}
+
+// signatureAccidental returns the accidental the key signature gives a
+// letter.
+func signatureAccidental(keySig int, letter byte) int {
+	for i := 0; i < keySig; i++ {
+		if sharpOrder[i] == letter {
+			return 1
+		}
+	}
+	for i := 0; i < -keySig; i++ {
+		if flatOrder[i] == letter {
+			return -1
+		}
+	}
+	return 0
+}
+
+// abs returns the absolute value of n.
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,44 @@
+package planner
+
+import (
+	"sort"
+
+	"violin/internal/practice"
+)
+
+// KeyOrder ranks keys from easiest to hardest to play on the violin, using
+// the same values as the scale page key options.
+var KeyOrder = []string{"G", "D", "A", "C", "F", "Bb", "E", "Eb", "B", "G#/Ab", "C#/Db", "F#/Gb"}
+
+// Catalog lists every combination of the given scale, pitch, key and octave
+// values as item ids, easiest first: fewer octaves before more, major before
+// minor, and keys in KeyOrder. Scale and arpeggio of the same key are kept
+// next to each other.
+func Catalog(scales, pitches, keys, octaves []string) []string {
+	keys = append([]string(nil), keys...)
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keyRank(keys[i]) < keyRank(keys[j])
+	})
+
+	var items []string
+	for _, octave := range octaves {
+		for _, pitch := range pitches {
+			for _, key := range keys {
+				for _, scale := range scales {
+					items = append(items, practice.ScaleItem(scale, pitch, key, octave))
+				}
+			}
+		}
+	}
+	return items
+}
+
+// keyRank returns the position of key in KeyOrder, unknown keys rank last.
+func keyRank(key string) int {
+	for i, k := range KeyOrder {
+		if k == key {
+			return i
+		}
+	}
+	return len(KeyOrder)
+}
@@ -0,0 +1,350 @@
+// Package planner schedules scale and arpeggio practice with spaced
+// repetition, building a daily routine from a student's self-ratings and
+// practice history.
+package planner
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"violin/internal/jsonfile"
+	"violin/internal/practice"
+
+	"github.com/pkg/errors"
+)
+
+// dayLayout is the format used for due dates.
+const dayLayout = "2006-01-02"
+
+// Defaults for the size of a daily routine.
+const (
+	DefaultNewPerDay = 3
+	DefaultMaxItems  = 10
+)
+
+var (
+	// ErrInvalidRating is returned for ratings outside the SM-2 quality scale.
+	ErrInvalidRating = errors.New("invalid rating")
+
+	// ErrInvalidItem is returned when rating or pinning an unknown item.
+	ErrInvalidItem = errors.New("invalid item")
+)
+
+// Entry is one item in a daily routine.
+type Entry struct {
+	Item    string `json:"item"`
+	Title   string `json:"title"`
+	Pinned  bool   `json:"pinned"`
+	New     bool   `json:"new"`
+	Done    bool   `json:"done"`
+	Due     string `json:"due,omitempty"`
+	Minutes int    `json:"minutes_today"`
+}
+
+// Plan is the practice routine for one day.
+type Plan struct {
+	Date    string  `json:"date"`
+	Entries []Entry `json:"entries"`
+}
+
+// Remaining returns the number of entries not yet rated today.
+func (p Plan) Remaining() int {
+	var n int
+	for _, e := range p.Entries {
+		if !e.Done {
+			n++
+		}
+	}
+	return n
+}
+
+// schedule is the scheduling state of one owner.
+type schedule struct {
+	Cards map[string]*Card `json:"cards"`
+	Pins  []string         `json:"pins"`
+}
+
+// Store holds the schedules of every owner, using the same owner keys as the
+// practice log. It is safe for concurrent use and persists every change to
+// a JSON file.
+type Store struct {
+	mu        sync.Mutex
+	path      string
+	schedules map[string]*schedule
+
+	// NewPerDay is how many items the routine introduces each day and
+	// MaxItems caps the routine length, not counting pinned items.
+	NewPerDay int
+	MaxItems  int
+}
+
+// NewStore constructs a Store backed by the JSON file at path, loading any
+// schedules already saved there.
+func NewStore(path string) (*Store, error) {
+	s := Store{
+		path:      path,
+		NewPerDay: DefaultNewPerDay,
+		MaxItems:  DefaultMaxItems,
+	}
+	if err := jsonfile.Load(path, &s.schedules); err != nil {
+		return nil, errors.Wrap(err, "loading schedules")
+	}
+	if s.schedules == nil {
+		s.schedules = make(map[string]*schedule)
+	}
+	return &s, nil
+}
+
+// Rate records a self-rating for an item and reschedules it.
+func (s *Store) Rate(owner, item string, quality int, now time.Time) error {
+	if quality < MinQuality || quality > MaxQuality {
+		return errors.Wrapf(ErrInvalidRating, "quality %d", quality)
+	}
+	if _, err := practice.ParseItem(item); err != nil {
+		return errors.Wrap(ErrInvalidItem, err.Error())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sch := s.schedule(owner)
+	c, ok := sch.Cards[item]
+	if !ok {
+		c = &Card{Item: item}
+		sch.Cards[item] = c
+	}
+	c.review(quality, now)
+
+	if err := jsonfile.Save(s.path, s.schedules); err != nil {
+		return errors.Wrap(err, "saving schedules")
+	}
+	return nil
+}
+
+// Pins returns the items pinned to the owner's routine.
+func (s *Store) Pins(owner string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sch, ok := s.schedules[owner]
+	if !ok {
+		return nil
+	}
+	pins := make([]string, len(sch.Pins))
+	copy(pins, sch.Pins)
+	return pins
+}
+
+// SetPins replaces the items pinned to the owner's routine. Pinned items are
+// part of every daily routine regardless of their schedule, which lets a
+// teacher require a fixed list such as the scales for an exam.
+func (s *Store) SetPins(owner string, items []string) error {
+	pins := make([]string, 0, len(items))
+	seen := make(map[string]bool)
+	for _, item := range items {
+		if _, err := practice.ParseItem(item); err != nil {
+			return errors.Wrap(ErrInvalidItem, err.Error())
+		}
+		if !seen[item] {
+			seen[item] = true
+			pins = append(pins, item)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.schedule(owner).Pins = pins
+
+	if err := jsonfile.Save(s.path, s.schedules); err != nil {
+		return errors.Wrap(err, "saving schedules")
+	}
+	return nil
+}
+
+// Merge moves the schedule of one owner into another. Where both owners have
+// rated an item, the most recently rated card is kept.
+func (s *Store) Merge(from, to string) error {
+	if from == to {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	src, ok := s.schedules[from]
+	if !ok {
+		return nil
+	}
+	dst := s.schedule(to)
+	for item, c := range src.Cards {
+		if cur, ok := dst.Cards[item]; !ok || c.LastRated.After(cur.LastRated) {
+			dst.Cards[item] = c
+		}
+	}
+	for _, pin := range src.Pins {
+		if !contains(dst.Pins, pin) {
+			dst.Pins = append(dst.Pins, pin)
+		}
+	}
+	delete(s.schedules, from)
+
+	if err := jsonfile.Save(s.path, s.schedules); err != nil {
+		return errors.Wrap(err, "saving schedules")
+	}
+	return nil
+}
+
+// Today builds the owner's routine for the day of now from the catalog of
+// items that can be practiced and their practice history. Required items,
+// such as work assigned by a teacher, are pinned for the day on top of the
+// owner's own pins. The routine lists pinned items first, then items due for
+// review, most overdue first, then new items. Items rated earlier in the day stay in the routine marked as done so
+// the routine does not change while the student works through it.
+//
+// Items which have been played but never rated are introduced before items
+// which have never been played. Otherwise new items are taken in catalog
+// order, so the catalog should list easier items first.
+func (s *Store) Today(owner string, catalog, required []string, history []practice.Session, now time.Time) Plan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	today := startOfDay(now)
+	todayKey := today.Format(dayLayout)
+
+	played := make(map[string]time.Duration)
+	playedToday := make(map[string]time.Duration)
+	for _, ses := range history {
+		played[ses.Item] += ses.Duration()
+		if !ses.Start.In(now.Location()).Before(today) {
+			playedToday[ses.Item] += ses.Duration()
+		}
+	}
+
+	cards := make(map[string]*Card)
+	pins := append([]string(nil), required...)
+	if sch, ok := s.schedules[owner]; ok {
+		cards = sch.Cards
+		for _, pin := range sch.Pins {
+			if !contains(pins, pin) {
+				pins = append(pins, pin)
+			}
+		}
+	}
+
+	ratedToday := func(c *Card) bool {
+		return !c.LastRated.In(now.Location()).Before(today)
+	}
+
+	plan := Plan{Date: todayKey}
+	included := make(map[string]bool)
+	add := func(item string, pinned bool) {
+		included[item] = true
+		e := Entry{
+			Item:    item,
+			Title:   item,
+			Pinned:  pinned,
+			Minutes: int(playedToday[item] / time.Minute),
+		}
+		if it, err := practice.ParseItem(item); err == nil {
+			e.Title = it.String()
+		}
+		c, ok := cards[item]
+		switch {
+		case !ok:
+			e.New = true
+		default:
+			e.Due = c.Due
+			e.Done = ratedToday(c)
+			e.New = !c.FirstRated.In(now.Location()).Before(today)
+		}
+		plan.Entries = append(plan.Entries, e)
+	}
+
+	for _, item := range pins {
+		if !included[item] {
+			add(item, true)
+		}
+	}
+
+	// Items due for review, plus items already reviewed today.
+	var due []*Card
+	var introduced int
+	for _, c := range cards {
+		if included[c.Item] {
+			continue
+		}
+		if !c.FirstRated.In(now.Location()).Before(today) {
+			introduced++
+		}
+		if c.Due <= todayKey || ratedToday(c) {
+			due = append(due, c)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if due[i].Due != due[j].Due {
+			return due[i].Due < due[j].Due
+		}
+		if due[i].Ease != due[j].Ease {
+			return due[i].Ease < due[j].Ease
+		}
+		return due[i].Item < due[j].Item
+	})
+	for _, c := range due {
+		if len(plan.Entries)-len(pins) >= s.MaxItems && !ratedToday(c) {
+			continue
+		}
+		add(c.Item, false)
+	}
+
+	// New items, played but unrated ones first.
+	var fresh []string
+	for _, item := range catalog {
+		if _, ok := cards[item]; !ok && !included[item] {
+			fresh = append(fresh, item)
+		}
+	}
+	sort.SliceStable(fresh, func(i, j int) bool {
+		return played[fresh[i]] > 0 && played[fresh[j]] == 0
+	})
+	for _, item := range fresh {
+		if introduced >= s.NewPerDay || len(plan.Entries)-len(pins) >= s.MaxItems {
+			break
+		}
+		add(item, false)
+		introduced++
+	}
+
+	return plan
+}
+
+// schedule returns the owner's schedule, creating it when needed. The caller
+// must hold s.mu.
+func (s *Store) schedule(owner string) *schedule {
+	sch, ok := s.schedules[owner]
+	if !ok {
+		sch = &schedule{}
+		s.schedules[owner] = sch
+	}
+	if sch.Cards == nil {
+		sch.Cards = make(map[string]*Card)
+	}
+	return sch
+}
+
+// contains reports whether list holds s.
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// startOfDay returns midnight at the start of t's day.
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
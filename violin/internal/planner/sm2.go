@@ -0,0 +1,67 @@
+package planner
+
+import (
+	"math"
+	"time"
+)
+
+// Self-rating bounds, following the SM-2 quality scale where 0 means the
+// item could not be played at all and 5 means it was played perfectly.
+const (
+	MinQuality  = 0
+	MaxQuality  = 5
+	PassQuality = 3
+)
+
+// DefaultEase is the ease factor given to an item the first time it is
+// rated, and MinEase is the lowest the ease factor is allowed to fall.
+const (
+	DefaultEase = 2.5
+	MinEase     = 1.3
+)
+
+// Card holds the scheduling state of one practice item.
+type Card struct {
+	Item        string    `json:"item"`
+	Ease        float64   `json:"ease"`
+	Interval    int       `json:"interval"`
+	Repetitions int       `json:"repetitions"`
+	Due         string    `json:"due"`
+	LastQuality int       `json:"last_quality"`
+	FirstRated  time.Time `json:"first_rated"`
+	LastRated   time.Time `json:"last_rated"`
+}
+
+// review applies a self-rating to the card using the SM-2 algorithm. Ratings
+// below PassQuality start the item again from a one day interval.
+func (c *Card) review(quality int, now time.Time) {
+	if c.Ease == 0 {
+		c.Ease = DefaultEase
+		c.FirstRated = now
+	}
+
+	if quality >= PassQuality {
+		switch c.Repetitions {
+		case 0:
+			c.Interval = 1
+		case 1:
+			c.Interval = 6
+		default:
+			c.Interval = int(math.Round(float64(c.Interval) * c.Ease))
+		}
+		c.Repetitions++
+	} else {
+		c.Repetitions = 0
+		c.Interval = 1
+	}
+
+	q := float64(MaxQuality - quality)
+	c.Ease += 0.1 - q*(0.08+q*0.02)
+	if c.Ease < MinEase {
+		c.Ease = MinEase
+	}
+
+	c.LastQuality = quality
+	c.LastRated = now
+	c.Due = startOfDay(now).AddDate(0, 0, c.Interval).Format(dayLayout)
+}
@@ -0,0 +1,224 @@
+package practice
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"violin/internal/theory"
+
+	"github.com/pkg/errors"
+)
+
+// Item kinds that can be practiced.
+const (
+	KindScale           = "Scale"
+	KindArpeggio        = "Arpeggio"
+	KindFirstInversion  = "Inversion1"
+	KindSecondInversion = "Inversion2"
+	KindDominant7       = "Dominant7"
+	KindDiminished7     = "Diminished7"
+	KindAugmented       = "Augmented"
+	KindThirds          = "Thirds"
+	KindSixths          = "Sixths"
+	KindOctaves         = "Octaves"
+	KindFingeredOctaves = "FingeredOctaves"
+	KindTenths          = "Tenths"
+	KindDuet            = "Duet"
+)
+
+// ScaleKinds lists the kinds the scale page plays, in the order it offers
+// them.
+var ScaleKinds = []string{
+	KindScale,
+	KindArpeggio,
+	KindFirstInversion,
+	KindSecondInversion,
+	KindDominant7,
+	KindDiminished7,
+	KindAugmented,
+	KindThirds,
+	KindSixths,
+	KindOctaves,
+	KindFingeredOctaves,
+	KindTenths,
+}
+
+// Keys lists the keys items can be in, named the way the scale page names
+// them.
+var Keys = []string{"A", "Bb", "B", "C", "C#/Db", "D", "Eb", "E", "F", "F#/Gb", "G", "G#/Ab"}
+
+// Pitched reports whether a kind differs between major and minor keys.
+// Dominant and diminished sevenths and augmented arpeggios sound the same in
+// both, their items are always major.
+func Pitched(kind string) bool {
+	switch kind {
+	case KindDominant7, KindDiminished7, KindAugmented:
+		return false
+	}
+	return true
+}
+
+// KindName returns the display name of a scale page kind in the pitch, such
+// as "Minor Arpeggio, 1st Inversion" or "Dominant 7th".
+func KindName(kind, pitch string) string {
+	switch kind {
+	case KindFirstInversion:
+		return pitch + " Arpeggio, 1st Inversion"
+	case KindSecondInversion:
+		return pitch + " Arpeggio, 2nd Inversion"
+	case KindDominant7:
+		return "Dominant 7th"
+	case KindDiminished7:
+		return "Diminished 7th"
+	case KindAugmented:
+		return "Augmented Arpeggio"
+	case KindFingeredOctaves:
+		return pitch + " Scale in Fingered Octaves"
+	case KindThirds, KindSixths, KindOctaves, KindTenths:
+		return pitch + " Scale in " + kind
+	}
+	return pitch + " " + kind
+}
+
+// Title names a scale page exercise in a key, such as "C# Minor Scale" or
+// "Dominant 7th in the Key of D". Sevenths and augmented arpeggios name the
+// key they are in, or the note they start on, after the exercise.
+func Title(kind, pitch, key string) string {
+	switch kind {
+	case KindDominant7:
+		return KindName(kind, pitch) + " in the Key of " + key
+	case KindDiminished7, KindAugmented:
+		return KindName(kind, pitch) + " Starting on " + key
+	}
+	return key + " " + KindName(kind, pitch)
+}
+
+// Item identifies something a student can practice: a scale or arpeggio in
+// a given pitch, key and octave, or a duet in a given key.
+type Item struct {
+	Kind   string
+	Pitch  string
+	Key    string
+	Octave string
+}
+
+// ScaleItem returns the id of a scale or arpeggio using the same values the
+// scale page form posts.
+func ScaleItem(scale, pitch, key, octave string) string {
+	return Item{Kind: scale, Pitch: pitch, Key: key, Octave: octave}.ID()
+}
+
+// DuetItem returns the id of the duet in the given key.
+func DuetItem(key string) string {
+	return Item{Kind: KindDuet, Key: key}.ID()
+}
+
+// ID returns the canonical string form of the item, such as
+// "scale/major/cs-db/2" or "duet/g".
+func (it Item) ID() string {
+	if it.Kind == KindDuet {
+		return "duet/" + KeySlug(it.Key)
+	}
+	return strings.Join([]string{
+		strings.ToLower(it.Kind),
+		strings.ToLower(it.Pitch),
+		KeySlug(it.Key),
+		it.Octave,
+	}, "/")
+}
+
+// String returns a human readable description of the item.
+func (it Item) String() string {
+	if it.Kind == KindDuet {
+		return fmt.Sprintf("%s Major Duet", it.Key)
+	}
+	s := Title(it.Kind, it.Pitch, it.Key)
+	if it.Octave != "" {
+		s += fmt.Sprintf(", %s Octave", it.Octave)
+	}
+	return s
+}
+
+// ParseItem converts an item id back into an Item. Only items the site
+// offers parse: the key must be one of Keys, the octaves must fit the violin
+// and exercises that are only played as major must be major.
+func ParseItem(id string) (Item, error) {
+	parts := strings.Split(id, "/")
+	switch {
+	case len(parts) == 2 && parts[0] == "duet":
+		key := keyFromSlug(parts[1])
+		if !isKey(key) {
+			break
+		}
+		return Item{Kind: KindDuet, Key: key}, nil
+	case len(parts) == 4:
+		kind := scaleKind(parts[0])
+		if kind == "" || parts[1] != "major" && parts[1] != "minor" {
+			break
+		}
+		it := Item{
+			Kind:   kind,
+			Pitch:  title(parts[1]),
+			Key:    keyFromSlug(parts[2]),
+			Octave: parts[3],
+		}
+		octave, err := strconv.Atoi(it.Octave)
+		if !isKey(it.Key) || !Pitched(kind) && it.Pitch != "Major" ||
+			err != nil || it.Octave != strconv.Itoa(octave) ||
+			octave < 1 || octave > theory.MaxOctaves(it.Key, it.Pitch, it.Kind) {
+			break
+		}
+		return it, nil
+	}
+	return Item{}, errors.Errorf("invalid item %q", id)
+}
+
+// isKey reports whether key is one of Keys.
+func isKey(key string) bool {
+	for _, k := range Keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// scaleKind returns the scale page kind with the lower case id, or "" if
+// there is none.
+func scaleKind(id string) string {
+	for _, kind := range ScaleKinds {
+		if strings.ToLower(kind) == id {
+			return kind
+		}
+	}
+	return ""
+}
+
+// KeySlug turns a key such as "C#/Db" into the url friendly "cs-db" item
+// ids use.
+func KeySlug(key string) string {
+	key = strings.ToLower(key)
+	key = strings.ReplaceAll(key, "#", "s")
+	return strings.ReplaceAll(key, "/", "-")
+}
+
+// keyFromSlug reverses KeySlug.
+func keyFromSlug(slug string) string {
+	names := strings.Split(slug, "-")
+	for i, name := range names {
+		if len(name) > 1 && name[1] == 's' {
+			name = name[:1] + "#" + name[2:]
+		}
+		names[i] = title(name)
+	}
+	return strings.Join(names, "/")
+}
+
+// title upper cases the first letter of s.
+func title(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
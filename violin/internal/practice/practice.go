@@ -0,0 +1,216 @@
+// Package practice records practice sessions and summarises them into daily,
+// weekly and per item totals.
+package practice
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"violin/internal/jsonfile"
+
+	"github.com/pkg/errors"
+)
+
+// Event kinds posted by the browser when playback starts and stops.
+const (
+	EventStart = "start"
+	EventStop  = "stop"
+)
+
+// MaxSession caps the length of a single session so a tab left open with
+// playback running does not count as hours of practice.
+const MaxSession = 2 * time.Hour
+
+// MaxDailySessions caps how many sessions an owner logs in a day, so that
+// events posted in a loop cannot grow the log without end.
+const MaxDailySessions = 200
+
+// Errors returned by Record.
+var (
+	ErrInvalidEvent = errors.New("invalid event")
+	ErrTooMany      = errors.New("too many sessions today")
+)
+
+// Session is a period of time spent practicing a single item.
+type Session struct {
+	Item  string    `json:"item"`
+	Start time.Time `json:"start"`
+	Stop  time.Time `json:"stop"`
+}
+
+// Duration returns how long the session lasted.
+func (s Session) Duration() time.Duration {
+	return s.Stop.Sub(s.Start)
+}
+
+// practiceLog holds the sessions of one owner, plus any sessions which have
+// started but not stopped yet.
+type practiceLog struct {
+	Sessions []Session            `json:"sessions"`
+	Open     map[string]time.Time `json:"open,omitempty"`
+}
+
+// Store holds the practice logs of every owner. An owner is either an
+// account or an anonymous visitor identified by a cookie. It is safe for
+// concurrent use and persists every change to the JSON file of the owner
+// whose log changed, so that a busy log does not rewrite everyone else's.
+type Store struct {
+	mu   sync.Mutex
+	dir  string
+	logs map[string]*practiceLog
+}
+
+// NewStore constructs a Store backed by a JSON file per owner in dir,
+// loading any logs already saved there.
+func NewStore(dir string) (*Store, error) {
+	s := Store{dir: dir, logs: make(map[string]*practiceLog)}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, errors.Wrap(err, "listing practice logs")
+	}
+	for _, path := range paths {
+		owner, err := hex.DecodeString(strings.TrimSuffix(filepath.Base(path), ".json"))
+		if err != nil {
+			continue
+		}
+		var l practiceLog
+		if err := jsonfile.Load(path, &l); err != nil {
+			return nil, errors.Wrap(err, "loading practice logs")
+		}
+		s.logs[string(owner)] = &l
+	}
+	return &s, nil
+}
+
+// Record applies a start or stop event for an item to the owner's log.
+// Starting an item which is already running is ignored, as is stopping an
+// item which was never started. Once the owner has logged MaxDailySessions
+// sessions that started the same day, stopping another fails with
+// ErrTooMany.
+func (s *Store) Record(owner, item, kind string, at time.Time) error {
+	if _, err := ParseItem(item); err != nil {
+		return errors.Wrap(ErrInvalidEvent, err.Error())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l := s.log(owner)
+	switch kind {
+	case EventStart:
+		if _, ok := l.Open[item]; ok {
+			return nil
+		}
+		l.Open[item] = at.UTC()
+	case EventStop:
+		start, ok := l.Open[item]
+		if !ok {
+			return nil
+		}
+		delete(l.Open, item)
+		stop := at.UTC()
+		if stop.Sub(start) > MaxSession {
+			stop = start.Add(MaxSession)
+		}
+		if !stop.After(start) {
+			return nil
+		}
+		if l.sessionsSince(startOfDay(start)) >= MaxDailySessions {
+			return errors.Wrapf(ErrTooMany, "owner %s", owner)
+		}
+		l.Sessions = append(l.Sessions, Session{Item: item, Start: start, Stop: stop})
+	default:
+		return errors.Wrapf(ErrInvalidEvent, "unknown event %q", kind)
+	}
+
+	return s.save(owner)
+}
+
+// Sessions returns a copy of the completed sessions in the owner's log.
+func (s *Store) Sessions(owner string) []Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.logs[owner]
+	if !ok {
+		return nil
+	}
+	sessions := make([]Session, len(l.Sessions))
+	copy(sessions, l.Sessions)
+	return sessions
+}
+
+// Merge moves every session logged by one owner into the log of another.
+// It is used to keep the anonymous practice of a visitor when they log in.
+func (s *Store) Merge(from, to string) error {
+	if from == to {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	src, ok := s.logs[from]
+	if !ok {
+		return nil
+	}
+	dst := s.log(to)
+	dst.Sessions = append(dst.Sessions, src.Sessions...)
+	for item, start := range src.Open {
+		if _, ok := dst.Open[item]; !ok {
+			dst.Open[item] = start
+		}
+	}
+	delete(s.logs, from)
+
+	if err := s.save(to); err != nil {
+		return err
+	}
+	if err := os.Remove(s.file(from)); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "removing practice log")
+	}
+	return nil
+}
+
+// sessionsSince counts the sessions of the log that started at t or later.
+// Sessions are logged in the order they stop, so the count stops at the
+// first earlier one.
+func (l *practiceLog) sessionsSince(t time.Time) int {
+	n := 0
+	for i := len(l.Sessions) - 1; i >= 0 && !l.Sessions[i].Start.Before(t); i-- {
+		n++
+	}
+	return n
+}
+
+// log returns the owner's log, creating it when needed. The caller must hold
+// s.mu.
+func (s *Store) log(owner string) *practiceLog {
+	l, ok := s.logs[owner]
+	if !ok {
+		l = &practiceLog{}
+		s.logs[owner] = l
+	}
+	if l.Open == nil {
+		l.Open = make(map[string]time.Time)
+	}
+	return l
+}
+
+// save writes the owner's log to its file. The caller must hold s.mu.
+func (s *Store) save(owner string) error {
+	if err := jsonfile.Save(s.file(owner), s.logs[owner]); err != nil {
+		return errors.Wrap(err, "saving practice log")
+	}
+	return nil
+}
+
+// file returns the path of the owner's log. Owner keys are hex encoded, so
+// whatever they hold cannot escape the directory.
+func (s *Store) file(owner string) string {
+	return filepath.Join(s.dir, hex.EncodeToString([]byte(owner))+".json")
+}
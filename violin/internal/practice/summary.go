@@ -0,0 +1,206 @@
+package practice
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// HeatmapWeeks is the number of weeks shown in the calendar heatmap.
+const HeatmapWeeks = 53
+
+// dayLayout is the format used for day keys.
+const dayLayout = "2006-01-02"
+
+// Total is the amount of practice for a day, week or item.
+type Total struct {
+	Key     string        `json:"key"`
+	Label   string        `json:"label"`
+	Seconds int64         `json:"seconds"`
+	Time    time.Duration `json:"-"`
+}
+
+// Minutes returns the total rounded down to whole minutes.
+func (t Total) Minutes() int {
+	return int(t.Time / time.Minute)
+}
+
+// HeatCell is one day in the calendar heatmap.
+type HeatCell struct {
+	Date    string `json:"date"`
+	Minutes int    `json:"minutes"`
+	Level   int    `json:"level"`
+	Future  bool   `json:"future,omitempty"`
+}
+
+// Summary aggregates a practice log.
+type Summary struct {
+	TotalSeconds  int64        `json:"total_seconds"`
+	Total         Total        `json:"-"`
+	Days          []Total      `json:"days"`
+	Weeks         []Total      `json:"weeks"`
+	Items         []Total      `json:"items"`
+	CurrentStreak int          `json:"current_streak"`
+	LongestStreak int          `json:"longest_streak"`
+	Heatmap       [][]HeatCell `json:"heatmap"`
+}
+
+// Summarize totals the sessions per day, ISO week and item, works out the
+// practice streaks and builds a heatmap of the weeks leading up to now. Days
+// are calculated in the location of now.
+func Summarize(sessions []Session, now time.Time) Summary {
+	loc := now.Location()
+	days := make(map[string]time.Duration)
+	weeks := make(map[string]time.Duration)
+	items := make(map[string]time.Duration)
+	var total time.Duration
+
+	for _, s := range sessions {
+		d := s.Duration()
+		start := s.Start.In(loc)
+		year, week := start.ISOWeek()
+
+		total += d
+		days[start.Format(dayLayout)] += d
+		weeks[fmt.Sprintf("%04d-W%02d", year, week)] += d
+		items[s.Item] += d
+	}
+
+	sum := Summary{
+		TotalSeconds: int64(total / time.Second),
+		Total:        newTotal("total", "Total", total),
+		Days:         totals(days, func(k string) string { return k }),
+		Weeks:        totals(weeks, func(k string) string { return k }),
+		Items: totals(items, func(k string) string {
+			if it, err := ParseItem(k); err == nil {
+				return it.String()
+			}
+			return k
+		}),
+		Heatmap: heatmap(days, now),
+	}
+
+	// Most recent days and weeks first, most practiced items first.
+	sort.Slice(sum.Days, func(i, j int) bool { return sum.Days[i].Key > sum.Days[j].Key })
+	sort.Slice(sum.Weeks, func(i, j int) bool { return sum.Weeks[i].Key > sum.Weeks[j].Key })
+	sort.Slice(sum.Items, func(i, j int) bool {
+		if sum.Items[i].Time == sum.Items[j].Time {
+			return sum.Items[i].Key < sum.Items[j].Key
+		}
+		return sum.Items[i].Time > sum.Items[j].Time
+	})
+	sum.CurrentStreak, sum.LongestStreak = streaks(days, now)
+
+	return sum
+}
+
+// newTotal constructs a Total.
+func newTotal(key, label string, d time.Duration) Total {
+	return Total{Key: key, Label: label, Seconds: int64(d / time.Second), Time: d}
+}
+
+// totals converts a map of durations into a slice of totals.
+func totals(m map[string]time.Duration, label func(string) string) []Total {
+	totals := make([]Total, 0, len(m))
+	for k, d := range m {
+		totals = append(totals, newTotal(k, label(k), d))
+	}
+	return totals
+}
+
+// streaks returns the number of consecutive days practiced up to now and the
+// longest run of consecutive days ever practiced. The current streak is kept
+// alive until the end of today, so a student who practiced yesterday but not
+// yet today has not lost it.
+func streaks(days map[string]time.Duration, now time.Time) (int, int) {
+	if len(days) == 0 {
+		return 0, 0
+	}
+
+	keys := make([]string, 0, len(days))
+	for k := range days {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var longest, run int
+	var prev time.Time
+	for _, k := range keys {
+		day, err := time.ParseInLocation(dayLayout, k, now.Location())
+		if err != nil {
+			continue
+		}
+		if run > 0 && prev.AddDate(0, 0, 1).Equal(day) {
+			run++
+		} else {
+			run = 1
+		}
+		if run > longest {
+			longest = run
+		}
+		prev = day
+	}
+
+	today := startOfDay(now)
+	current := 0
+	day := today
+	if _, ok := days[day.Format(dayLayout)]; !ok {
+		day = day.AddDate(0, 0, -1)
+	}
+	for {
+		if _, ok := days[day.Format(dayLayout)]; !ok {
+			break
+		}
+		current++
+		day = day.AddDate(0, 0, -1)
+	}
+
+	return current, longest
+}
+
+// heatmap lays out the daily totals as a calendar with one row per weekday,
+// starting on Monday, and one column per week ending with the current week.
+func heatmap(days map[string]time.Duration, now time.Time) [][]HeatCell {
+	today := startOfDay(now)
+	offset := (int(today.Weekday()) + 6) % 7
+	first := today.AddDate(0, 0, -offset-7*(HeatmapWeeks-1))
+
+	rows := make([][]HeatCell, 7)
+	for wd := range rows {
+		rows[wd] = make([]HeatCell, HeatmapWeeks)
+		for w := 0; w < HeatmapWeeks; w++ {
+			day := first.AddDate(0, 0, 7*w+wd)
+			key := day.Format(dayLayout)
+			minutes := int(days[key] / time.Minute)
+			rows[wd][w] = HeatCell{
+				Date:    key,
+				Minutes: minutes,
+				Level:   level(minutes),
+				Future:  day.After(today),
+			}
+		}
+	}
+	return rows
+}
+
+// level buckets minutes of practice into heatmap intensities 0 to 4.
+func level(minutes int) int {
+	switch {
+	case minutes <= 0:
+		return 0
+	case minutes < 10:
+		return 1
+	case minutes < 20:
+		return 2
+	case minutes < 40:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// startOfDay returns midnight at the start of t's day.
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
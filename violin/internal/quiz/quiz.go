@@ -0,0 +1,237 @@
+// Package quiz generates ear training questions on intervals, scale types and
+// tuning against a drone, scores the answers and adapts the difficulty of
+// each kind of question to how well a student is doing.
+package quiz
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Kinds of question.
+const (
+	KindInterval = "interval" // name the interval between two notes
+	KindScale    = "scale"    // name the type of a scale or arpeggio
+	KindTuning   = "tuning"   // tell whether a note is in tune with a drone
+)
+
+// Kinds lists the kinds of question in the order the quiz page offers them.
+var Kinds = []string{KindInterval, KindScale, KindTuning}
+
+// Difficulty levels. Every kind starts at MinLevel.
+const (
+	MinLevel = 1
+	MaxLevel = 5
+)
+
+// Answers of tuning questions.
+const (
+	Flat   = "flat"
+	InTune = "in tune"
+	Sharp  = "sharp"
+)
+
+// Keys lists the keys questions are played in, named the way the scale page
+// names them.
+var Keys = []string{"A", "Bb", "B", "C", "C#/Db", "D", "Eb", "E", "F", "F#/Gb", "G", "G#/Ab"}
+
+// ErrInvalidKind is returned for unknown kinds of question.
+var ErrInvalidKind = errors.New("invalid kind")
+
+// Choice is one of the answers offered to a question.
+type Choice struct {
+	Value string `json:"value"`
+	Text  string `json:"text"`
+}
+
+// Question is an ear training question. The answer stays on the server, the
+// rest describes what to play: Notes for intervals, the exercise for scale
+// types and the drone Key with a note Note semitones above it played Cents
+// off for tuning.
+type Question struct {
+	ID      string    `json:"id"`
+	Kind    string    `json:"kind"`
+	Level   int       `json:"level"`
+	Prompt  string    `json:"prompt"`
+	Choices []Choice  `json:"choices"`
+	Answer  string    `json:"answer"`
+	Asked   time.Time `json:"asked"`
+
+	Notes    []int `json:"notes,omitempty"`
+	Together bool  `json:"together,omitempty"`
+
+	Key     string `json:"key,omitempty"`
+	Pitch   string `json:"pitch,omitempty"`
+	Scale   string `json:"scale,omitempty"`
+	Melodic bool   `json:"melodic,omitempty"`
+
+	Note  int     `json:"note,omitempty"`
+	Cents float64 `json:"cents,omitempty"`
+}
+
+// Text returns the text of the choice with the value, or the value itself
+// when no choice has it.
+func (q Question) Text(value string) string {
+	for _, c := range q.Choices {
+		if c.Value == value {
+			return c.Text
+		}
+	}
+	return value
+}
+
+// New generates a random question of the kind at the level.
+func New(kind string, level int, rnd *rand.Rand, now time.Time) (Question, error) {
+	if level < MinLevel {
+		level = MinLevel
+	}
+	if level > MaxLevel {
+		level = MaxLevel
+	}
+	q := Question{
+		ID:    fmt.Sprintf("%016x", rnd.Uint64()),
+		Kind:  kind,
+		Level: level,
+		Asked: now,
+	}
+
+	switch kind {
+	case KindInterval:
+		newInterval(&q, rnd)
+	case KindScale:
+		newScale(&q, rnd)
+	case KindTuning:
+		newTuning(&q, rnd)
+	default:
+		return Question{}, errors.Wrapf(ErrInvalidKind, "kind %q", kind)
+	}
+	return q, nil
+}
+
+// interval is an interval and the level it is first asked at.
+type interval struct {
+	semitones int
+	name      string
+	level     int
+}
+
+// intervals are introduced from the most to the least distinct, and offered
+// from the smallest to the largest.
+var intervals = []interval{
+	{1, "Minor 2nd", 3},
+	{2, "Major 2nd", 2},
+	{3, "Minor 3rd", 3},
+	{4, "Major 3rd", 1},
+	{5, "Perfect 4th", 2},
+	{6, "Tritone", 5},
+	{7, "Perfect 5th", 1},
+	{8, "Minor 6th", 3},
+	{9, "Major 6th", 2},
+	{10, "Minor 7th", 4},
+	{11, "Major 7th", 4},
+	{12, "Octave", 1},
+}
+
+// newInterval asks for the interval between two notes on the violin. They
+// rise one after the other until level 4, which also has them fall, and
+// level 5 sometimes plays them together.
+func newInterval(q *Question, rnd *rand.Rand) {
+	var pool []interval
+	for _, i := range intervals {
+		if i.level <= q.Level {
+			pool = append(pool, i)
+			q.Choices = append(q.Choices, Choice{Value: fmt.Sprint(i.semitones), Text: i.name})
+		}
+	}
+	i := pool[rnd.Intn(len(pool))]
+	q.Answer = fmt.Sprint(i.semitones)
+
+	low := lowestNote + rnd.Intn(highestNote-lowestNote-12)
+	q.Notes = []int{low, low + i.semitones}
+	if q.Level >= 4 && rnd.Intn(2) == 0 {
+		q.Notes[0], q.Notes[1] = q.Notes[1], q.Notes[0]
+	}
+	q.Together = q.Level >= 5 && rnd.Intn(3) == 0
+	q.Prompt = "Which interval do you hear?"
+}
+
+// Range of the notes of interval questions, G3 to E6.
+const (
+	lowestNote  = 55
+	highestNote = 88
+)
+
+// scaleType is an exercise scale type questions play and the level it is
+// first asked at.
+type scaleType struct {
+	value, name  string
+	scale, pitch string
+	melodic      bool
+	level        int
+}
+
+// scaleTypes are introduced from major against minor to the arpeggios of
+// seventh chords.
+var scaleTypes = []scaleType{
+	{"major", "Major Scale", "Scale", "Major", false, 1},
+	{"harmonic", "Harmonic Minor Scale", "Scale", "Minor", false, 1},
+	{"melodic", "Melodic Minor Scale", "Scale", "Minor", true, 2},
+	{"majorarp", "Major Arpeggio", "Arpeggio", "Major", false, 3},
+	{"minorarp", "Minor Arpeggio", "Arpeggio", "Minor", false, 3},
+	{"dom7", "Dominant 7th Arpeggio", "Dominant7", "Major", false, 4},
+	{"dim7", "Diminished 7th Arpeggio", "Diminished7", "Major", false, 4},
+	{"aug", "Augmented Arpeggio", "Augmented", "Major", false, 5},
+}
+
+// newScale asks for the type of a one octave scale or arpeggio in a random
+// key.
+func newScale(q *Question, rnd *rand.Rand) {
+	var pool []scaleType
+	for _, t := range scaleTypes {
+		if t.level <= q.Level {
+			pool = append(pool, t)
+			q.Choices = append(q.Choices, Choice{Value: t.value, Text: t.name})
+		}
+	}
+	t := pool[rnd.Intn(len(pool))]
+	q.Answer = t.value
+	q.Key = Keys[rnd.Intn(len(Keys))]
+	q.Scale, q.Pitch, q.Melodic = t.scale, t.pitch, t.melodic
+	q.Prompt = "Which scale or arpeggio do you hear?"
+}
+
+// tuningCents is how far off the note of a tuning question is at each
+// level, from an obvious quarter tone down to what a string player learns to
+// hear with a drone.
+var tuningCents = []float64{40, 25, 15, 10, 6}
+
+// tuningIntervals are the notes above the drone tuning questions play: the
+// tonic, its fifth and its octave, which ring with the drone when in tune.
+var tuningIntervals = []int{0, 7, 12}
+
+// newTuning asks whether a note over a drone of a random key is flat, in
+// tune or sharp.
+func newTuning(q *Question, rnd *rand.Rand) {
+	q.Choices = []Choice{
+		{Value: Flat, Text: "Flat"},
+		{Value: InTune, Text: "In tune"},
+		{Value: Sharp, Text: "Sharp"},
+	}
+	q.Key = Keys[rnd.Intn(len(Keys))]
+	q.Pitch = "Major"
+	q.Note = tuningIntervals[rnd.Intn(len(tuningIntervals))]
+
+	cents := tuningCents[q.Level-MinLevel]
+	switch rnd.Intn(3) {
+	case 0:
+		q.Answer, q.Cents = Flat, -cents
+	case 1:
+		q.Answer = InTune
+	default:
+		q.Answer, q.Cents = Sharp, cents
+	}
+	q.Prompt = "Is the note flat, in tune or sharp against the drone?"
+}
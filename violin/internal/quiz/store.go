@@ -0,0 +1,247 @@
+package quiz
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"violin/internal/jsonfile"
+
+	"github.com/pkg/errors"
+)
+
+// Levels move up after upStreak right answers in a row and down after
+// downStreak wrong ones.
+const (
+	upStreak   = 3
+	downStreak = 2
+)
+
+// ErrNoQuestion is returned when answering a question that was never asked
+// or has been answered already.
+var ErrNoQuestion = errors.New("no such question")
+
+// Stats is how an owner is doing at one kind of question. Streak counts the
+// answers in a row that were right, or wrong when negative.
+type Stats struct {
+	Level   int `json:"level"`
+	Streak  int `json:"streak"`
+	Asked   int `json:"asked"`
+	Correct int `json:"correct"`
+}
+
+// Percent returns the share of answers that were right.
+func (s Stats) Percent() int {
+	if s.Asked == 0 {
+		return 0
+	}
+	return s.Correct * 100 / s.Asked
+}
+
+// Result is the score of an answer.
+type Result struct {
+	Correct bool   `json:"correct"`
+	Answer  string `json:"answer"`
+	Text    string `json:"text"`
+	Stats   Stats  `json:"stats"`
+	Moved   int    `json:"moved"` // 1 when the level went up, -1 when down
+}
+
+// record holds the quiz state of one owner: the stats of each kind and the
+// question of each kind waiting for an answer.
+type record struct {
+	Stats   map[string]*Stats   `json:"stats"`
+	Pending map[string]Question `json:"pending,omitempty"`
+}
+
+// Store holds the quiz state of every owner, using the same owner keys as
+// the practice log. It is safe for concurrent use and persists every change
+// to a JSON file.
+type Store struct {
+	mu      sync.Mutex
+	path    string
+	records map[string]*record
+	rnd     *rand.Rand
+}
+
+// NewStore constructs a Store backed by the JSON file at path, loading any
+// quiz state already saved there.
+func NewStore(path string) (*Store, error) {
+	s := Store{
+		path: path,
+		rnd:  rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	if err := jsonfile.Load(path, &s.records); err != nil {
+		return nil, errors.Wrap(err, "loading quizzes")
+	}
+	if s.records == nil {
+		s.records = make(map[string]*record)
+	}
+	return &s, nil
+}
+
+// Next asks the owner a new question of the kind at their level, replacing
+// any question of the kind left unanswered.
+func (s *Store) Next(owner, kind string, now time.Time) (Question, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec := s.record(owner)
+	q, err := New(kind, rec.stats(kind).Level, s.rnd, now)
+	if err != nil {
+		return Question{}, err
+	}
+	rec.Pending[kind] = q
+
+	if err := jsonfile.Save(s.path, s.records); err != nil {
+		return Question{}, errors.Wrap(err, "saving quizzes")
+	}
+	return q, nil
+}
+
+// Question returns the owner's unanswered question with the id.
+func (s *Store) Question(owner, id string) (Question, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.pending(owner, id)
+}
+
+// Answer scores the owner's answer to the question with the id and adapts
+// the level of its kind.
+func (s *Store) Answer(owner, id, answer string) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q, ok := s.pending(owner, id)
+	if !ok {
+		return Result{}, ErrNoQuestion
+	}
+	rec := s.record(owner)
+	delete(rec.Pending, q.Kind)
+
+	st := rec.stats(q.Kind)
+	res := Result{Correct: answer == q.Answer, Answer: q.Answer, Text: q.Text(q.Answer)}
+	st.Asked++
+	if res.Correct {
+		st.Correct++
+		if st.Streak < 0 {
+			st.Streak = 0
+		}
+		st.Streak++
+		if st.Streak >= upStreak && st.Level < MaxLevel {
+			st.Level++
+			st.Streak = 0
+			res.Moved = 1
+		}
+	} else {
+		if st.Streak > 0 {
+			st.Streak = 0
+		}
+		st.Streak--
+		if st.Streak <= -downStreak && st.Level > MinLevel {
+			st.Level--
+			st.Streak = 0
+			res.Moved = -1
+		}
+	}
+	res.Stats = *st
+
+	if err := jsonfile.Save(s.path, s.records); err != nil {
+		return Result{}, errors.Wrap(err, "saving quizzes")
+	}
+	return res, nil
+}
+
+// Stats returns how the owner is doing at each kind of question, in the
+// order of Kinds.
+func (s *Store) Stats(owner string) []Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := make([]Stats, len(Kinds))
+	for i, kind := range Kinds {
+		stats[i] = Stats{Level: MinLevel}
+		if rec, ok := s.records[owner]; ok {
+			if st, ok := rec.Stats[kind]; ok {
+				stats[i] = *st
+			}
+		}
+	}
+	return stats
+}
+
+// Merge moves the quiz state of one owner into another. Where both owners
+// have answered a kind of question, their counts are added up and the
+// higher level is kept.
+func (s *Store) Merge(from, to string) error {
+	if from == to {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	src, ok := s.records[from]
+	if !ok {
+		return nil
+	}
+	dst := s.record(to)
+	for kind, st := range src.Stats {
+		cur := dst.stats(kind)
+		if st.Level > cur.Level {
+			cur.Level, cur.Streak = st.Level, st.Streak
+		}
+		cur.Asked += st.Asked
+		cur.Correct += st.Correct
+	}
+	delete(s.records, from)
+
+	if err := jsonfile.Save(s.path, s.records); err != nil {
+		return errors.Wrap(err, "saving quizzes")
+	}
+	return nil
+}
+
+// pending returns the owner's unanswered question with the id. The caller
+// must hold the lock.
+func (s *Store) pending(owner, id string) (Question, bool) {
+	rec, ok := s.records[owner]
+	if !ok {
+		return Question{}, false
+	}
+	for _, q := range rec.Pending {
+		if q.ID == id {
+			return q, true
+		}
+	}
+	return Question{}, false
+}
+
+// record returns the owner's record, creating it on first use. The caller
+// must hold the lock.
+func (s *Store) record(owner string) *record {
+	rec, ok := s.records[owner]
+	if !ok {
+		rec = &record{}
+		s.records[owner] = rec
+	}
+	if rec.Stats == nil {
+		rec.Stats = make(map[string]*Stats)
+	}
+	if rec.Pending == nil {
+		rec.Pending = make(map[string]Question)
+	}
+	return rec
+}
+
+// stats returns the record's stats of the kind, starting at the lowest
+// level.
+func (r *record) stats(kind string) *Stats {
+	st, ok := r.Stats[kind]
+	if !ok {
+		st = &Stats{Level: MinLevel}
+		r.Stats[kind] = st
+	}
+	return st
+}
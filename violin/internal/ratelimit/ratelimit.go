@@ -0,0 +1,89 @@
+// Package ratelimit limits how often each client calls the server with a
+// token bucket per client.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepEvery is how often buckets left full, whose clients have gone
+// quiet, are forgotten.
+const sweepEvery = time.Minute
+
+// bucket holds the tokens a client has left and when it was last filled.
+type bucket struct {
+	tokens float64
+	filled time.Time
+}
+
+// Limiter lets each client make burst calls at once, then rate calls a
+// second. It is safe for concurrent use.
+type Limiter struct {
+	rate  float64
+	burst float64
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+// New constructs a Limiter refilling rate tokens a second up to burst. It
+// returns nil, a Limiter allowing every call, when rate or burst is not
+// positive.
+func New(rate float64, burst int) *Limiter {
+	if rate <= 0 || burst <= 0 {
+		return nil
+	}
+	return &Limiter{rate: rate, burst: float64(burst), buckets: make(map[string]*bucket)}
+}
+
+// Allow takes a token from the bucket of client at now. When there is none
+// left it reports false and how long the client has to wait for the next.
+func (l *Limiter) Allow(client string, now time.Time) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.swept) >= sweepEvery {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: l.burst, filled: now}
+		l.buckets[client] = b
+	}
+	l.fill(b, now)
+
+	if b.tokens < 1 {
+		wait := time.Duration(math.Ceil((1 - b.tokens) / l.rate * float64(time.Second)))
+		return false, wait
+	}
+	b.tokens--
+	return true, 0
+}
+
+// fill adds the tokens a bucket earned since it was last filled.
+func (l *Limiter) fill(b *bucket, now time.Time) {
+	if elapsed := now.Sub(b.filled).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(l.burst, b.tokens+elapsed*l.rate)
+		b.filled = now
+	}
+}
+
+// sweep forgets the buckets that have filled up again, as a client with a
+// full bucket is one that starts afresh.
+func (l *Limiter) sweep(now time.Time) {
+	for client, b := range l.buckets {
+		l.fill(b, now)
+		if b.tokens >= l.burst {
+			delete(l.buckets, client)
+		}
+	}
+	l.swept = now
+}
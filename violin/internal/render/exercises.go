@@ -0,0 +1,70 @@
+package render
+
+import (
+	"violin/internal/practice"
+	"violin/internal/rhythm"
+)
+
+// Exercise is an exercise type of the scale page. Text labels its option and
+// Dir is the folder under img/ and mp3/ holding its notation and recordings.
+type Exercise struct {
+	Value string
+	Text  string
+	Dir   string
+}
+
+// Exercises lists the exercise types the scale page offers.
+var Exercises = []Exercise{
+	{practice.KindScale, "Scales", "scale"},
+	{practice.KindArpeggio, "Arpeggios", "arps"},
+	{practice.KindFirstInversion, "1st Inversions", "inv1"},
+	{practice.KindSecondInversion, "2nd Inversions", "inv2"},
+	{practice.KindDominant7, "Dominant 7ths", "dom7"},
+	{practice.KindDiminished7, "Diminished 7ths", "dim7"},
+	{practice.KindAugmented, "Augmented", "aug"},
+	{practice.KindThirds, "Thirds", "thirds"},
+	{practice.KindSixths, "Sixths", "sixths"},
+	{practice.KindOctaves, "Octaves", "octaves"},
+	{practice.KindFingeredOctaves, "Fingered Octaves", "foctaves"},
+	{practice.KindTenths, "Tenths", "tenths"},
+}
+
+// exerciseDir returns the asset folder of an exercise type, or "" for an
+// unknown one.
+func exerciseDir(scale string) string {
+	for _, e := range Exercises {
+		if e.Value == scale {
+			return e.Dir
+		}
+	}
+	return ""
+}
+
+// ExercisePitch returns the pitch the scale page plays an exercise type in.
+// Exercises that sound the same in major and minor keys are played as major.
+func ExercisePitch(scale, pitch string) string {
+	if !practice.Pitched(scale) {
+		return "Major"
+	}
+	return pitch
+}
+
+// exerciseOptions returns the exercise type options under the form name,
+// with scale checked.
+func exerciseOptions(name, scale string) []Option {
+	options := make([]Option, len(Exercises))
+	for i, e := range Exercises {
+		options[i] = Option{Name: name, Value: e.Value, IsChecked: e.Value == scale, Text: e.Text}
+	}
+	return options
+}
+
+// SetRhythmOptions sets the rhythm and bowing options based on the specified
+// pattern.
+func SetRhythmOptions(pattern string) []Option {
+	options := make([]Option, len(rhythm.Patterns))
+	for i, p := range rhythm.Patterns {
+		options[i] = Option{Name: "Rhythm", Value: p.ID, Text: p.Name}
+	}
+	return checkOption(options, pattern, rhythm.Default)
+}
@@ -0,0 +1,24 @@
+package render
+
+import "html/template"
+
+// KeyReference holds the key reference page: the circle of fifths drawn inline so
+// its keys link to their scales, and every key signature in order of
+// sharps and flats.
+type KeyReference struct {
+	Circle     template.HTML
+	Signatures []KeySignature
+}
+
+// KeySignature is one row of the key reference: the major and relative
+// minor keys that share a signature, links to their scales, the sharps or
+// flats it holds and the url of it drawn on a staff.
+type KeySignature struct {
+	Name        string
+	Major       string
+	MajorPath   string
+	Minor       string
+	MinorPath   string
+	Accidentals string
+	ImgPath     string
+}
@@ -0,0 +1,84 @@
+package render
+
+// Metronome holds the metronome settings of the scale page. Path is the url
+// of the click track on its own and MixPath the url of the clicks mixed
+// under the scale or arpeggio.
+type Metronome struct {
+	BPM          int
+	Meters       []Option
+	Accents      []Option
+	Subdivisions []Option
+	CountIns     []Option
+	SilentBars   []Option
+	Path         string
+	MixPath      string
+}
+
+// SetMeterOptions sets the meter options based on the specified meter.
+func SetMeterOptions(meter string) []Option {
+	return checkOption([]Option{
+		{Name: "Meter", Value: "2/4", Text: "2/4"},
+		{Name: "Meter", Value: "3/4", Text: "3/4"},
+		{Name: "Meter", Value: "4/4", Text: "4/4"},
+		{Name: "Meter", Value: "6/8", Text: "6/8"},
+	}, meter, "4/4")
+}
+
+// SetAccentOptions sets the accent options based on the specified accent.
+func SetAccentOptions(accent string) []Option {
+	return checkOption([]Option{
+		{Name: "Accent", Value: "meter", Text: "Meter accents"},
+		{Name: "Accent", Value: "first", Text: "First beat only"},
+		{Name: "Accent", Value: "none", Text: "No accents"},
+	}, accent, "meter")
+}
+
+// SetSubdivisionOptions sets the subdivision options based on the specified
+// number of clicks per beat.
+func SetSubdivisionOptions(subdivision string) []Option {
+	return checkOption([]Option{
+		{Name: "Subdivision", Value: "1", Text: "Beats"},
+		{Name: "Subdivision", Value: "2", Text: "Eighths"},
+		{Name: "Subdivision", Value: "3", Text: "Triplets"},
+		{Name: "Subdivision", Value: "4", Text: "Sixteenths"},
+	}, subdivision, "1")
+}
+
+// SetCountInOptions sets the count-in options based on the specified
+// number of bars.
+func SetCountInOptions(bars string) []Option {
+	return checkOption([]Option{
+		{Name: "CountIn", Value: "0", Text: "No count-in"},
+		{Name: "CountIn", Value: "1", Text: "1 bar count-in"},
+		{Name: "CountIn", Value: "2", Text: "2 bar count-in"},
+	}, bars, "1")
+}
+
+// SetSilentBarOptions sets the silent bar trainer options based on how
+// often a bar is muted.
+func SetSilentBarOptions(every string) []Option {
+	return checkOption([]Option{
+		{Name: "SilentEvery", Value: "0", Text: "Every bar"},
+		{Name: "SilentEvery", Value: "2", Text: "Mute every 2nd bar"},
+		{Name: "SilentEvery", Value: "3", Text: "Mute every 3rd bar"},
+		{Name: "SilentEvery", Value: "4", Text: "Mute every 4th bar"},
+	}, every, "0")
+}
+
+// checkOption checks the option with the given value, or the default when
+// no option has it.
+func checkOption(options []Option, value, def string) []Option {
+	found := false
+	for _, o := range options {
+		if o.Value == value {
+			found = true
+		}
+	}
+	if !found {
+		value = def
+	}
+	for i := range options {
+		options[i].IsChecked = options[i].Value == value
+	}
+	return options
+}
@@ -0,0 +1,9 @@
+package render
+
+// Offline is the toggle that keeps what a page shows for offline use. Item
+// is the id of the asset bundle the service worker keeps, such as
+// "key/major/a" or "duet/g", Name what the toggle calls it.
+type Offline struct {
+	Item string
+	Name string
+}
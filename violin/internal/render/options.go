@@ -1,7 +1,15 @@
 package render
 
 import (
+	"strconv"
 	"strings"
+
+	"violin/internal/i18n"
+	"violin/internal/planner"
+	"violin/internal/practice"
+	"violin/internal/studio"
+	"violin/internal/syllabus"
+	"violin/internal/theory"
 )
 
 // PageVars represents the input for generating a web page.
@@ -12,7 +20,6 @@ type PageVars struct {
 	Pitch         string
 	DuetImgPath   string
 	ScaleImgPath  string
-	GifPath       string
 	AudioPath     string
 	AudioPath2    string
 	DuetAudioBoth string
@@ -25,6 +32,105 @@ type PageVars struct {
 	Pitches       []Option
 	Keys          []Option
 	Octaves       []Option
+	Item          string
+	UserName      string
+	Error         string
+	Progress      practice.Summary
+	Plan          planner.Plan
+	Step          int
+	Boards        []syllabus.Board
+	Board         syllabus.Board
+	Grade         syllabus.Grade
+	Requirements  []Requirement
+	Role          string
+	Studios       []StudioView
+	Assignments   []AssignmentView
+	Catalog       []Option
+	Today         string
+	Mix           DuetMix
+	DronePath     string
+	Voicings      []Option
+	DroneLengths  []Option
+	Metronome     Metronome
+	Rhythms       []Option
+	Spellings     []Option
+	Locale        i18n.Locale
+	Languages     []Option
+	Namings       []Option
+	MIDIPath      string
+	MusicXMLPath  string
+	SharePath     string
+	NotationPath  string
+	TimingPath    string
+	Quiz          Quiz
+	SightReading  SightReading
+	KeyReference  KeyReference
+	Offline       Offline
+	Static        bool
+	Links         map[string]string
+	Nonce         string
+	CSRF          string
+}
+
+// DuetMix holds the play-along mixer settings of the duet page. Volumes and
+// the tempo are percentages, pans run from -100, hard left, to 100, hard
+// right. Path is the url of the rendered mix.
+type DuetMix struct {
+	Volume1 int
+	Volume2 int
+	Mute1   bool
+	Mute2   bool
+	Pan1    int
+	Pan2    int
+	Tempo   int
+	Path    string
+}
+
+// Where the audio and notation of a syllabus requirement come from.
+const (
+	SourceFile      = "file"      // a recording or image on disk
+	SourceGenerated = "generated" // synthesized or drawn when asked for
+	SourceMissing   = "missing"   // neither
+)
+
+// Requirement is an exam syllabus requirement as shown on the syllabus page.
+// Link and Item are set when the scale page can show the requirement. Audio
+// and Notation hold the source of each, and Missing lists the assets it is
+// missing, such as "audio" or "notation".
+type Requirement struct {
+	syllabus.Requirement
+	Item     string
+	Link     string
+	Audio    string
+	Notation string
+	Missing  []string
+}
+
+// StudioView is a studio as shown on the teacher dashboard.
+type StudioView struct {
+	studio.Studio
+	Teacher     string
+	Members     []Option
+	Invites     []studio.Invite
+	Assignments []AssignmentView
+}
+
+// AssignmentView is an assignment as shown on the dashboards, with the
+// practice the student logged for it since it was set.
+type AssignmentView struct {
+	studio.Assignment
+	Student string     `json:"student"`
+	Overdue bool       `json:"overdue"`
+	Minutes int        `json:"minutes"`
+	Tasks   []TaskView `json:"tasks"`
+}
+
+// TaskView is one item of an assignment as shown on the dashboards.
+type TaskView struct {
+	studio.Task
+	Title   string `json:"title"`
+	Link    string `json:"link"`
+	Minutes int    `json:"minutes"`
 }
 
 // Option represents the options for generating content.
@@ -36,39 +142,42 @@ type Option struct {
 	Text       string
 }
 
-// SetScaleOptions sets the scale options based on the specified scale.
+// SetScaleOptions sets the exercise type options based on the specified
+// scale.
 func SetScaleOptions(scale string) []Option {
-	var options []Option
-	switch scale {
-	case "Scale":
-		options = []Option{
-			{Name: "Scale", Value: "Scale", IsDisabled: false, IsChecked: true, Text: "Scales"},
-			{Name: "Scale", Value: "Arpeggio", IsDisabled: false, IsChecked: false, Text: "Arpeggios"},
-		}
-	case "Arpeggio":
-		options = []Option{
-			{Name: "Scale", Value: "Scale", IsDisabled: false, IsChecked: false, Text: "Scales"},
-			{Name: "Scale", Value: "Arpeggio", IsDisabled: false, IsChecked: true, Text: "Arpeggios"},
-		}
-	}
-	return options
+	return exerciseOptions("Scale", scale)
 }
 
-// SetOctaveOptions sets the key options based on specified octave.
-func SetOctaveOptions(octave string) []Option {
-	var options []Option
-	switch octave {
-	case "1":
-		options = []Option{
-			{Name: "Octave", Value: "1", IsDisabled: false, IsChecked: true, Text: "1 Octave"},
-			{Name: "Octave", Value: "2", IsDisabled: false, IsChecked: false, Text: "2 Octave"},
-		}
-	default:
-		options = []Option{
-			{Name: "Octave", Value: "1", IsDisabled: false, IsChecked: false, Text: "1 Octave"},
-			{Name: "Octave", Value: "2", IsDisabled: false, IsChecked: true, Text: "2 Octave"},
+// SetOctaveOptions sets the octave options based on the specified exercise
+// and octave. Octaves that do not fit the range of the violin from the first
+// position starting note of the exercise are disabled, and the highest
+// octave that does is checked instead of one that does not.
+func SetOctaveOptions(scale, pitch, key, octave string) []Option {
+	options := []Option{
+		{Name: "Octave", Value: "1", IsDisabled: false, IsChecked: false, Text: "1 Octave"},
+		{Name: "Octave", Value: "2", IsDisabled: false, IsChecked: false, Text: "2 Octave"},
+		{Name: "Octave", Value: "3", IsDisabled: false, IsChecked: false, Text: "3 Octave"},
+	}
+
+	fits := theory.MaxOctaves(key, pitch, scale)
+	for i := range options {
+		options[i].IsDisabled = i+1 > fits
+	}
+	if n, err := strconv.Atoi(octave); err == nil && n > fits {
+		octave = strconv.Itoa(fits)
+	}
+
+	checked := false
+	for i := range options {
+		if options[i].Value == octave && !options[i].IsDisabled {
+			options[i].IsChecked = true
+			checked = true
 		}
 	}
+	if !checked {
+		// Octaves used to default to two when the form sent none.
+		options[1].IsChecked = true
+	}
 
 	return options
 }
@@ -287,6 +396,9 @@ func SetKeyOptions(key string) []Option {
 // the last 2 characters.
 // For minor scales if the key is longer than 2 characters, we only care about
 // the first 2 characters.
+// This is the spelling the recordings are named by, shared by both
+// spellings of a key; notation is written in the key's spelling from
+// SetSpellingOptions.
 func SetActualKey(pitch string, key string) string {
 	switch pitch {
 	case "Major":
@@ -306,45 +418,38 @@ func SetActualKey(pitch string, key string) string {
 	return key
 }
 
-// SetMusicLabels sets the text for the music players. Major have a scale and
-// a drone, while minor have melodic and harmonic minor scales
-func SetMusicLabels(pitch, scale string) (string, string) {
-	var left string
-	var right string
-	switch pitch {
-	case "Major":
-		left = "Listen to Major "
-		right = "Listen to Drone"
-		if scale == "Scale" {
-			left += "Scale"
-			break
-		}
-		left += "Arpeggio"
-	case "Minor":
-		switch scale {
-		case "Scale":
-			left = "Listen to Harmonic Minor Scale"
-			right = "Listen to Melodic Minor Scale"
-		case "Arpeggio":
-			left = "Listen to Minor Arpeggio"
-			right = "Listen to Drone"
-		}
+// SetSpellingOptions sets the spelling options of a key in the pitch based
+// on the specified spelling, the conventional one when it is not one of the
+// key's. Keys written only one way have a single option.
+func SetSpellingOptions(pitch, key, spelling string) []Option {
+	spellings, err := theory.Spellings(key, pitch)
+	if err != nil {
+		return nil
+	}
+	options := make([]Option, len(spellings))
+	for i, s := range spellings {
+		options[i] = Option{Name: "Spelling", Value: s, Text: s}
 	}
+	return checkOption(options, spelling, spellings[0])
+}
 
-	return left, right
+// SetMusicLabels sets the text for the music players in the locale. Minor
+// scales have melodic and harmonic minor scales, every other exercise has
+// itself and a drone.
+func SetMusicLabels(loc i18n.Locale, pitch, scale string) (string, string) {
+	if scale == "Scale" && pitch == "Minor" {
+		return loc.T("Listen to %s", loc.T("Harmonic Minor Scale")), loc.T("Listen to %s", loc.T("Melodic Minor Scale"))
+	}
+	return loc.T("Listen to %s", loc.T(practice.KindName(scale, pitch))), loc.T("Listen to %s", loc.T("Drone"))
 }
 
 // SetAssetPaths builds paths to img and mp3 files that correspond to user
 // selection.
 func SetAssetPaths(pitch, scale, key, octave string) (string, string, string) {
 	imgPath, audioPath, audioPath2 := "img/", "mp3/", "mp3/"
-	switch scale {
-	case "Scale":
-		imgPath += "scale/"
-		audioPath += "scale/"
-	case "Arpeggio":
-		imgPath += "arps/"
-		audioPath += "arps/"
+	if dir := exerciseDir(scale); dir != "" {
+		imgPath += dir + "/"
+		audioPath += dir + "/"
 	}
 	switch pitch {
 	case "Major":
@@ -369,6 +474,9 @@ func SetAssetPaths(pitch, scale, key, octave string) (string, string, string) {
 	case "2":
 		imgPath += "2"
 		audioPath += "2"
+	case "3":
+		imgPath += "3"
+		audioPath += "3"
 	}
 
 	audioPath += ".mp3"
@@ -394,8 +502,10 @@ func SetAssetPaths(pitch, scale, key, octave string) (string, string, string) {
 		// May have just added a # to the path, so use the function
 		// to change # to s
 		audioPath2 = ChangeSharpToS(audioPath2)
+		// Drones are only recorded in two octaves, three octave scales
+		// start from the same tonic as one octave ones.
 		switch octave {
-		case "1":
+		case "1", "3":
 			audioPath2 += "1.mp3"
 		case "2":
 			audioPath2 += "2.mp3"
@@ -409,10 +519,7 @@ func SetAssetPaths(pitch, scale, key, octave string) (string, string, string) {
 func SetDefaultOptions() ([]Option, []Option, []Option, []Option) {
 
 	// Set the default Options for scales and arpeggios.
-	scale := []Option{
-		{"Scalearp", "Scale", false, true, "Scales"},
-		{"Scalearp", "Arpeggio", false, false, "Arpeggios"},
-	}
+	scale := exerciseOptions("Scalearp", "Scale")
 
 	// Set the default PitchOptions for scales and arpeggios.
 	pitch := []Option{
@@ -440,6 +547,7 @@ func SetDefaultOptions() ([]Option, []Option, []Option, []Option) {
 	octave := []Option{
 		{"Octave", "1", false, true, "1 Octave"},
 		{"Octave", "2", false, false, "2 Octave"},
+		{"Octave", "3", false, false, "3 Octave"},
 	}
 
 	return scale, pitch, key, octave
@@ -453,3 +561,80 @@ func ChangeSharpToS(path string) string {
 	}
 	return path
 }
+
+// Asset is the exercise an img or mp3 path built by SetAssetPaths holds.
+// Melodic is set for the melodic minor scales played next to the harmonic
+// ones.
+type Asset struct {
+	Scale   string
+	Pitch   string
+	Key     string
+	Octave  string
+	Melodic bool
+}
+
+// ParseAssetPath works out which exercise an img or mp3 path such as
+// "mp3/scale/minor/cs3m.mp3" or "img/dom7/major/d1.png" holds, reporting
+// false for paths that are not one that fits the violin.
+func ParseAssetPath(path string) (Asset, bool) {
+	var a Asset
+	parts := strings.Split(path, "/")
+	if len(parts) != 4 || (parts[0] != "img" && parts[0] != "mp3") {
+		return a, false
+	}
+
+	for _, e := range Exercises {
+		if e.Dir == parts[1] {
+			a.Scale = e.Value
+		}
+	}
+	if a.Scale == "" {
+		return a, false
+	}
+	switch parts[2] {
+	case "major":
+		a.Pitch = "Major"
+	case "minor":
+		a.Pitch = "Minor"
+	default:
+		return a, false
+	}
+	if a.Pitch != ExercisePitch(a.Scale, a.Pitch) {
+		return a, false
+	}
+
+	name := parts[3]
+	switch {
+	case parts[0] == "img" && strings.HasSuffix(name, ".png"):
+		name = strings.TrimSuffix(name, ".png")
+	case parts[0] == "mp3" && strings.HasSuffix(name, ".mp3"):
+		name = strings.TrimSuffix(name, ".mp3")
+	default:
+		return a, false
+	}
+	if a.Scale == "Scale" && a.Pitch == "Minor" && parts[0] == "mp3" && strings.HasSuffix(name, "m") {
+		a.Melodic = true
+		name = strings.TrimSuffix(name, "m")
+	}
+	if len(name) < 2 || len(name) > 3 {
+		return a, false
+	}
+
+	a.Octave = name[len(name)-1:]
+	a.Key = strings.ToUpper(name[:1])
+	switch name[1 : len(name)-1] {
+	case "":
+	case "s":
+		a.Key += "#"
+	case "b":
+		a.Key += "b"
+	default:
+		return a, false
+	}
+
+	octaves, err := strconv.Atoi(a.Octave)
+	if err != nil || octaves < 1 || octaves > theory.MaxOctaves(a.Key, a.Pitch, a.Scale) {
+		return a, false
+	}
+	return a, true
+}
@@ -0,0 +1,53 @@
+package render
+
+import "violin/internal/quiz"
+
+// Quiz holds the ear training page: the kinds of question to choose from,
+// the question being asked with the url of its audio, the verdict on the
+// last answer and how the student is doing at each kind.
+type Quiz struct {
+	Kinds     []Option
+	Kind      string
+	ID        string
+	Level     int
+	Prompt    string
+	Choices   []Option
+	AudioPath string
+	Answered  bool
+	Correct   bool
+	Feedback  string
+	Stats     []QuizStats
+}
+
+// QuizStats is how the student is doing at one kind of question.
+type QuizStats struct {
+	Name string
+	quiz.Stats
+}
+
+// quizKindNames maps the kinds of question onto their names.
+var quizKindNames = map[string]string{
+	quiz.KindInterval: "Intervals",
+	quiz.KindScale:    "Scale types",
+	quiz.KindTuning:   "Tuning",
+}
+
+// SetQuizKindOptions sets the kind of question options based on the
+// specified kind, intervals when it is unknown.
+func SetQuizKindOptions(kind string) []Option {
+	options := make([]Option, len(quiz.Kinds))
+	for i, k := range quiz.Kinds {
+		options[i] = Option{Name: "Kind", Value: k, Text: quizKindNames[k]}
+	}
+	return checkOption(options, kind, quiz.KindInterval)
+}
+
+// SetQuizStats names the stats of each kind of question, given in the order
+// of quiz.Kinds.
+func SetQuizStats(stats []quiz.Stats) []QuizStats {
+	named := make([]QuizStats, len(stats))
+	for i, st := range stats {
+		named[i] = QuizStats{Name: quizKindNames[quiz.Kinds[i]], Stats: st}
+	}
+	return named
+}
@@ -1,26 +1,78 @@
 package render
 
 import (
-	"fmt"
 	"html/template"
 	"net/http"
+	"path/filepath"
+
+	"violin/internal/i18n"
+
+	"github.com/pkg/errors"
 )
 
+// Templates holds the parsed page templates. They are parsed once, so a
+// template with an error is found when they are loaded rather than when a
+// page is asked for, and a set once loaded is never changed.
+type Templates struct {
+	pages map[string]*template.Template
+}
+
+// LoadTemplates parses every html template in dir.
+func LoadTemplates(dir string) (*Templates, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil {
+		return nil, errors.Wrap(err, "listing templates")
+	}
+	if len(files) == 0 {
+		return nil, errors.Errorf("no templates in %s", dir)
+	}
+
+	t := Templates{pages: make(map[string]*template.Template)}
+	for _, f := range files {
+		name := filepath.Base(f)
+		page, err := template.New(name).Funcs(localeFuncs(i18n.Locale{})).ParseFiles(f)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing %s", name)
+		}
+		t.pages[name] = page
+	}
+	return &t, nil
+}
+
+// Len returns how many templates the set holds.
+func (t *Templates) Len() int {
+	return len(t.pages)
+}
+
 // Render generates the html for any given web page.
-func Render(w http.ResponseWriter, tmpl string, pageVars PageVars) error {
-	// Prefix the name passed in with templates.
-	tmpl = fmt.Sprintf("templates/%s", tmpl)
+func (t *Templates) Render(w http.ResponseWriter, tmpl string, pageVars PageVars) error {
+	page, ok := t.pages[tmpl]
+	if !ok {
+		return errors.Errorf("no template %s", tmpl)
+	}
 
-	// Parse the template file held in the templates folder.
-	t, err := template.ParseFiles(tmpl)
+	// Work on a copy of the template, with functions that write its text
+	// and note names in the page's locale. The parsed template itself is
+	// never executed, so it can be copied for every page.
+	page, err := page.Clone()
 	if err != nil {
 		return err
 	}
+	page.Funcs(localeFuncs(pageVars.Locale))
 
 	// Execute the template and pass in the variables to fill the gaps.
-	if err := t.Execute(w, pageVars); err != nil {
+	if err := page.Execute(w, pageVars); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// localeFuncs returns the functions templates write text and note names
+// with in a locale.
+func localeFuncs(loc i18n.Locale) template.FuncMap {
+	return template.FuncMap{
+		"t":    loc.T,
+		"note": loc.Note,
+	}
+}
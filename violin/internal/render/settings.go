@@ -0,0 +1,33 @@
+package render
+
+import "violin/internal/i18n"
+
+// namingNames maps the note naming systems onto the English names of their
+// options.
+var namingNames = map[string]string{
+	i18n.NotesLetter:  "Letter names (C D E)",
+	i18n.NotesGerman:  "German names (C D E … H)",
+	i18n.NotesSolfege: "Fixed-do solfège (Do Ré Mi)",
+}
+
+// SetLanguageOptions sets the language options of the settings page based
+// on the specified language, following the browser when it is empty or
+// unknown. Languages are named in themselves.
+func SetLanguageOptions(language string) []Option {
+	options := []Option{{Name: "Language", Value: "", Text: "Automatic"}}
+	for _, l := range i18n.Languages {
+		options = append(options, Option{Name: "Language", Value: l, Text: i18n.LanguageNames[l]})
+	}
+	return checkOption(options, language, "")
+}
+
+// SetNamingOptions sets the note naming options of the settings page based
+// on the specified system, the usual one of the language when it is empty
+// or unknown.
+func SetNamingOptions(notes string) []Option {
+	options := []Option{{Name: "Notes", Value: "", Text: "Automatic"}}
+	for _, n := range i18n.Namings {
+		options = append(options, Option{Name: "Notes", Value: n, Text: namingNames[n]})
+	}
+	return checkOption(options, notes, "")
+}
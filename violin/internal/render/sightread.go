@@ -0,0 +1,76 @@
+package render
+
+import (
+	"strconv"
+
+	"violin/internal/sightread"
+)
+
+// SightReading holds the sight-reading page: the settings melodies are
+// generated from, the name of the melody and the urls of its notation,
+// audio and exports. SharePath links to this exact melody and NewPath to a
+// new one with the same settings.
+type SightReading struct {
+	Keys         []Option
+	Spellings    []Option
+	Pitches      []Option
+	Levels       []Option
+	Ranges       []Option
+	Leaps        []Option
+	Rhythms      []Option
+	Bars         int
+	Seed         int64
+	Name         string
+	ImgPath      string
+	AudioPath    string
+	MIDIPath     string
+	MusicXMLPath string
+	SharePath    string
+	NewPath      string
+}
+
+// SetLevelOptions sets the sight-reading level options based on the
+// specified level.
+func SetLevelOptions(level int) []Option {
+	var options []Option
+	for l := sightread.MinLevel; l <= sightread.MaxLevel; l++ {
+		options = append(options, Option{Name: "Level", Value: strconv.Itoa(l), Text: strconv.Itoa(l)})
+	}
+	return checkOption(options, strconv.Itoa(level), strconv.Itoa(sightread.MinLevel))
+}
+
+// SetRangeOptions sets the sight-reading range options based on the
+// specified range.
+func SetRangeOptions(rng string) []Option {
+	var options []Option
+	for _, r := range sightread.Ranges {
+		options = append(options, Option{Name: "Range", Value: r.ID, Text: r.Name})
+	}
+	return checkOption(options, rng, sightread.Ranges[0].ID)
+}
+
+// SetLeapOptions sets the sight-reading largest interval options based on
+// the specified one.
+func SetLeapOptions(leap string) []Option {
+	var options []Option
+	for _, l := range sightread.Leaps {
+		options = append(options, Option{Name: "Leap", Value: l.ID, Text: l.Name})
+	}
+	return checkOption(options, leap, sightread.Leaps[0].ID)
+}
+
+// SetCellOptions sets the sight-reading rhythm options, checking each of the
+// specified ones.
+func SetCellOptions(rhythms []string) []Option {
+	var options []Option
+	for _, c := range sightread.Cells {
+		o := Option{Name: "Rhythm", Value: c.ID, Text: c.Name}
+		for _, id := range rhythms {
+			if id == c.ID {
+				o.IsChecked = true
+			}
+		}
+		options = append(options, o)
+	}
+	return options
+}
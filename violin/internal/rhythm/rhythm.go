@@ -0,0 +1,90 @@
+// Package rhythm holds the rhythm and bowing patterns scales are practised
+// in, such as dotted rhythms, triplets and four notes to a bow, and applies
+// them to the notes of an exercise.
+package rhythm
+
+// Beat is the number of divisions of a crotchet beat durations are counted
+// in, enough for dotted rhythms, triplets and semiquavers. Triplet notes are
+// the ones whose durations are not a multiple of 3.
+const Beat = 12
+
+// Default is the pattern of plain scales, a crotchet to each bow.
+const Default = "separate"
+
+// Pattern is a rhythm and bowing pattern. The durations of its notes repeat
+// every group, and each bow slurs Slur notes, 1 being separate bows. Every
+// group fills a whole number of beats.
+type Pattern struct {
+	ID        string
+	Name      string
+	Durations []int // in divisions of a beat
+	Slur      int
+}
+
+// Patterns lists the patterns in the order the scale page offers them.
+var Patterns = []Pattern{
+	{"separate", "Separate Bows", []int{12}, 1},
+	{"detache", "Détaché Quavers", []int{6}, 1},
+	{"slur2", "Two Notes to a Bow", []int{6}, 2},
+	{"slur4", "Four Notes to a Bow", []int{6}, 4},
+	{"legato", "Eight Notes to a Bow", []int{3}, 8},
+	{"dotted", "Dotted Rhythm", []int{9, 3}, 1},
+	{"hooked", "Dotted Rhythm, Hooked Bows", []int{9, 3}, 2},
+	{"snap", "Reverse Dotted Rhythm", []int{3, 9}, 1},
+	{"triplets", "Triplets, Three Notes to a Bow", []int{4}, 3},
+}
+
+// Find returns the pattern with the id.
+func Find(id string) (Pattern, bool) {
+	for _, p := range Patterns {
+		if p.ID == id {
+			return p, true
+		}
+	}
+	return Pattern{}, false
+}
+
+// IsTriplet reports whether a duration is that of a triplet note.
+func IsTriplet(duration int) bool {
+	return duration%3 != 0
+}
+
+// Step is the rhythm and bowing of one note, or chord, of an exercise. The
+// zero Step is a crotchet in a bow of its own.
+type Step struct {
+	Duration  int  // in divisions of a beat, 0 for a crotchet
+	SlurStart bool // first of several notes in one bow
+	SlurEnd   bool // last of several notes in one bow
+	Slurred   bool // into the next note, in the same bow
+	Triplet   bool // first of three triplet notes
+}
+
+// Length returns the duration of the step in divisions of a beat.
+func (s Step) Length() int {
+	if s.Duration == 0 {
+		return Beat
+	}
+	return s.Duration
+}
+
+// Apply returns the steps of n notes played in the pattern. The last bow
+// slurs whatever notes are left.
+func (p Pattern) Apply(n int) []Step {
+	steps := make([]Step, n)
+	at := 0
+	for i := range steps {
+		d := p.Durations[i%len(p.Durations)]
+		steps[i].Duration = d
+		steps[i].Triplet = IsTriplet(d) && at%(3*d) == 0
+		at += d
+
+		if p.Slur > 1 && i+1 < n {
+			steps[i].SlurStart = i%p.Slur == 0
+			steps[i].Slurred = i%p.Slur != p.Slur-1
+		}
+		if p.Slur > 1 && i > 0 && (i%p.Slur == p.Slur-1 || i == n-1) && i%p.Slur != 0 {
+			steps[i].SlurEnd = true
+		}
+	}
+	return steps
+}
@@ -0,0 +1,332 @@
+// Package sightread generates short random melodies to sight-read, kept to
+// a key, a range of the violin, the leaps and rhythms a student has learned
+// and a difficulty level. The same settings and seed always give the same
+// melody, so a teacher can share one by its link.
+package sightread
+
+import (
+	"math/rand"
+
+	"violin/internal/rhythm"
+	"violin/internal/theory"
+
+	"github.com/pkg/errors"
+)
+
+// Difficulty levels.
+const (
+	MinLevel = 1
+	MaxLevel = 5
+)
+
+// Melodies are written in 4/4.
+const BeatsPerBar = 4
+
+// barLength is the length of a bar in divisions of a beat.
+const barLength = BeatsPerBar * rhythm.Beat
+
+// Limits on the number of bars.
+const (
+	MinBars = 2
+	MaxBars = 16
+)
+
+// ErrInvalid is returned for settings no melody can be written for.
+var ErrInvalid = errors.New("invalid settings")
+
+// Cell is a rhythm a melody is built from, a beat or more of notes.
+type Cell struct {
+	ID        string
+	Name      string
+	Durations []int // in divisions of a beat
+	Level     int   // the level it is used from by default
+}
+
+// Cells lists the rhythms melodies can be built from, simplest first.
+var Cells = []Cell{
+	{"minim", "Minims", []int{24}, 1},
+	{"crotchet", "Crotchets", []int{12}, 1},
+	{"quavers", "Quavers", []int{6, 6}, 2},
+	{"dottedcrotchet", "Dotted Crotchet and Quaver", []int{18, 6}, 3},
+	{"dotted", "Dotted Quaver and Semiquaver", []int{9, 3}, 4},
+	{"triplet", "Quaver Triplets", []int{4, 4, 4}, 4},
+	{"semiquavers", "Semiquavers", []int{3, 3, 3, 3}, 5},
+}
+
+// Range is a stretch of the violin melodies keep to.
+type Range struct {
+	ID        string
+	Name      string
+	Low, High int // MIDI notes
+	Level     int // the level it is used from by default
+}
+
+// Ranges lists the ranges melodies can keep to, narrowest first.
+var Ranges = []Range{
+	{"dastring", "D and A Strings, 1st Position", 62, 74, 1},
+	{"first", "1st Position", 55, 83, 2},
+	{"third", "Up to 3rd Position", 55, 86, 4},
+	{"fifth", "Up to 5th Position", 55, 90, 5},
+}
+
+// Leap is the largest interval melodies move by, in steps of the scale.
+type Leap struct {
+	ID    string
+	Name  string
+	Steps int
+	Level int // the level it is used from by default
+}
+
+// Leaps lists the largest intervals melodies can move by, smallest first.
+var Leaps = []Leap{
+	{"step", "Steps", 1, 1},
+	{"third", "Thirds", 2, 2},
+	{"fifth", "Fifths", 4, 3},
+	{"sixth", "Sixths", 5, 4},
+	{"octave", "Octaves", 7, 5},
+}
+
+// Settings are what a melody is generated from. Zero values take the
+// defaults of the level.
+type Settings struct {
+	Key     string // spelled as the melody is written, such as "Db"
+	Pitch   string // "Major" or "Minor"
+	Level   int
+	Range   string   // the ID of one of Ranges
+	Leap    string   // the ID of one of Leaps
+	Rhythms []string // the IDs of some of Cells
+	Bars    int
+	Seed    int64
+}
+
+// Defaults fills in the settings left out with those of the level.
+func (s Settings) Defaults() Settings {
+	if s.Level < MinLevel || s.Level > MaxLevel {
+		s.Level = MinLevel
+	}
+	if _, ok := findRange(s.Range); !ok {
+		for _, r := range Ranges {
+			if r.Level <= s.Level {
+				s.Range = r.ID
+			}
+		}
+	}
+	if _, ok := findLeap(s.Leap); !ok {
+		for _, l := range Leaps {
+			if l.Level <= s.Level {
+				s.Leap = l.ID
+			}
+		}
+	}
+	var rhythms []string
+	for _, id := range s.Rhythms {
+		if _, ok := findCell(id); ok {
+			rhythms = append(rhythms, id)
+		}
+	}
+	if len(rhythms) == 0 {
+		for _, c := range Cells {
+			if c.Level <= s.Level {
+				rhythms = append(rhythms, c.ID)
+			}
+		}
+	}
+	s.Rhythms = rhythms
+	if s.Bars < MinBars || s.Bars > MaxBars {
+		s.Bars = 4 + 2*(s.Level-MinLevel)
+	}
+	return s
+}
+
+// Melody is a generated melody, a note to each step of its rhythm. BarEnds
+// marks the notes that end a bar.
+type Melody struct {
+	Notes   []theory.Note
+	Steps   []rhythm.Step
+	BarEnds []bool
+}
+
+// Generate writes the melody of the settings, after filling in their
+// defaults. Melodies start on the tonic, third or fifth, move mostly by step
+// with the occasional leap, turn back after a large leap and end on the
+// tonic with a note as long as the last bar has left.
+func Generate(s Settings) (Melody, error) {
+	s = s.Defaults()
+	rng, _ := findRange(s.Range)
+	leap, _ := findLeap(s.Leap)
+
+	all, err := theory.KeyNotes(s.Key, s.Pitch)
+	if err != nil {
+		return Melody{}, errors.Wrap(ErrInvalid, err.Error())
+	}
+	var notes []theory.Note
+	for _, n := range all {
+		if n.MIDI() >= rng.Low && n.MIDI() <= rng.High {
+			notes = append(notes, n)
+		}
+	}
+	// The range must hold a whole octave so there is a tonic to start and
+	// end on and room to move.
+	if len(notes) < 8 {
+		return Melody{}, errors.Wrapf(ErrInvalid, "%s %s has too few notes in %s", s.Key, s.Pitch, rng.Name)
+	}
+	tonic, err := theory.Tonic(s.Key)
+	if err != nil {
+		return Melody{}, errors.Wrap(ErrInvalid, err.Error())
+	}
+	var tonics []int
+	for i, n := range notes {
+		if (n.MIDI()-tonic.MIDI())%12 == 0 {
+			tonics = append(tonics, i)
+		}
+	}
+
+	r := rand.New(rand.NewSource(s.Seed))
+	var m Melody
+
+	// Start on the tonic, third or fifth of the lowest complete octave.
+	at := tonics[0] + 2*r.Intn(3)
+	if at >= len(notes) {
+		at = tonics[0]
+	}
+	prev := 0
+
+	for bar := 0; bar < s.Bars; bar++ {
+		last := bar == s.Bars-1
+		for filled := 0; filled < barLength; {
+			left := barLength - filled
+			if last && left <= 2*rhythm.Beat {
+				// Finish on the nearest tonic, held to the end.
+				at = nearest(tonics, at)
+				m.add(notes[at], rhythm.Step{Duration: left})
+				break
+			}
+
+			cell, ok := pickCell(r, s.Rhythms, left)
+			if !ok {
+				// No rhythm fits what is left of the bar: hold a note
+				// over it.
+				m.add(notes[at], rhythm.Step{Duration: left})
+				break
+			}
+			for i, d := range cell.Durations {
+				step := rhythm.Step{Duration: d, Triplet: rhythm.IsTriplet(d) && i == 0}
+				m.add(notes[at], step)
+				at, prev = move(r, at, prev, leap.Steps, len(notes))
+				filled += d
+			}
+		}
+		m.BarEnds[len(m.BarEnds)-1] = true
+	}
+	return m, nil
+}
+
+// add appends a note to the melody.
+func (m *Melody) add(n theory.Note, step rhythm.Step) {
+	m.Notes = append(m.Notes, n)
+	m.Steps = append(m.Steps, step)
+	m.BarEnds = append(m.BarEnds, false)
+}
+
+// move returns the index of the next note from at, among n notes, and the
+// interval moved by. Steps are six times as likely as any one leap,
+// repeated notes rare, and a leap of more than a third is followed by a
+// step back the other way.
+func move(r *rand.Rand, at, prev, maxLeap, n int) (int, int) {
+	var by int
+	switch {
+	case prev > 2:
+		by = -1
+	case prev < -2:
+		by = 1
+	default:
+		var choices []int
+		for i := 1; i <= maxLeap; i++ {
+			weight := 1
+			if i == 1 {
+				weight = 6
+			}
+			for w := 0; w < weight; w++ {
+				choices = append(choices, i, -i)
+			}
+		}
+		choices = append(choices, 0)
+		by = choices[r.Intn(len(choices))]
+	}
+	if at+by < 0 || at+by >= n {
+		by = -by
+	}
+	if at+by < 0 || at+by >= n {
+		by = 0
+	}
+	return at + by, by
+}
+
+// pickCell returns a random one of the rhythms that fits in the rest of a
+// bar, left divisions long.
+func pickCell(r *rand.Rand, ids []string, left int) (Cell, bool) {
+	var fits []Cell
+	for _, id := range ids {
+		c, _ := findCell(id)
+		var total int
+		for _, d := range c.Durations {
+			total += d
+		}
+		if total <= left {
+			fits = append(fits, c)
+		}
+	}
+	if len(fits) == 0 {
+		return Cell{}, false
+	}
+	return fits[r.Intn(len(fits))], true
+}
+
+// nearest returns the index among the tonics closest to at.
+func nearest(tonics []int, at int) int {
+	best := tonics[0]
+	for _, t := range tonics[1:] {
+		if abs(t-at) < abs(best-at) {
+			best = t
+		}
+	}
+	return best
+}
+
+// findCell returns the rhythm with the id.
+func findCell(id string) (Cell, bool) {
+	for _, c := range Cells {
+		if c.ID == id {
+			return c, true
+		}
+	}
+	return Cell{}, false
+}
+
+// findRange returns the range with the id.
+func findRange(id string) (Range, bool) {
+	for _, r := range Ranges {
+		if r.ID == id {
+			return r, true
+		}
+	}
+	return Range{}, false
+}
+
+// findLeap returns the largest interval with the id.
+func findLeap(id string) (Leap, bool) {
+	for _, l := range Leaps {
+		if l.ID == id {
+			return l, true
+		}
+	}
+	return Leap{}, false
+}
+
+// abs returns the absolute value of n.
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
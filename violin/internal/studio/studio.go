@@ -0,0 +1,425 @@
+// Package studio manages teaching studios: the teacher running a studio, the
+// students who joined it with an invite code and the assignments the teacher
+// gives them.
+package studio
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"violin/internal/jsonfile"
+	"violin/internal/practice"
+
+	"github.com/pkg/errors"
+)
+
+// InviteLifetime is how long an invite code can be used to join a studio.
+const InviteLifetime = 14 * 24 * time.Hour
+
+// dayLayout is the format used for due dates.
+const dayLayout = "2006-01-02"
+
+var (
+	// ErrNotFound is returned when a studio, invite or assignment does not
+	// exist.
+	ErrNotFound = errors.New("not found")
+
+	// ErrForbidden is returned when a user acts on a studio or assignment
+	// they have no rights over.
+	ErrForbidden = errors.New("forbidden")
+
+	// ErrInvalid is returned for incomplete or malformed input.
+	ErrInvalid = errors.New("invalid")
+)
+
+// Studio is a teacher's group of students.
+type Studio struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	TeacherID   string    `json:"teacher_id"`
+	Students    []string  `json:"students"`
+	DateCreated time.Time `json:"date_created"`
+}
+
+// HasStudent reports whether the user is a student of the studio.
+func (s Studio) HasStudent(userID string) bool {
+	for _, id := range s.Students {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// Invite is a code a student enters to join a studio.
+type Invite struct {
+	Code     string    `json:"code"`
+	StudioID string    `json:"studio_id"`
+	Expires  time.Time `json:"expires"`
+}
+
+// Task is one item of an assignment with the tempo the student should reach.
+type Task struct {
+	Item        string `json:"item"`
+	TargetTempo int    `json:"target_tempo,omitempty"`
+}
+
+// Assignment is work a teacher sets for one student.
+type Assignment struct {
+	ID            string    `json:"id"`
+	StudioID      string    `json:"studio_id"`
+	TeacherID     string    `json:"teacher_id"`
+	StudentID     string    `json:"student_id"`
+	Tasks         []Task    `json:"tasks"`
+	Notes         string    `json:"notes"`
+	Due           string    `json:"due"`
+	DateCreated   time.Time `json:"date_created"`
+	DateCompleted time.Time `json:"date_completed,omitempty"`
+}
+
+// Completed reports whether the student marked the assignment as done.
+func (a Assignment) Completed() bool {
+	return !a.DateCompleted.IsZero()
+}
+
+// Overdue reports whether the assignment is still open after its due date.
+func (a Assignment) Overdue(now time.Time) bool {
+	return !a.Completed() && a.Due < now.Format(dayLayout)
+}
+
+// NewAssignment is the input for creating an assignment.
+type NewAssignment struct {
+	StudioID  string `json:"studio_id"`
+	StudentID string `json:"student_id"`
+	Tasks     []Task `json:"tasks"`
+	Notes     string `json:"notes"`
+	Due       string `json:"due"`
+}
+
+// Store holds every studio, invite and assignment. It is safe for
+// concurrent use and persists every change to a JSON file.
+type Store struct {
+	mu   sync.Mutex
+	path string
+	data struct {
+		Studios     map[string]*Studio     `json:"studios"`
+		Invites     map[string]Invite      `json:"invites"`
+		Assignments map[string]*Assignment `json:"assignments"`
+	}
+}
+
+// NewStore constructs a Store backed by the JSON file at path, loading any
+// studios already saved there.
+func NewStore(path string) (*Store, error) {
+	s := Store{path: path}
+	if err := jsonfile.Load(path, &s.data); err != nil {
+		return nil, errors.Wrap(err, "loading studios")
+	}
+	if s.data.Studios == nil {
+		s.data.Studios = make(map[string]*Studio)
+	}
+	if s.data.Invites == nil {
+		s.data.Invites = make(map[string]Invite)
+	}
+	if s.data.Assignments == nil {
+		s.data.Assignments = make(map[string]*Assignment)
+	}
+	return &s, nil
+}
+
+// Create starts a new studio run by the teacher.
+func (s *Store) Create(teacherID, name string, now time.Time) (Studio, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return Studio{}, errors.Wrap(ErrInvalid, "studio needs a name")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := Studio{
+		ID:          newToken(8),
+		Name:        name,
+		TeacherID:   teacherID,
+		DateCreated: now.UTC(),
+	}
+	s.data.Studios[st.ID] = &st
+
+	if err := s.save(); err != nil {
+		return Studio{}, err
+	}
+	return st, nil
+}
+
+// Studio returns the studio with the given id.
+func (s *Store) Studio(id string) (Studio, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.data.Studios[id]
+	if !ok {
+		return Studio{}, ErrNotFound
+	}
+	return copyStudio(st), nil
+}
+
+// TeacherStudios returns the studios run by the teacher, ordered by name.
+func (s *Store) TeacherStudios(teacherID string) []Studio {
+	return s.studios(func(st *Studio) bool { return st.TeacherID == teacherID })
+}
+
+// StudentStudios returns the studios the student has joined, ordered by name.
+func (s *Store) StudentStudios(studentID string) []Studio {
+	return s.studios(func(st *Studio) bool { return st.HasStudent(studentID) })
+}
+
+// Invite creates an invite code for the studio. Only the studio's teacher
+// can invite students.
+func (s *Store) Invite(teacherID, studioID string, now time.Time) (Invite, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.data.Studios[studioID]
+	if !ok {
+		return Invite{}, ErrNotFound
+	}
+	if st.TeacherID != teacherID {
+		return Invite{}, ErrForbidden
+	}
+
+	// Drop expired invites while we are holding the lock anyway.
+	for code, inv := range s.data.Invites {
+		if now.After(inv.Expires) {
+			delete(s.data.Invites, code)
+		}
+	}
+
+	inv := Invite{
+		Code:     strings.ToUpper(newToken(4)),
+		StudioID: studioID,
+		Expires:  now.Add(InviteLifetime).UTC(),
+	}
+	s.data.Invites[inv.Code] = inv
+
+	if err := s.save(); err != nil {
+		return Invite{}, err
+	}
+	return inv, nil
+}
+
+// Invites returns the unexpired invite codes of the studio. Only the studio's
+// teacher can see them.
+func (s *Store) Invites(teacherID, studioID string, now time.Time) ([]Invite, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.data.Studios[studioID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if st.TeacherID != teacherID {
+		return nil, ErrForbidden
+	}
+
+	var invites []Invite
+	for _, inv := range s.data.Invites {
+		if inv.StudioID == studioID && !now.After(inv.Expires) {
+			invites = append(invites, inv)
+		}
+	}
+	sort.Slice(invites, func(i, j int) bool {
+		return invites[i].Expires.After(invites[j].Expires)
+	})
+	return invites, nil
+}
+
+// Join adds the student to the studio the invite code belongs to. A teacher
+// cannot join their own studio as a student.
+func (s *Store) Join(studentID, code string, now time.Time) (Studio, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inv, ok := s.data.Invites[strings.ToUpper(strings.TrimSpace(code))]
+	if !ok || now.After(inv.Expires) {
+		return Studio{}, ErrNotFound
+	}
+	st, ok := s.data.Studios[inv.StudioID]
+	if !ok {
+		return Studio{}, ErrNotFound
+	}
+	if st.TeacherID == studentID {
+		return Studio{}, ErrForbidden
+	}
+
+	if !st.HasStudent(studentID) {
+		st.Students = append(st.Students, studentID)
+		if err := s.save(); err != nil {
+			return Studio{}, err
+		}
+	}
+	return copyStudio(st), nil
+}
+
+// Assign creates an assignment. Only the teacher of the studio can assign
+// work, and only to students of that studio.
+func (s *Store) Assign(teacherID string, na NewAssignment, now time.Time) (Assignment, error) {
+	if len(na.Tasks) == 0 {
+		return Assignment{}, errors.Wrap(ErrInvalid, "assignment needs at least one item")
+	}
+	for _, t := range na.Tasks {
+		if _, err := practice.ParseItem(t.Item); err != nil {
+			return Assignment{}, errors.Wrap(ErrInvalid, err.Error())
+		}
+		if t.TargetTempo < 0 {
+			return Assignment{}, errors.Wrap(ErrInvalid, "target tempo must be positive")
+		}
+	}
+	if _, err := time.Parse(dayLayout, na.Due); err != nil {
+		return Assignment{}, errors.Wrap(ErrInvalid, "due date must be YYYY-MM-DD")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.data.Studios[na.StudioID]
+	if !ok {
+		return Assignment{}, ErrNotFound
+	}
+	if st.TeacherID != teacherID || !st.HasStudent(na.StudentID) {
+		return Assignment{}, ErrForbidden
+	}
+
+	a := Assignment{
+		ID:          newToken(8),
+		StudioID:    na.StudioID,
+		TeacherID:   teacherID,
+		StudentID:   na.StudentID,
+		Tasks:       append([]Task(nil), na.Tasks...),
+		Notes:       strings.TrimSpace(na.Notes),
+		Due:         na.Due,
+		DateCreated: now.UTC(),
+	}
+	s.data.Assignments[a.ID] = &a
+
+	if err := s.save(); err != nil {
+		return Assignment{}, err
+	}
+	return a, nil
+}
+
+// Complete marks an assignment as done, or reopens it. Only the student the
+// work was assigned to can do this.
+func (s *Store) Complete(studentID, assignmentID string, done bool, now time.Time) (Assignment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.data.Assignments[assignmentID]
+	if !ok {
+		return Assignment{}, ErrNotFound
+	}
+	if a.StudentID != studentID {
+		return Assignment{}, ErrForbidden
+	}
+
+	a.DateCompleted = time.Time{}
+	if done {
+		a.DateCompleted = now.UTC()
+	}
+
+	if err := s.save(); err != nil {
+		return Assignment{}, err
+	}
+	return copyAssignment(a), nil
+}
+
+// StudentAssignments returns the work assigned to the student, soonest due
+// first.
+func (s *Store) StudentAssignments(studentID string) []Assignment {
+	return s.assignments(func(a *Assignment) bool { return a.StudentID == studentID })
+}
+
+// StudioAssignments returns the work assigned in the studio, soonest due
+// first. Only the studio's teacher can see every assignment.
+func (s *Store) StudioAssignments(teacherID, studioID string) ([]Assignment, error) {
+	st, err := s.Studio(studioID)
+	if err != nil {
+		return nil, err
+	}
+	if st.TeacherID != teacherID {
+		return nil, ErrForbidden
+	}
+	return s.assignments(func(a *Assignment) bool { return a.StudioID == studioID }), nil
+}
+
+// studios returns copies of the studios matching keep, ordered by name.
+func (s *Store) studios(keep func(*Studio) bool) []Studio {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var studios []Studio
+	for _, st := range s.data.Studios {
+		if keep(st) {
+			studios = append(studios, copyStudio(st))
+		}
+	}
+	sort.Slice(studios, func(i, j int) bool {
+		return studios[i].Name < studios[j].Name
+	})
+	return studios
+}
+
+// assignments returns copies of the assignments matching keep, soonest due
+// first.
+func (s *Store) assignments(keep func(*Assignment) bool) []Assignment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var list []Assignment
+	for _, a := range s.data.Assignments {
+		if keep(a) {
+			list = append(list, copyAssignment(a))
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Due != list[j].Due {
+			return list[i].Due < list[j].Due
+		}
+		return list[i].DateCreated.Before(list[j].DateCreated)
+	})
+	return list
+}
+
+// save writes the store to disk. The caller must hold s.mu.
+func (s *Store) save() error {
+	if err := jsonfile.Save(s.path, &s.data); err != nil {
+		return errors.Wrap(err, "saving studios")
+	}
+	return nil
+}
+
+// copyStudio returns a copy of st that shares no memory with the store.
+func copyStudio(st *Studio) Studio {
+	c := *st
+	c.Students = append([]string(nil), st.Students...)
+	return c
+}
+
+// copyAssignment returns a copy of a that shares no memory with the store.
+func copyAssignment(a *Assignment) Assignment {
+	c := *a
+	c.Tasks = append([]Task(nil), a.Tasks...)
+	return c
+}
+
+// newToken returns n random bytes encoded as hex.
+func newToken(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,177 @@
+// Package syllabus loads the scale and arpeggio requirements of graded exam
+// boards from JSON data files.
+package syllabus
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"violin/internal/jsonfile"
+	"violin/internal/theory"
+
+	"github.com/pkg/errors"
+)
+
+// Exercise types used in syllabus files.
+const (
+	ExerciseScale       = "scale"
+	ExerciseArpeggio    = "arpeggio"
+	ExerciseChromatic   = "chromatic"
+	ExerciseDominant7   = "dominant7"
+	ExerciseDiminished7 = "diminished7"
+	ExerciseThirds      = "thirds"
+	ExerciseSixths      = "sixths"
+	ExerciseOctaves     = "octaves"
+)
+
+// exerciseNames holds the display name of each exercise type.
+var exerciseNames = map[string]string{
+	ExerciseScale:       "Scale",
+	ExerciseArpeggio:    "Arpeggio",
+	ExerciseChromatic:   "Chromatic Scale",
+	ExerciseDominant7:   "Dominant 7th",
+	ExerciseDiminished7: "Diminished 7th",
+	ExerciseThirds:      "Scale in Thirds",
+	ExerciseSixths:      "Scale in Sixths",
+	ExerciseOctaves:     "Scale in Octaves",
+}
+
+// Minor scale forms.
+const (
+	FormNatural  = "natural"
+	FormHarmonic = "harmonic"
+	FormMelodic  = "melodic"
+	FormEither   = "harmonic or melodic"
+)
+
+// Board is an exam board and its graded requirements.
+type Board struct {
+	ID     string  `json:"id"`
+	Name   string  `json:"name"`
+	URL    string  `json:"url"`
+	Note   string  `json:"note"`
+	Grades []Grade `json:"grades"`
+}
+
+// Grade is the set of requirements for one exam grade.
+type Grade struct {
+	ID           string        `json:"id"`
+	Name         string        `json:"name"`
+	Requirements []Requirement `json:"requirements"`
+}
+
+// Tempo is a minimum speed, such as 80 crotchet beats per minute.
+type Tempo struct {
+	Beat string `json:"beat"`
+	BPM  int    `json:"bpm"`
+}
+
+// Requirement is one scale, arpeggio or other exercise a grade asks for.
+type Requirement struct {
+	Exercise string `json:"exercise"`
+	Key      string `json:"key"`
+	Pitch    string `json:"pitch,omitempty"`
+	Form     string `json:"form,omitempty"`
+	Octaves  int    `json:"octaves"`
+	Bowing   string `json:"bowing"`
+	Tempo    Tempo  `json:"tempo"`
+}
+
+// Title returns a human readable description of the requirement, such as
+// "D Harmonic Minor Scale, 2 Octaves".
+func (r Requirement) Title() string {
+	parts := []string{r.Key}
+	if r.Pitch != "" {
+		pitch := r.Pitch
+		if r.Form != "" {
+			pitch = strings.ToUpper(r.Form[:1]) + r.Form[1:] + " " + pitch
+		}
+		parts = append(parts, pitch)
+	}
+	parts = append(parts, exerciseNames[r.Exercise])
+
+	octaves := "Octaves"
+	if r.Octaves == 1 {
+		octaves = "Octave"
+	}
+	return strings.Join(parts, " ") + ", " + strconv.Itoa(r.Octaves) + " " + octaves
+}
+
+// Load reads every *.json syllabus file in dir, returning the boards ordered
+// by name.
+func Load(dir string) ([]Board, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, errors.Wrap(err, "listing syllabus files")
+	}
+	if len(paths) == 0 {
+		if _, err := os.Stat(dir); err != nil {
+			return nil, errors.Wrap(err, "reading syllabus directory")
+		}
+	}
+
+	var boards []Board
+	for _, path := range paths {
+		var b Board
+		if err := jsonfile.Load(path, &b); err != nil {
+			return nil, err
+		}
+		if err := b.validate(); err != nil {
+			return nil, errors.Wrapf(err, "validating %s", path)
+		}
+		boards = append(boards, b)
+	}
+
+	sort.Slice(boards, func(i, j int) bool {
+		return boards[i].Name < boards[j].Name
+	})
+	return boards, nil
+}
+
+// Find returns the board and grade with the given ids.
+func Find(boards []Board, boardID, gradeID string) (Board, Grade, bool) {
+	for _, b := range boards {
+		if b.ID != boardID {
+			continue
+		}
+		for _, g := range b.Grades {
+			if g.ID == gradeID {
+				return b, g, true
+			}
+		}
+		return b, Grade{}, false
+	}
+	return Board{}, Grade{}, false
+}
+
+// validate checks the board holds only known exercises and sensible values.
+func (b Board) validate() error {
+	if b.ID == "" || b.Name == "" {
+		return errors.New("board needs an id and a name")
+	}
+	for _, g := range b.Grades {
+		if g.ID == "" {
+			return errors.Errorf("grade %q needs an id", g.Name)
+		}
+		for i, r := range g.Requirements {
+			if _, ok := exerciseNames[r.Exercise]; !ok {
+				return errors.Errorf("%s requirement %d: unknown exercise %q", g.Name, i+1, r.Exercise)
+			}
+			if _, err := theory.PitchClass(r.Key); err != nil {
+				return errors.Wrapf(err, "%s requirement %d", g.Name, i+1)
+			}
+			switch r.Pitch {
+			case "", "Major", "Minor":
+			default:
+				return errors.Errorf("%s requirement %d: unknown pitch %q", g.Name, i+1, r.Pitch)
+			}
+			if r.Octaves < 1 {
+				return errors.Errorf("%s requirement %d: needs at least one octave", g.Name, i+1)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package theory
+
+// fifthsOrder lists the natural notes a fifth apart, the order sharps are
+// added to key signatures in. Flats are added in the reverse order.
+const fifthsOrder = "FCGDAEB"
+
+// Signature is a key signature with the major and relative minor keys that
+// share it. Fifths counts its sharps, or flats when negative, and
+// Accidentals names them in the order they are written.
+type Signature struct {
+	Fifths      int      `json:"fifths"`
+	Major       string   `json:"major"`
+	Minor       string   `json:"minor"`
+	Accidentals []string `json:"accidentals"`
+}
+
+// SignatureOf returns the key signature of the given number of sharps, or
+// flats when negative, from 7 flats to 7 sharps.
+func SignatureOf(fifths int) Signature {
+	s := Signature{
+		Fifths:      fifths,
+		Major:       fifthName(fifths),
+		Minor:       fifthName(fifths + 3),
+		Accidentals: []string{},
+	}
+	for i := 0; i < fifths; i++ {
+		s.Accidentals = append(s.Accidentals, string(fifthsOrder[i])+"#")
+	}
+	for i := 0; i < -fifths; i++ {
+		s.Accidentals = append(s.Accidentals, string(fifthsOrder[6-i])+"b")
+	}
+	return s
+}
+
+// fifthName names the note the given number of fifths above C, or below
+// when negative.
+func fifthName(fifths int) string {
+	at := fifths + 1 // F is a fifth below C
+	acc := at / 7
+	if at < 0 && at%7 != 0 {
+		acc--
+	}
+	n := Note{Letter: fifthsOrder[(at%7+7)%7], Accidental: acc}
+	return n.Name()
+}
+
+// CirclePosition is one of the twelve positions of the circle of fifths,
+// counted clockwise from C at the top. The positions at the bottom hold two
+// enharmonic signatures, such as six sharps and six flats.
+type CirclePosition struct {
+	Position   int         `json:"position"`
+	Signatures []Signature `json:"signatures"`
+}
+
+// CircleOfFifths returns the positions of the circle of fifths with every
+// key signature of up to seven sharps or flats, sharps first.
+func CircleOfFifths() []CirclePosition {
+	circle := make([]CirclePosition, 12)
+	for i := range circle {
+		circle[i].Position = i
+	}
+	for fifths := 0; fifths <= 7; fifths++ {
+		circle[fifths%12].Signatures = append(circle[fifths%12].Signatures, SignatureOf(fifths))
+	}
+	for fifths := -1; fifths >= -7; fifths-- {
+		circle[fifths+12].Signatures = append(circle[fifths+12].Signatures, SignatureOf(fifths))
+	}
+	return circle
+}
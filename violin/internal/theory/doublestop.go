@@ -0,0 +1,146 @@
+package theory
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// Strings holds the open strings of the violin, lowest first, as MIDI note
+// numbers.
+var Strings = []int{55, 62, 69, 76}
+
+// Measurements of the violin and the left hand used to work out which double
+// stops a fingering can reach, in millimetres along the string.
+const (
+	stringLength = 328.0 // from the nut to the bridge
+	minFingerGap = 8.0   // between the tips of neighbouring fingers
+	maxFingerGap = 34.0
+	maxHandSpan  = 85.0 // from the first finger to the fourth
+	maxSemitones = 24   // the highest note of a string above its open note
+)
+
+// Fingering is the fingers that stop the lower and upper notes of a double
+// stop, from 1 for the first finger to 4 for the fourth.
+type Fingering struct {
+	Low  int
+	High int
+}
+
+// Stop is where a double stop is played: the lower of the two neighbouring
+// strings, as an index into Strings, and the fingering.
+type Stop struct {
+	String    int
+	Fingering Fingering
+}
+
+// DoubleStop is a pair of notes of a double stop exercise, with where it is
+// played. Playable is false when none of the fingerings of the exercise can
+// reach the pair, in which case Stop is zero.
+type DoubleStop struct {
+	Low      Note
+	High     Note
+	Stop     Stop
+	Playable bool
+}
+
+// doubleStop is a kind of double stop exercise: the scale degrees between its
+// voices and the fingerings it is played with, in order of preference.
+type doubleStop struct {
+	letters    int
+	fingerings []Fingering
+}
+
+// doubleStops holds the double stop exercise kinds.
+var doubleStops = map[string]doubleStop{
+	"Thirds":          {2, []Fingering{{3, 1}, {4, 2}}},
+	"Sixths":          {5, []Fingering{{1, 2}, {2, 3}, {3, 4}}},
+	"Octaves":         {7, []Fingering{{1, 4}}},
+	"FingeredOctaves": {7, []Fingering{{1, 3}, {2, 4}}},
+	"Tenths":          {9, []Fingering{{1, 4}}},
+}
+
+// IsDoubleStop reports whether a kind is a double stop exercise: "Thirds",
+// "Sixths", "Octaves", "FingeredOctaves" or "Tenths".
+func IsDoubleStop(kind string) bool {
+	_, ok := doubleStops[kind]
+	return ok
+}
+
+// DoubleStops returns the pairs of notes of a double stop exercise in the
+// key: the scale from its first position tonic up the given number of
+// octaves and back down, under the same scale the interval of the exercise
+// higher. Minor keys use the harmonic minor scale.
+func DoubleStops(key, pitch, kind string, octaves int) ([]DoubleStop, error) {
+	ds, ok := doubleStops[kind]
+	if !ok {
+		return nil, errors.Errorf("unknown double stop %q", kind)
+	}
+	lower, err := Scale(key, pitch, kind, octaves, false)
+	if err != nil {
+		return nil, err
+	}
+	sh, err := exerciseShape(pitch, kind, false)
+	if err != nil {
+		return nil, err
+	}
+
+	tonic := lower[0]
+	degree := func(d int) Note {
+		return tonic.up(d, 12*(d/7)+sh.upSemitones[d%7])
+	}
+
+	stops := make([]DoubleStop, len(lower))
+	for i := range lower {
+		d := i
+		if i > 7*octaves {
+			d = 14*octaves - i
+		}
+		s := DoubleStop{Low: degree(d), High: degree(d + ds.letters)}
+		s.Stop, s.Playable = Playable(s.Low, s.High, ds.fingerings)
+		stops[i] = s
+	}
+	return stops, nil
+}
+
+// Playable works out where a pair of notes can be played as a double stop on
+// neighbouring strings with one of the fingerings, trying the lowest strings
+// first. A note on an open string is played open whatever its finger.
+func Playable(low, high Note, fingerings []Fingering) (Stop, bool) {
+	for s := 0; s+1 < len(Strings); s++ {
+		lo, hi := low.MIDI()-Strings[s], high.MIDI()-Strings[s+1]
+		if lo < 0 || hi < 0 || lo > maxSemitones || hi > maxSemitones {
+			continue
+		}
+		for _, f := range fingerings {
+			if reach(lo, f.Low, hi, f.High) {
+				return Stop{String: s, Fingering: f}, true
+			}
+		}
+	}
+	return Stop{}, false
+}
+
+// reach reports whether one hand can stop two strings the given number of
+// semitones above their open notes with the given fingers.
+func reach(semitones1, finger1, semitones2, finger2 int) bool {
+	if semitones1 == 0 || semitones2 == 0 {
+		return true
+	}
+	if finger1 == finger2 {
+		return semitones1 == semitones2
+	}
+	if finger1 > finger2 {
+		semitones1, finger1, semitones2, finger2 = semitones2, finger2, semitones1, finger1
+	}
+
+	gap := fingerPosition(semitones2) - fingerPosition(semitones1)
+	fingers := float64(finger2 - finger1)
+	return gap >= fingers*minFingerGap && gap <= fingers*maxFingerGap && gap <= maxHandSpan
+}
+
+// fingerPosition returns how far from the nut a string is stopped to sound
+// the given number of semitones above its open note.
+func fingerPosition(semitones int) float64 {
+	return stringLength * (1 - math.Pow(2, -float64(semitones)/12))
+}
@@ -0,0 +1,305 @@
+package theory
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// The range of the violin GoViolin writes scales for, as MIDI note numbers.
+const (
+	LowestNote  = 55  // G3, the open G string
+	HighestNote = 100 // E7
+)
+
+// letters lists the note letters in order from C.
+const letters = "CDEFGAB"
+
+// Note is a spelled note such as F#5, with middle C as C4.
+type Note struct {
+	Letter     byte // 'A' to 'G'
+	Accidental int  // sharps above zero, flats below
+	Octave     int
+}
+
+// ParseNote parses a note name such as "C", "F#" or "Bb" as a note in the
+// given octave.
+func ParseNote(name string, octave int) (Note, error) {
+	name = strings.TrimSpace(name)
+	if _, err := PitchClass(name); err != nil {
+		return Note{}, err
+	}
+	n := Note{Letter: strings.ToUpper(name[:1])[0], Octave: octave}
+	for _, acc := range name[1:] {
+		if acc == '#' || acc == '♯' {
+			n.Accidental++
+		} else {
+			n.Accidental--
+		}
+	}
+	return n, nil
+}
+
+// Step returns the diatonic position of the note, counting letters from C0,
+// which is where the note sits on a staff.
+func (n Note) Step() int {
+	return n.Octave*7 + strings.IndexByte(letters, n.Letter)
+}
+
+// MIDI returns the MIDI note number of the note.
+func (n Note) MIDI() int {
+	return (n.Octave+1)*12 + letterClasses[n.Letter] + n.Accidental
+}
+
+// Name returns the note name without its octave, such as "F#".
+func (n Note) Name() string {
+	name := string(n.Letter)
+	for i := 0; i < n.Accidental; i++ {
+		name += "#"
+	}
+	for i := 0; i > n.Accidental; i-- {
+		name += "b"
+	}
+	return name
+}
+
+// String returns the note name with its octave, such as "F#5".
+func (n Note) String() string {
+	return n.Name() + strconv.Itoa(n.Octave)
+}
+
+// up returns the note the given number of letters above n, spelled so it
+// sounds the given number of semitones above n.
+func (n Note) up(steps, semitones int) Note {
+	step := n.Step() + steps
+	to := Note{Letter: letters[step%7], Octave: step / 7}
+	to.Accidental = n.MIDI() + semitones - to.MIDI()
+	return to
+}
+
+// keyName returns the first spelling of a key option such as "C#/Db".
+func keyName(key string) string {
+	if i := strings.IndexByte(key, '/'); i >= 0 {
+		return key[:i]
+	}
+	return key
+}
+
+// Tonic returns the lowest note of a key the violin can play, the starting
+// note of its scales and arpeggios in first position.
+func Tonic(key string) (Note, error) {
+	n, err := ParseNote(keyName(key), 0)
+	if err != nil {
+		return Note{}, err
+	}
+	for n.MIDI() < LowestNote {
+		n.Octave++
+	}
+	return n, nil
+}
+
+// Start returns the lowest note the violin can play that an exercise in the
+// key starts on: the tonic for most, the third or fifth for inversions and
+// the dominant for dominant sevenths.
+func Start(key, pitch, kind string) (Note, error) {
+	tonic, err := ParseNote(keyName(key), 0)
+	if err != nil {
+		return Note{}, err
+	}
+	sh, err := exerciseShape(pitch, kind, false)
+	if err != nil {
+		return Note{}, err
+	}
+
+	n := tonic.up(sh.startLetters, sh.startSemitones)
+	for n.MIDI() < LowestNote {
+		n.Octave++
+	}
+	for n.MIDI()-12 >= LowestNote {
+		n.Octave--
+	}
+	return n, nil
+}
+
+// MaxOctaves returns how many octaves of an exercise in the key fit the
+// range of the violin from its first position starting note, or 0 for an
+// unknown key or exercise.
+func MaxOctaves(key, pitch, kind string) int {
+	n, err := Start(key, pitch, kind)
+	if err != nil {
+		return 0
+	}
+	highest := HighestNote
+	if ds, ok := doubleStops[kind]; ok {
+		// The upper voice of a double stop sounds above the scale.
+		sh, _ := exerciseShape(pitch, kind, false)
+		highest -= 12*(ds.letters/7) + sh.upSemitones[ds.letters%7]
+	}
+	if highest < n.MIDI() {
+		return 0
+	}
+	return (highest - n.MIDI()) / 12
+}
+
+// The intervals between the degrees of the scales, in semitones.
+var (
+	majorSteps         = []int{2, 2, 1, 2, 2, 2, 1}
+	naturalMinorSteps  = []int{2, 1, 2, 2, 1, 2, 2}
+	harmonicMinorSteps = []int{2, 1, 2, 2, 1, 3, 1}
+	melodicMinorSteps  = []int{2, 1, 2, 2, 2, 2, 1}
+)
+
+// shape is the pattern of an exercise: where it starts, as letters and
+// semitones above the tonic, and its notes within each octave above the
+// start, as letters and semitones above it, going up and coming down.
+type shape struct {
+	startLetters, startSemitones int
+	upLetters, upSemitones       []int
+	downLetters, downSemitones   []int
+}
+
+// exerciseShape returns the shape of an exercise. Kind is "Scale",
+// "Arpeggio", "Inversion1" or "Inversion2" for the first and second
+// inversions of the arpeggio, "Dominant7" for the dominant seventh of the
+// key, "Diminished7" or "Augmented". Minor scales are harmonic, or melodic
+// when asked for, which falls as a natural minor. Double stops have the
+// shape of the scale their lower voice plays.
+func exerciseShape(pitch, kind string, melodic bool) (shape, error) {
+	var sh shape
+	var third int
+	switch pitch {
+	case "Major":
+		third = 4
+	case "Minor":
+		third = 3
+	default:
+		return sh, errors.Errorf("unknown pitch %q", pitch)
+	}
+
+	if IsDoubleStop(kind) {
+		kind = "Scale"
+	}
+	switch kind {
+	case "Scale":
+		var up, down []int
+		switch {
+		case pitch == "Major":
+			up, down = majorSteps, majorSteps
+		case melodic:
+			up, down = melodicMinorSteps, naturalMinorSteps
+		default:
+			up, down = harmonicMinorSteps, harmonicMinorSteps
+		}
+		sh.upLetters, sh.upSemitones = scaleDegrees(up)
+		sh.downLetters, sh.downSemitones = scaleDegrees(down)
+		return sh, nil
+
+	case "Arpeggio":
+		sh.upLetters, sh.upSemitones = []int{0, 2, 4}, []int{0, third, 7}
+	case "Inversion1":
+		sh.startLetters, sh.startSemitones = 2, third
+		sh.upLetters, sh.upSemitones = []int{0, 2, 5}, []int{0, 7 - third, 12 - third}
+	case "Inversion2":
+		sh.startLetters, sh.startSemitones = 4, 7
+		sh.upLetters, sh.upSemitones = []int{0, 3, 5}, []int{0, 5, 5 + third}
+	case "Dominant7":
+		sh.startLetters, sh.startSemitones = 4, 7
+		sh.upLetters, sh.upSemitones = []int{0, 2, 4, 6}, []int{0, 4, 7, 10}
+	case "Diminished7":
+		sh.upLetters, sh.upSemitones = []int{0, 2, 4, 6}, []int{0, 3, 6, 9}
+	case "Augmented":
+		sh.upLetters, sh.upSemitones = []int{0, 2, 4}, []int{0, 4, 8}
+	default:
+		return sh, errors.Errorf("unknown kind %q", kind)
+	}
+	sh.downLetters, sh.downSemitones = sh.upLetters, sh.upSemitones
+	return sh, nil
+}
+
+// scaleDegrees returns the letter and semitone offsets of each degree of a
+// scale from its tonic.
+func scaleDegrees(steps []int) ([]int, []int) {
+	letters, semitones := []int{0}, []int{0}
+	for i, s := range steps[:6] {
+		letters = append(letters, i+1)
+		semitones = append(semitones, semitones[i]+s)
+	}
+	return letters, semitones
+}
+
+// Scale returns the notes of an exercise in the key, see exerciseShape,
+// from its first position starting note up the given number of octaves and
+// back down.
+func Scale(key, pitch, kind string, octaves int, melodic bool) ([]Note, error) {
+	start, err := Start(key, pitch, kind)
+	if err != nil {
+		return nil, err
+	}
+	if octaves < 1 || octaves > MaxOctaves(key, pitch, kind) {
+		return nil, errors.Errorf("%d octaves of %s do not fit the violin", octaves, key)
+	}
+	sh, err := exerciseShape(pitch, kind, melodic)
+	if err != nil {
+		return nil, err
+	}
+
+	var notes []Note
+	for o := 0; o < octaves; o++ {
+		for i := range sh.upLetters {
+			notes = append(notes, start.up(7*o+sh.upLetters[i], 12*o+sh.upSemitones[i]))
+		}
+	}
+	notes = append(notes, start.up(7*octaves, 12*octaves))
+	for o := octaves - 1; o >= 0; o-- {
+		for i := len(sh.downLetters) - 1; i >= 0; i-- {
+			notes = append(notes, start.up(7*o+sh.downLetters[i], 12*o+sh.downSemitones[i]))
+		}
+	}
+	return notes, nil
+}
+
+// KeyNotes returns every note of the scale of a key the violin can play,
+// from the lowest up. Minor keys take the notes of the harmonic minor scale.
+func KeyNotes(key, pitch string) ([]Note, error) {
+	tonic, err := Tonic(key)
+	if err != nil {
+		return nil, err
+	}
+	sh, err := exerciseShape(pitch, "Scale", false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Start an octave down so the notes below the tonic are included.
+	tonic.Octave--
+	var notes []Note
+	for o := 0; ; o++ {
+		for i := range sh.upLetters {
+			n := tonic.up(7*o+sh.upLetters[i], 12*o+sh.upSemitones[i])
+			if n.MIDI() > HighestNote {
+				return notes, nil
+			}
+			if n.MIDI() >= LowestNote {
+				notes = append(notes, n)
+			}
+		}
+	}
+}
+
+// fifths places each natural note on the circle of fifths, with C as 0.
+var fifths = map[byte]int{'F': -1, 'C': 0, 'G': 1, 'D': 2, 'A': 3, 'E': 4, 'B': 5}
+
+// KeySignature returns the number of sharps, or flats when negative, in the
+// key signature of a major or minor key.
+func KeySignature(key, pitch string) (int, error) {
+	n, err := ParseNote(keyName(key), 0)
+	if err != nil {
+		return 0, err
+	}
+	sig := fifths[n.Letter] + 7*n.Accidental
+	if pitch == "Minor" {
+		sig -= 3
+	}
+	return sig, nil
+}
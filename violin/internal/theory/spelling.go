@@ -0,0 +1,58 @@
+package theory
+
+// Spellings returns the names a major or minor key can be written under,
+// conventional first, for a key given by any of its names or by a key
+// option such as "C#/Db". Only keys of up to seven sharps or flats are
+// written, so most keys have a single spelling. Of two, the one with fewer
+// sharps or flats is conventional, and sharps on a tie, as for F# major and
+// D# minor.
+func Spellings(key, pitch string) ([]string, error) {
+	pc, err := PitchClass(keyName(key))
+	if err != nil {
+		return nil, err
+	}
+
+	var spellings []string
+	for _, p := range CircleOfFifths() {
+		for _, s := range p.Signatures {
+			name := s.Major
+			if pitch == "Minor" {
+				name = s.Minor
+			}
+			if c, _ := PitchClass(name); c == pc {
+				spellings = append(spellings, name)
+			}
+		}
+	}
+	if len(spellings) == 2 {
+		first, _ := KeySignature(spellings[0], pitch)
+		second, _ := KeySignature(spellings[1], pitch)
+		if abs(second) < abs(first) {
+			spellings[0], spellings[1] = spellings[1], spellings[0]
+		}
+	}
+	return spellings, nil
+}
+
+// Spell returns the name a key is written under: the chosen spelling when
+// it is one of the key's, otherwise the conventional one.
+func Spell(key, pitch, choice string) (string, error) {
+	spellings, err := Spellings(key, pitch)
+	if err != nil {
+		return "", err
+	}
+	for _, s := range spellings {
+		if s == choice {
+			return s, nil
+		}
+	}
+	return spellings[0], nil
+}
+
+// abs returns the absolute value of n.
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
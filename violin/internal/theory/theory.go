@@ -0,0 +1,39 @@
+// Package theory holds the music theory GoViolin needs: note names, pitch
+// classes and keys.
+package theory
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// letterClasses maps natural note letters to their pitch class, with C as 0.
+var letterClasses = map[byte]int{'C': 0, 'D': 2, 'E': 4, 'F': 5, 'G': 7, 'A': 9, 'B': 11}
+
+// PitchClass returns the pitch class, 0 to 11 with C as 0, of a note name
+// such as "C", "F#" or "Bb". Double sharps and flats are accepted.
+func PitchClass(name string) (int, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return 0, errors.New("empty note name")
+	}
+
+	pc, ok := letterClasses[strings.ToUpper(name[:1])[0]]
+	if !ok {
+		return 0, errors.Errorf("invalid note name %q", name)
+	}
+
+	for _, acc := range name[1:] {
+		switch acc {
+		case '#', '♯':
+			pc++
+		case 'b', '♭':
+			pc--
+		default:
+			return 0, errors.Errorf("invalid note name %q", name)
+		}
+	}
+
+	return (pc + 12) % 12, nil
+}
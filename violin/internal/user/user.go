@@ -0,0 +1,256 @@
+// Package user manages GoViolin accounts and their login sessions.
+package user
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"violin/internal/jsonfile"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// SessionLifetime is how long a login session stays valid.
+const SessionLifetime = 30 * 24 * time.Hour
+
+// Roles a user can have. Teachers run studios and assign work, students
+// join studios and practice.
+const (
+	RoleTeacher = "teacher"
+	RoleStudent = "student"
+)
+
+var (
+	// ErrNotFound is returned when a user or session does not exist.
+	ErrNotFound = errors.New("not found")
+
+	// ErrExists is returned when creating a user whose name is taken.
+	ErrExists = errors.New("user already exists")
+
+	// ErrAuthenticationFailure is returned when a name and password do not
+	// match an account.
+	ErrAuthenticationFailure = errors.New("authentication failed")
+
+	// ErrInvalid is returned when a name, password or role is not
+	// acceptable.
+	ErrInvalid = errors.New("invalid name, password or role")
+)
+
+// dummyHash is compared against when logging in with an unknown name, so
+// that it takes as long as a wrong password and response times do not tell
+// which names have accounts. Its cost is bcrypt.DefaultCost like real hashes.
+var dummyHash = []byte("$2a$10$ELfQtWKpdMm4d/xhFFILUu1waeZRDmzhE1vgygwssqZBxJtuNQZF.")
+
+// User represents a GoViolin account.
+type User struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Role         string    `json:"role"`
+	PasswordHash []byte    `json:"password_hash"`
+	DateCreated  time.Time `json:"date_created"`
+	Language     string    `json:"language,omitempty"`
+	Notes        string    `json:"notes,omitempty"`
+}
+
+// IsTeacher reports whether the user has the teacher role. Accounts created
+// before roles existed are students.
+func (u User) IsTeacher() bool {
+	return u.Role == RoleTeacher
+}
+
+// session ties a login token to a user.
+type session struct {
+	UserID  string    `json:"user_id"`
+	Expires time.Time `json:"expires"`
+}
+
+// Store holds all accounts and login sessions. It is safe for concurrent use
+// and persists every change to a JSON file.
+type Store struct {
+	mu   sync.Mutex
+	path string
+	data struct {
+		Users    map[string]User    `json:"users"`
+		Sessions map[string]session `json:"sessions"`
+	}
+}
+
+// NewStore constructs a Store backed by the JSON file at path, loading any
+// accounts already saved there.
+func NewStore(path string) (*Store, error) {
+	s := Store{path: path}
+	if err := jsonfile.Load(path, &s.data); err != nil {
+		return nil, errors.Wrap(err, "loading users")
+	}
+	if s.data.Users == nil {
+		s.data.Users = make(map[string]User)
+	}
+	if s.data.Sessions == nil {
+		s.data.Sessions = make(map[string]session)
+	}
+	return &s, nil
+}
+
+// Create adds a new account with the given name, password and role.
+func (s *Store) Create(name, password, role string, now time.Time) (User, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(password) < 8 {
+		return User{}, ErrInvalid
+	}
+	if role != RoleTeacher && role != RoleStudent {
+		return User{}, ErrInvalid
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return User{}, errors.Wrap(err, "generating password hash")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.byName(name); err == nil {
+		return User{}, ErrExists
+	}
+
+	u := User{
+		ID:           newToken(16),
+		Name:         name,
+		Role:         role,
+		PasswordHash: hash,
+		DateCreated:  now.UTC(),
+	}
+	s.data.Users[u.ID] = u
+
+	if err := jsonfile.Save(s.path, &s.data); err != nil {
+		return User{}, errors.Wrap(err, "saving users")
+	}
+	return u, nil
+}
+
+// Authenticate returns the account matching the name and password.
+func (s *Store) Authenticate(name, password string) (User, error) {
+	s.mu.Lock()
+	u, err := s.byName(strings.TrimSpace(name))
+	s.mu.Unlock()
+	if err != nil {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return User{}, ErrAuthenticationFailure
+	}
+
+	if err := bcrypt.CompareHashAndPassword(u.PasswordHash, []byte(password)); err != nil {
+		return User{}, ErrAuthenticationFailure
+	}
+	return u, nil
+}
+
+// ByID returns the account with the given id.
+func (s *Store) ByID(id string) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.data.Users[id]
+	if !ok {
+		return User{}, ErrNotFound
+	}
+	return u, nil
+}
+
+// SetPreferences saves the language and note naming system the user reads
+// pages in. Empty values follow the browser.
+func (s *Store) SetPreferences(id, language, notes string) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.data.Users[id]
+	if !ok {
+		return User{}, ErrNotFound
+	}
+	u.Language, u.Notes = language, notes
+	s.data.Users[id] = u
+
+	if err := jsonfile.Save(s.path, &s.data); err != nil {
+		return User{}, errors.Wrap(err, "saving users")
+	}
+	return u, nil
+}
+
+// StartSession creates a login session for the user and returns its token.
+func (s *Store) StartSession(userID string, now time.Time) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.data.Users[userID]; !ok {
+		return "", ErrNotFound
+	}
+
+	// Drop expired sessions while we are holding the lock anyway.
+	for token, ses := range s.data.Sessions {
+		if now.After(ses.Expires) {
+			delete(s.data.Sessions, token)
+		}
+	}
+
+	token := newToken(32)
+	s.data.Sessions[token] = session{UserID: userID, Expires: now.Add(SessionLifetime).UTC()}
+
+	if err := jsonfile.Save(s.path, &s.data); err != nil {
+		return "", errors.Wrap(err, "saving sessions")
+	}
+	return token, nil
+}
+
+// SessionUser returns the account logged in with the given session token.
+func (s *Store) SessionUser(token string, now time.Time) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ses, ok := s.data.Sessions[token]
+	if !ok || now.After(ses.Expires) {
+		return User{}, ErrNotFound
+	}
+	u, ok := s.data.Users[ses.UserID]
+	if !ok {
+		return User{}, ErrNotFound
+	}
+	return u, nil
+}
+
+// EndSession removes the login session with the given token.
+func (s *Store) EndSession(token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.data.Sessions[token]; !ok {
+		return nil
+	}
+	delete(s.data.Sessions, token)
+
+	if err := jsonfile.Save(s.path, &s.data); err != nil {
+		return errors.Wrap(err, "saving sessions")
+	}
+	return nil
+}
+
+// byName finds a user by case insensitive name. The caller must hold s.mu.
+func (s *Store) byName(name string) (User, error) {
+	for _, u := range s.data.Users {
+		if strings.EqualFold(u.Name, name) {
+			return u, nil
+		}
+	}
+	return User{}, ErrNotFound
+}
+
+// newToken returns n random bytes encoded as hex.
+func newToken(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}